@@ -17,10 +17,11 @@ along with this program. If not, see <http://www.gnu.org/licenses/>.
 package cmd
 
 import (
-	"encoding/json"
 	"fmt"
 	"os"
+	"strings"
 
+	"github.com/cmmorrow/url/urlkit"
 	"github.com/spf13/cobra"
 )
 
@@ -34,6 +35,9 @@ var uriPathInput string
 var fragmentInput string
 var queryInput string
 var paramsInput []string
+var templateInput string
+var varsInput []string
+var varsFile string
 
 // buildCmd represents the build command
 var buildCmd = &cobra.Command{
@@ -53,21 +57,61 @@ Examples:
 		http://myhost.com:8888/colorado/denver
 
 	url build --scheme http --host myhost.com --param foo=bar --param bar=baz
+		http://myhost.com?foo=bar&bar=baz
+
+	url build --scheme http --host myhost.com --param foo=bar --param bar=baz --sort-params
 		http://myhost.com?bar=baz&foo=bar
 
 	url build --scheme http --host myhost.com --query "foo=bar&bar=baz"
 		http://myhost.com?foo=bar&bar=baz
 
 	url build --json '{"scheme":"http","host":"myhost.com","params":{"foo":"bar","bar":"baz"}}'
-		http://myhost.com?bar=baz&foo=bar
+		http://myhost.com?foo=bar&bar=baz
+
+	cat uris.ndjson | url build --json - --jobs 4
+
+Expand an RFC 6570 URI Template with --template. Every level of RFC 6570 is
+supported, including the explode modifier (*) and prefix lengths such as
+{var:3}. Variables are given with --var key=value. A key given more than once
+is a list. Use --vars to read variables from a JSON object, where arrays are
+lists and objects are associative arrays. --var takes precedence over --vars.
+
+	url build --template '/users/{id}/repos{?page,per_page}' --var id=42 --var page=2
+		/users/42/repos?page=2
+
+	url build --template 'https://mysite.com/search{?tag*}' --var tag=go --var tag=url
+		https://mysite.com/search?tag=go&tag=url
+
+	url build --template '{/path*}{?query*}' --vars '{"path":["a","b c"],"query":{"x":"1","y":"2"}}'
+		/a/b%20c?x=1&y=2
 	`,
 	Args: cobra.MaximumNArgs(0),
 	Run: func(cmd *cobra.Command, args []string) {
-		var uri URI
+		if templateInput != "" {
+			out, err := expandTemplate()
+			if err != nil {
+				fail(err)
+			}
+			fmt.Println(out)
+			return
+		}
+		if jsonInput == stdinName || len(batch.Files) > 0 {
+			var inputs []string
+			if jsonInput == stdinName {
+				inputs = []string{stdinName}
+			}
+			runBatch(inputs, buildFromJSON, false)
+			return
+		}
+		var uri urlkit.URI
 		if jsonInput != "" {
-			uri = buildURIFromJSON(jsonInput)
+			var err error
+			uri, err = buildURIFromJSON(jsonInput)
+			if err != nil {
+				fail(err)
+			}
 		} else {
-			uri = URI{
+			uri = urlkit.URI{
 				Scheme:    schemeInput,
 				User:      userInput,
 				UriPath:   uriPathInput,
@@ -79,25 +123,71 @@ Examples:
 				RawParams: paramsInput,
 			}
 		}
-		u := uri.AsURL()
-		fmt.Printf("%s\n", u.String())
+		uri.SortParams = sortParamsFlag
+		fmt.Printf("%s\n", uri.String())
 	},
 }
 
-func buildURIFromJSON(j string) URI {
-	var uri = URI{}
-	err := json.Unmarshal([]byte(j), &uri)
+// buildFromJSON builds a single URL from a line of JSON read in batch mode.
+func buildFromJSON(j string) (string, error) {
+	uri, err := buildURIFromJSON(j)
 	if err != nil {
-		fmt.Println("Error reading JSON.")
-		os.Exit(1)
+		return "", err
+	}
+	uri.SortParams = sortParamsFlag
+	return uri.String() + "\n", nil
+}
+
+func buildURIFromJSON(j string) (urlkit.URI, error) {
+	return urlkit.FromJSON([]byte(j))
+}
+
+// expandTemplate expands --template with the variables from --vars and --var.
+func expandTemplate() (string, error) {
+	tmpl, err := urlkit.ParseTemplate(templateInput)
+	if err != nil {
+		return "", err
+	}
+	vars := urlkit.TemplateVars{}
+	if varsFile != "" {
+		data := []byte(varsFile)
+		if !strings.HasPrefix(strings.TrimSpace(varsFile), "{") {
+			if data, err = os.ReadFile(varsFile); err != nil {
+				return "", ioError("read", varsFile, err)
+			}
+		}
+		if vars, err = urlkit.ParseTemplateVars(data); err != nil {
+			return "", err
+		}
+	}
+	given := map[string]bool{}
+	for _, v := range varsInput {
+		key, value, err := splitEdit("var", v)
+		if err != nil {
+			return "", err
+		}
+		switch current := vars[key].(type) {
+		case string:
+			if given[key] {
+				vars[key] = []string{current, value}
+				continue
+			}
+		case []string:
+			if given[key] {
+				vars[key] = append(current, value)
+				continue
+			}
+		}
+		vars[key] = value
+		given[key] = true
 	}
-	return uri
+	return tmpl.Expand(vars)
 }
 
 func init() {
 	rootCmd.AddCommand(buildCmd)
 
-	buildCmd.Flags().StringVar(&jsonInput, "json", "", "Provide input as JSON.")
+	buildCmd.Flags().StringVar(&jsonInput, "json", "", "Provide input as JSON. Use - to read one JSON object per line from stdin.")
 	buildCmd.Flags().StringVar(&schemeInput, schemeLabel, "", "Provide a URI scheme (or protocol).")
 	buildCmd.Flags().StringVar(&userInput, userLabel, "", "Provides a user[:password].")
 	buildCmd.Flags().StringVar(&domainInput, hostLabel, "", "Provide a URI authority/domain/host or host:port.")
@@ -107,4 +197,9 @@ func init() {
 	buildCmd.Flags().StringVar(&fragmentInput, fragmentLabel, "", "Provide a URI fragment.")
 	buildCmd.Flags().StringVar(&queryInput, "query", "", "Provide a URL query string (without ?).")
 	buildCmd.Flags().StringArrayVar(&paramsInput, paramLabel, nil, "Provide a key=value pair of query parameters.")
+	buildCmd.Flags().StringVar(&templateInput, "template", "", "Expand an RFC 6570 URI Template.")
+	buildCmd.Flags().StringArrayVar(&varsInput, "var", nil, "Provide a key=value template variable. A key given more than once is a list.")
+	buildCmd.Flags().StringVar(&varsFile, "vars", "", "Read template variables from a JSON file or a JSON object.")
+	buildCmd.Flags().BoolVar(&sortParamsFlag, "sort-params", false, "Sort query parameters by key instead of keeping their order.")
+	addBatchFlags(buildCmd)
 }
@@ -0,0 +1,40 @@
+/*
+Copyright © 2022 Chris Morrow cmmorrow@gmail.com
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program. If not, see <http://www.gnu.org/licenses/>.
+*/
+package cmd
+
+import (
+	"github.com/cmmorrow/url/urlkit"
+	"github.com/spf13/cobra"
+)
+
+var charsetFlag string
+var detectCharsetFlag bool
+
+// addCharsetFlags adds --charset to a command.
+func addCharsetFlags(cmd *cobra.Command, usage string) {
+	cmd.Flags().StringVar(&charsetFlag, "charset", "", usage+" Defaults to utf-8.")
+}
+
+// checkCharset fails if --charset is not a known charset.
+func checkCharset() {
+	if charsetFlag == "" {
+		return
+	}
+	if _, err := urlkit.LookupCharset(charsetFlag); err != nil {
+		fail(err)
+	}
+}
@@ -0,0 +1,129 @@
+/*
+Copyright © 2022 Chris Morrow cmmorrow@gmail.com
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program. If not, see <http://www.gnu.org/licenses/>.
+*/
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"github.com/cmmorrow/url/urlkit"
+	"github.com/spf13/cobra"
+)
+
+var rulesFiles []string
+var noDefaultRulesFlag bool
+
+// cleanReport is the JSON output of the clean command.
+type cleanReport struct {
+	URL     string       `json:"url"`
+	Removed urlkit.Query `json:"removed"`
+}
+
+// cleanCmd represents the clean command
+var cleanCmd = &cobra.Command{
+	Use:   "clean [url|-]",
+	Short: "Remove tracking parameters from a URL.",
+	Long: `Remove tracking query parameters such as utm_*, fbclid and gclid from a URL.
+
+A built-in list of rules is used unless --no-default-rules is set. Use --rules
+to add rules from a file. Each line of a rules file is a shell-style pattern
+for a parameter key, optionally preceded by a host pattern and a space. A rule
+with a host only applies to that host and its subdomains. For example:
+
+	# Remove ref from every URL and tag from shop.com and its subdomains.
+	ref
+	shop.com tag
+
+The rest of the URL is left exactly as written. With --json, the removed
+parameters are also displayed.
+
+Examples:
+
+	url clean 'https://mysite.com/post?id=1&utm_source=news&fbclid=abc'
+		https://mysite.com/post?id=1
+
+If no URL is given, or the URL is -, newline delimited URLs are read from
+stdin. Use --file to read URLs from one or more files.`,
+	Args: cobra.MatchAll(cobra.MaximumNArgs(1), batchArgs),
+	Run: func(cmd *cobra.Command, args []string) {
+		rules, err := loadRules()
+		if err != nil {
+			fail(err)
+		}
+		process := func(input string) (string, error) {
+			return cleanString(input, rules)
+		}
+		if isBatch(args) {
+			runBatch(args, process, false)
+			return
+		}
+		out, err := process(args[0])
+		if err != nil {
+			fail(err)
+		}
+		fmt.Print(out)
+	},
+}
+
+func cleanString(input string, rules urlkit.Rules) (string, error) {
+	out, removed, err := urlkit.Clean(unshell(input), rules)
+	if err != nil {
+		return "", err
+	}
+	if !jsonOutputFlag {
+		return out + "\n", nil
+	}
+	if removed == nil {
+		removed = urlkit.Query{}
+	}
+	b, err := json.Marshal(cleanReport{URL: out, Removed: removed})
+	if err != nil {
+		return "", &urlkit.Error{Kind: urlkit.KindJSON, Op: "marshal", Offset: -1, Err: err}
+	}
+	return string(b) + "\n", nil
+}
+
+// loadRules returns the built-in rules and the rules from each --rules file.
+func loadRules() (urlkit.Rules, error) {
+	var rules urlkit.Rules
+	if !noDefaultRulesFlag {
+		rules = urlkit.DefaultRules()
+	}
+	for _, name := range rulesFiles {
+		f, err := os.Open(name)
+		if err != nil {
+			return nil, ioError("open", name, err)
+		}
+		fileRules, err := urlkit.ParseRules(f)
+		f.Close()
+		if err != nil {
+			return nil, err
+		}
+		rules = append(rules, fileRules...)
+	}
+	return rules, nil
+}
+
+func init() {
+	rootCmd.AddCommand(cleanCmd)
+
+	cleanCmd.Flags().StringArrayVar(&rulesFiles, "rules", nil, "Read more rules from a file. Can be repeated.")
+	cleanCmd.Flags().BoolVar(&noDefaultRulesFlag, "no-default-rules", false, "Do not use the built-in rules.")
+	cleanCmd.Flags().BoolVar(&jsonOutputFlag, "json", false, "Output the URL and the removed parameters as JSON.")
+	addBatchFlags(cleanCmd)
+}
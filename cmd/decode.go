@@ -17,45 +17,170 @@ along with this program. If not, see <http://www.gnu.org/licenses/>.
 package cmd
 
 import (
+	"encoding/json"
 	"fmt"
-	"net/url"
-	"os"
 	"strings"
 
+	"github.com/cmmorrow/url/urlkit"
 	"github.com/spf13/cobra"
-	"golang.org/x/net/idna"
 )
 
+var strictFlag bool
+var lenientFlag bool
+var recursiveFlag bool
+var maxDepthFlag int
+
+// decodeReport is the JSON output of the decode command.
+type decodeReport struct {
+	Decoded string                 `json:"decoded"`
+	Charset string                 `json:"charset,omitempty"`
+	Invalid []urlkit.InvalidEscape `json:"invalid"`
+}
+
+// layersReport is the JSON output of the decode command with --recursive.
+type layersReport struct {
+	Layers     []string `json:"layers"`
+	FixedPoint bool     `json:"fixedPoint"`
+}
+
 // decodeCmd represents the decode command
 var decodeCmd = &cobra.Command{
-	Use:   "decode string",
+	Use:   "decode [string|-]",
 	Short: "Decode a URL.",
-	Long:  `Decode a URL encoded string.`,
-	Args:  cobra.MatchAll(cobra.MinimumNArgs(1), cobra.MaximumNArgs(1)),
+	Long: `Decode a URL encoded string.
+
+	A + is decoded to a space unless --component is given with a component
+	other than form. The components are path, path-segment, query,
+	query-value, fragment, userinfo, host and form. With --strict, characters
+	that RFC 3986 does not allow in the component, or in a query if no
+	component is given, must be percent encoded.
+
+	A malformed percent encoding such as %zz is an error unless --lenient is
+	set, in which case it is left as it is. With --json, the byte offset of
+	every malformed percent encoding is displayed with the decoded string.
+
+	With --recursive, the string is decoded again and again until it stops
+	changing or --max-depth layers have been decoded. Each layer is displayed
+	on a new line, starting with the input as layer 0.
+
+	Examples:
+
+		url decode 'a%2Fb+c%2Bd'
+			a/b c+d
+
+		url decode 'a%2Fb+c%2Bd' --component path
+			a/b+c+d
+
+		url decode '100%+off%2' --lenient --json
+			{"decoded":"100% off%2","invalid":[{"offset":3,"escape":"%+o"},{"offset":8,"escape":"%2"}]}
+
+		url decode '%2525252F' --recursive
+			0: %2525252F
+			1: %25252F
+			2: %252F
+			3: %2F
+			4: /
+
+	The decoded bytes are UTF-8 unless --charset names a legacy charset such
+	as shift_jis, euc-kr, gbk or iso-8859-1. With --detect-charset, the
+	charset of decoded bytes that are not valid UTF-8 is guessed, and --json
+	displays the charset that was used.
+
+		url decode '%93%FA%96%7B' --charset shift_jis
+			日本
+
+		url decode '%C7%D1%B1%B9%BE%EE' --detect-charset --json
+			{"decoded":"한국어","charset":"euc-kr","invalid":[]}
+
+	If no string is given, or the string is -, newline delimited strings are
+	read from stdin. Use --file to read strings from one or more files.`,
+	Args: cobra.MatchAll(cobra.MaximumNArgs(1), batchArgs),
 	Run: func(cmd *cobra.Command, args []string) {
-		var input string = args[0]
-		if shell {
-			input = strings.ReplaceAll(input, "\\", "")
-		}
-		if puny {
-			var p *idna.Profile = idna.New()
-			out, err := p.ToUnicode(input)
-			if err != nil {
-				fmt.Printf("Error converting %s.\n", input)
-				os.Exit(1)
+		checkCharset()
+		if componentFlag != "" {
+			if _, err := urlkit.ComponentSet(componentFlag, urlkit.StandardRFC3986); err != nil {
+				fail(err)
 			}
-			fmt.Printf("%s\n", out)
-		} else {
-			decoded, err := url.QueryUnescape(input)
-			if err != nil {
-				fmt.Printf("Error decoding %s.\n", input)
-				os.Exit(1)
-			}
-			fmt.Printf("%s\n", decoded)
 		}
+		if isBatch(args) {
+			runBatch(args, decodeString, false)
+			return
+		}
+		out, err := decodeString(args[0])
+		if err != nil {
+			fail(err)
+		}
+		fmt.Print(out)
 	},
 }
 
+func decodeString(input string) (string, error) {
+	input = unshell(input)
+	if puny {
+		out, err := idnaOptions.ToUnicode(input)
+		if err != nil {
+			return "", err
+		}
+		return out + "\n", nil
+	}
+	opts := urlkit.DecodeOptions{Component: componentFlag, Strict: strictFlag, Lenient: lenientFlag,
+		Charset: charsetFlag, DetectCharset: detectCharsetFlag}
+	if recursiveFlag {
+		return decodeLayers(input, opts)
+	}
+	decoded, err := urlkit.DecodeComponent(input, opts)
+	if err != nil {
+		return "", err
+	}
+	if !jsonOutputFlag {
+		return decoded + "\n", nil
+	}
+	report := decodeReport{Decoded: decoded, Charset: charsetFlag, Invalid: urlkit.InvalidEscapes(input)}
+	if detectCharsetFlag {
+		opts.DetectCharset = false
+		opts.Charset = ""
+		raw, _ := urlkit.DecodeComponent(input, opts)
+		report.Charset = urlkit.DetectCharset(raw)
+	}
+	if report.Invalid == nil {
+		report.Invalid = []urlkit.InvalidEscape{}
+	}
+	b, err := json.Marshal(report)
+	if err != nil {
+		return "", &urlkit.Error{Kind: urlkit.KindJSON, Op: "marshal", Offset: -1, Err: err}
+	}
+	return string(b) + "\n", nil
+}
+
+func decodeLayers(input string, opts urlkit.DecodeOptions) (string, error) {
+	layers, fixed, err := urlkit.DecodeLayers(input, opts, maxDepthFlag)
+	if err != nil {
+		return "", err
+	}
+	if jsonOutputFlag {
+		b, err := json.Marshal(layersReport{Layers: layers, FixedPoint: fixed})
+		if err != nil {
+			return "", &urlkit.Error{Kind: urlkit.KindJSON, Op: "marshal", Offset: -1, Err: err}
+		}
+		return string(b) + "\n", nil
+	}
+	var b strings.Builder
+	for i, layer := range layers {
+		fmt.Fprintf(&b, "%d: %s\n", i, layer)
+	}
+	return b.String(), nil
+}
+
 func init() {
 	rootCmd.AddCommand(decodeCmd)
+
+	decodeCmd.Flags().StringVar(&componentFlag, "component", "", "Decode for a URL component: "+strings.Join(urlkit.ComponentNames, ", ")+".")
+	decodeCmd.Flags().BoolVar(&strictFlag, "strict", false, "Fail on characters RFC 3986 requires to be encoded in the component.")
+	decodeCmd.Flags().BoolVar(&lenientFlag, "lenient", false, "Leave malformed percent encodings as they are.")
+	decodeCmd.Flags().BoolVar(&recursiveFlag, "recursive", false, "Decode until the string stops changing and display each layer.")
+	decodeCmd.Flags().IntVar(&maxDepthFlag, "max-depth", urlkit.DefaultMaxDepth, "Maximum number of layers to decode with --recursive.")
+	decodeCmd.Flags().BoolVar(&jsonOutputFlag, "json", false, "Output the decoded string and any malformed percent encodings as JSON.")
+	addCharsetFlags(decodeCmd, "Charset of the decoded bytes.")
+	decodeCmd.Flags().BoolVar(&detectCharsetFlag, "detect-charset", false, "Guess the charset of decoded bytes that are not valid UTF-8.")
+	addBatchFlags(decodeCmd)
 }
@@ -0,0 +1,143 @@
+/*
+Copyright © 2022 Chris Morrow cmmorrow@gmail.com
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program. If not, see <http://www.gnu.org/licenses/>.
+*/
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/cmmorrow/url/urlkit"
+	"github.com/fatih/color"
+	"github.com/spf13/cobra"
+)
+
+var normalizeFlag bool
+
+// diffReport is the JSON output of the diff command.
+type diffReport struct {
+	A       string          `json:"a"`
+	B       string          `json:"b"`
+	Equal   bool            `json:"equal"`
+	Changes []urlkit.Change `json:"changes"`
+}
+
+// diffCmd represents the diff command
+var diffCmd = &cobra.Command{
+	Use:   "diff url url",
+	Short: "Compare two URLs.",
+	Long: `Compare two URLs component by component.
+
+Each component or query parameter that was added, removed or changed from
+the first URL to the second is displayed on a new line, prefixed with +, -
+or ~. Nothing is displayed if the URLs are the same.
+
+Use --normalize to compare the URLs after RFC 3986 normalization so that
+differences such as the case of the host or a default port are ignored.
+
+Examples:
+
+	url diff 'https://mysite.com/a?page=1&tag=x' 'https://mysite.com/b?page=2&tag=x&tag=y'
+		~ path: /a -> /b
+		~ param page: 1 -> 2
+		+ param tag: y`,
+	Args: cobra.ExactArgs(2),
+	Run: func(cmd *cobra.Command, args []string) {
+		a, b := unshell(args[0]), unshell(args[1])
+		changes, err := diffURLs(a, b)
+		if err != nil {
+			fail(err)
+		}
+		if jsonOutputFlag {
+			report := diffReport{A: a, B: b, Equal: len(changes) == 0, Changes: changes}
+			if report.Changes == nil {
+				report.Changes = []urlkit.Change{}
+			}
+			out, err := json.Marshal(report)
+			if err != nil {
+				fail(&urlkit.Error{Kind: urlkit.KindJSON, Op: "marshal", Offset: -1, Err: err})
+			}
+			fmt.Println(string(out))
+			return
+		}
+		displayChanges(os.Stdout, changes)
+	},
+}
+
+// diffURLs parses both URLs, normalizing them first if --normalize is set,
+// and returns the changes from a to b.
+func diffURLs(a string, b string) ([]urlkit.Change, error) {
+	var components [2]urlkit.Components
+	for i, input := range []string{a, b} {
+		if normalizeFlag {
+			var err error
+			if input, err = urlkit.Normalize(input, urlkit.DefaultNormalizeOptions); err != nil {
+				return nil, err
+			}
+		}
+		u, err := urlkit.Parse(input)
+		if err != nil {
+			return nil, err
+		}
+		if components[i], err = urlkit.Split(u, parseOptions()); err != nil {
+			return nil, err
+		}
+	}
+	return urlkit.Diff(components[0], components[1]), nil
+}
+
+func displayChanges(w io.Writer, changes []urlkit.Change) {
+	colors := map[string]*color.Color{
+		urlkit.Added:     color.New(color.FgGreen),
+		urlkit.Removed:   color.New(color.FgRed),
+		urlkit.Changed:   color.New(color.FgYellow),
+		urlkit.Reordered: color.New(color.FgYellow),
+	}
+	if noColorFlag {
+		for _, c := range colors {
+			c.DisableColor()
+		}
+	}
+	for _, change := range changes {
+		name := change.Component
+		if change.Key != "" || name == paramLabel {
+			name += " " + change.Key
+		}
+		var line string
+		switch change.Kind {
+		case urlkit.Added:
+			line = fmt.Sprintf("+ %s: %s", name, change.New)
+		case urlkit.Removed:
+			line = fmt.Sprintf("- %s: %s", name, change.Old)
+		case urlkit.Changed:
+			line = fmt.Sprintf("~ %s: %s -> %s", name, change.Old, change.New)
+		case urlkit.Reordered:
+			line = fmt.Sprintf("~ %s: values reordered", name)
+		}
+		fmt.Fprintln(w, colors[change.Kind].Sprint(line))
+	}
+}
+
+func init() {
+	rootCmd.AddCommand(diffCmd)
+
+	diffCmd.Flags().BoolVar(&normalizeFlag, "normalize", false, "Normalize both URLs before comparing them.")
+	diffCmd.Flags().BoolVar(&noColorFlag, "no-color", false, "Suppress color text output.")
+	diffCmd.Flags().BoolVar(&jsonOutputFlag, "json", false, "Output as JSON.")
+	diffCmd.Flags().BoolVar(&noDecodeFlag, "no-decode", false, "Do not URL decode paths and query parameters.")
+}
@@ -18,39 +18,116 @@ package cmd
 
 import (
 	"fmt"
-	"net/url"
-	"os"
 	"strings"
 
+	"github.com/cmmorrow/url/urlkit"
 	"github.com/spf13/cobra"
-	"golang.org/x/net/idna"
 )
 
+var componentFlag string
+var standardFlag string
+var safeFlag string
+var unsafeFlag string
+
 // encodeCmd represents the encode command
 var encodeCmd = &cobra.Command{
-	Use:   "encode string",
+	Use:   "encode [string|-]",
 	Short: "Encode a URL.",
-	Long:  `Percent encode a string into valid URL.`,
-	Args:  cobra.MatchAll(cobra.MinimumNArgs(1), cobra.MaximumNArgs(1)),
+	Long: `Percent encode a string into valid URL.
+
+	Use --component to encode the string for a particular part of a URL with
+	the percent-encode set of RFC 3986, or of the WHATWG URL Standard with
+	--standard whatwg. The components are path, path-segment, query,
+	query-value, fragment, userinfo, host and form. Use --safe and --unsafe
+	to list more ASCII characters to leave as they are or to encode.
+
+	Examples:
+
+		url encode 'a/b c+d' --component path
+			a/b%20c+d
+
+		url encode 'a/b c+d' --component query-value
+			a/b%20c%2Bd
+
+		url encode 'a/b c+d' --component form
+			a%2Fb+c%2Bd
+
+		url encode '日本' --charset shift_jis
+			%93%FA%96%7B
+
+	With --charset, the string is converted to a legacy charset such as
+	shift_jis, euc-kr, gbk or iso-8859-1 before it is percent encoded.
+
+	If no string is given, or the string is -, newline delimited strings are
+	read from stdin. Use --file to read strings from one or more files.`,
+	Args: cobra.MatchAll(cobra.MaximumNArgs(1), batchArgs),
 	Run: func(cmd *cobra.Command, args []string) {
-		var input string = args[0]
-		if shell {
-			input = strings.ReplaceAll(input, "\\", "")
+		checkCharset()
+		encode, err := encoder()
+		if err != nil {
+			fail(err)
 		}
-		if puny {
-			var p *idna.Profile = idna.New()
-			out, err := p.ToASCII(input)
-			if err != nil {
-				fmt.Printf("Error coverting %s to punycode.\n", input)
-				os.Exit(1)
-			}
-			fmt.Printf("%s\n", out)
-		} else {
-			fmt.Printf("%s\n", url.PathEscape(input))
+		process := func(input string) (string, error) {
+			return encodeString(input, encode)
 		}
+		if isBatch(args) {
+			runBatch(args, process, false)
+			return
+		}
+		out, err := process(args[0])
+		if err != nil {
+			fail(err)
+		}
+		fmt.Print(out)
 	},
 }
 
+// encoder returns the function that percent encodes with the flags given.
+// Without --component, --safe or --unsafe a path segment is encoded as
+// url.PathEscape does.
+func encoder() (func(string) string, error) {
+	if componentFlag == "" && safeFlag == "" && unsafeFlag == "" {
+		return urlkit.Encode, nil
+	}
+	component := componentFlag
+	if component == "" {
+		component = urlkit.ComponentPathSegment
+	}
+	set, err := urlkit.ComponentSet(component, standardFlag)
+	if err != nil {
+		return nil, err
+	}
+	set = set.Without(unsafeFlag).With(safeFlag)
+	return func(s string) string {
+		return urlkit.EncodeComponent(s, set)
+	}, nil
+}
+
+func encodeString(input string, encode func(string) string) (string, error) {
+	input = unshell(input)
+	if puny {
+		out, err := idnaOptions.ToASCII(input)
+		if err != nil {
+			return "", err
+		}
+		return out + "\n", nil
+	}
+	if charsetFlag != "" {
+		var err error
+		if input, err = urlkit.ToCharset(input, charsetFlag); err != nil {
+			return "", err
+		}
+	}
+	return encode(input) + "\n", nil
+}
+
 func init() {
 	rootCmd.AddCommand(encodeCmd)
+
+	encodeCmd.Flags().StringVar(&componentFlag, "component", "", "Encode for a URL component: "+strings.Join(urlkit.ComponentNames, ", ")+".")
+	encodeCmd.Flags().StringVar(&standardFlag, "standard", urlkit.StandardRFC3986, "Percent-encode sets to use: rfc3986 or whatwg.")
+	encodeCmd.Flags().StringVar(&safeFlag, "safe", "", "ASCII characters to leave as they are.")
+	encodeCmd.Flags().StringVar(&unsafeFlag, "unsafe", "", "ASCII characters to always encode.")
+	addCharsetFlags(encodeCmd, "Charset to convert the string to before encoding.")
+	addBatchFlags(encodeCmd)
 }
@@ -0,0 +1,158 @@
+/*
+Copyright © 2022 Chris Morrow cmmorrow@gmail.com
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program. If not, see <http://www.gnu.org/licenses/>.
+*/
+package cmd
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sync/atomic"
+
+	"github.com/cmmorrow/url/urlkit"
+)
+
+// Exit codes returned by the url command.
+const (
+	exitOK           = 0
+	exitUsage        = 1
+	exitInvalidInput = 2
+	exitIDNA         = 3
+	exitJSON         = 4
+	exitValidation   = 5
+	exitIO           = 6
+)
+
+const textErrorFormat = "text"
+const jsonErrorFormat = "json"
+
+var errorFormat string
+
+// validationFailed is set when a check fails for an input that is otherwise
+// processed successfully, so that the command exits with exitValidation.
+var validationFailed int32
+
+// errorReport is the JSON object written to stderr with --error-format json.
+type errorReport struct {
+	Kind    string `json:"kind"`
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+	Input   string `json:"input,omitempty"`
+	Offset  *int   `json:"offset,omitempty"`
+	Source  string `json:"source,omitempty"`
+	Line    int    `json:"line,omitempty"`
+}
+
+// exitCode returns the exit status for err.
+func exitCode(err error) int {
+	switch urlkit.KindOf(err) {
+	case urlkit.KindInvalidInput:
+		return exitInvalidInput
+	case urlkit.KindIDNA:
+		return exitIDNA
+	case urlkit.KindJSON:
+		return exitJSON
+	case urlkit.KindValidation:
+		return exitValidation
+	case urlkit.KindIO:
+		return exitIO
+	}
+	return exitUsage
+}
+
+// ioError wraps a failure to read or write source.
+func ioError(op string, source string, err error) error {
+	return &urlkit.Error{Kind: urlkit.KindIO, Op: op, Input: source, Offset: -1, Err: err}
+}
+
+// reportError writes err to w in the --error-format. In batch mode, rec is
+// the input that caused the error, otherwise it is nil.
+func reportError(w io.Writer, err error, rec *InputRecord) {
+	if errorFormat != jsonErrorFormat {
+		if rec != nil {
+			fmt.Fprintf(w, "%s:%d: %s\n", rec.Source, rec.Line, err)
+		} else {
+			fmt.Fprintf(w, "Error: %s\n", err)
+		}
+		return
+	}
+
+	report := errorReport{
+		Kind:    urlkit.KindOf(err).String(),
+		Code:    exitCode(err),
+		Message: err.Error(),
+	}
+	if report.Code == exitUsage {
+		report.Kind = "usage"
+	}
+	var e *urlkit.Error
+	if errors.As(err, &e) {
+		report.Message = e.Err.Error()
+		report.Input = e.Input
+		if e.Offset >= 0 {
+			offset := e.Offset
+			report.Offset = &offset
+		}
+	}
+	if rec != nil {
+		report.Source = rec.Source
+		report.Line = rec.Line
+	}
+	b, _ := json.Marshal(report)
+	fmt.Fprintln(w, string(b))
+}
+
+// reportWarning writes a finding that does not stop input from being
+// processed to w in the --error-format. In batch mode, rec is the input the
+// finding is about, otherwise it is nil.
+func reportWarning(w io.Writer, f urlkit.Finding, input string, rec *InputRecord) {
+	if errorFormat != jsonErrorFormat {
+		if rec != nil {
+			fmt.Fprintf(w, "%s:%d: Warning: %s\n", rec.Source, rec.Line, f)
+		} else {
+			fmt.Fprintf(w, "Warning: %s\n", f)
+		}
+		return
+	}
+	report := errorReport{Kind: "warning", Code: exitOK, Message: f.String(), Input: input}
+	if rec != nil {
+		report.Source = rec.Source
+		report.Line = rec.Line
+	}
+	b, _ := json.Marshal(report)
+	fmt.Fprintln(w, string(b))
+}
+
+// markValidationFailed records that a check failed. It is safe to call from
+// the batch workers.
+func markValidationFailed() {
+	atomic.StoreInt32(&validationFailed, 1)
+}
+
+// exitStatus exits with exitValidation if a check failed.
+func exitStatus() {
+	if atomic.LoadInt32(&validationFailed) != 0 {
+		os.Exit(exitValidation)
+	}
+}
+
+// fail reports err and exits with its exit code.
+func fail(err error) {
+	reportError(os.Stderr, err, nil)
+	os.Exit(exitCode(err))
+}
@@ -0,0 +1,68 @@
+/*
+Copyright © 2022 Chris Morrow cmmorrow@gmail.com
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program. If not, see <http://www.gnu.org/licenses/>.
+*/
+package cmd
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/cmmorrow/url/urlkit"
+	"github.com/spf13/cobra"
+)
+
+var idnaProfileFlag string
+var idnaTransitionalFlag bool
+var idnaSTD3Flag bool
+var idnaBidiFlag bool
+var idnaContextJFlag bool
+
+// idnaOptions are the UTS #46 options --puny converts domains with. They are
+// set from the --idna-* flags before a command runs.
+var idnaOptions urlkit.IDNAOptions
+
+// setIDNAOptions sets idnaOptions from the defaults of --idna-profile and
+// the --idna-* flags that are given.
+func setIDNAOptions(cmd *cobra.Command) error {
+	opts, err := urlkit.DefaultIDNAOptions(idnaProfileFlag)
+	if err != nil {
+		return fmt.Errorf("invalid IDNA profile %q, use %s", idnaProfileFlag, strings.Join(urlkit.IDNAProfileNames, ", "))
+	}
+	flags := cmd.Flags()
+	if flags.Changed("idna-transitional") {
+		opts.Transitional = idnaTransitionalFlag
+	}
+	if flags.Changed("idna-std3") {
+		opts.STD3 = idnaSTD3Flag
+	}
+	if flags.Changed("idna-bidi") {
+		opts.CheckBidi = idnaBidiFlag
+	}
+	if flags.Changed("idna-contextj") {
+		opts.CheckJoiners = idnaContextJFlag
+	}
+	idnaOptions = opts
+	return nil
+}
+
+func init() {
+	flags := rootCmd.PersistentFlags()
+	flags.StringVar(&idnaProfileFlag, "idna-profile", urlkit.IDNAPunycode, "UTS #46 profile for --puny: "+strings.Join(urlkit.IDNAProfileNames, ", ")+".")
+	flags.BoolVar(&idnaTransitionalFlag, "idna-transitional", false, "Use transitional processing, mapping ß to ss and ς to σ.")
+	flags.BoolVar(&idnaSTD3Flag, "idna-std3", false, "Only allow letters, digits and hyphens in ASCII labels. On by default except for the punycode profile.")
+	flags.BoolVar(&idnaBidiFlag, "idna-bidi", false, "Check the Bidi rule for right-to-left domains. On by default except for the punycode profile.")
+	flags.BoolVar(&idnaContextJFlag, "idna-contextj", false, "Check the CONTEXTJ rules for zero width joiners. On by default except for the punycode profile.")
+}
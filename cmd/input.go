@@ -0,0 +1,134 @@
+/*
+Copyright © 2022 Chris Morrow cmmorrow@gmail.com
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program. If not, see <http://www.gnu.org/licenses/>.
+*/
+package cmd
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/spf13/cobra"
+)
+
+const stdinName = "-"
+
+// maxLineSize is the longest single input record accepted in batch mode.
+const maxLineSize = 1024 * 1024
+
+// InputRecord is a single input read in batch mode along with where it came from.
+type InputRecord struct {
+	Source string
+	Line   int
+	Text   string
+}
+
+// Batch holds the options for reading and processing inputs in batch mode.
+type Batch struct {
+	// Files are read in order after stdin, which is only read if there are
+	// no files or the first argument is -.
+	Files []string
+	// Null delimits inputs by NUL instead of newline.
+	Null bool
+	// Jobs is the number of inputs to process in parallel.
+	Jobs int
+	// Unordered outputs results as they finish instead of in input order.
+	Unordered bool
+	Stdin     io.Reader
+	Stdout    io.Writer
+	Stderr    io.Writer
+}
+
+// batch is the Batch that the batch flags of every command are bound to.
+var batch = Batch{Stdin: os.Stdin, Stdout: os.Stdout, Stderr: os.Stderr}
+
+// isBatch returns true when the inputs should be read from stdin or files
+// rather than taken from the command-line arguments.
+func isBatch(args []string) bool {
+	return len(batch.Files) > 0 || len(args) == 0 || args[0] == stdinName
+}
+
+// ReadInputs reads newline or NUL delimited records from stdin and each of
+// the files and calls fn for every non-empty record.
+func (b *Batch) ReadInputs(args []string, fn func(InputRecord)) error {
+	sources := b.Files
+	if len(args) > 0 && args[0] == stdinName || len(sources) == 0 {
+		sources = append([]string{stdinName}, sources...)
+	}
+	for _, source := range sources {
+		if err := b.readSource(source, fn); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (b *Batch) readSource(source string, fn func(InputRecord)) error {
+	r := b.Stdin
+	if source != stdinName {
+		f, err := os.Open(source)
+		if err != nil {
+			return ioError("open", source, err)
+		}
+		defer f.Close()
+		r = f
+	}
+
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), maxLineSize)
+	if b.Null {
+		scanner.Split(scanNull)
+	}
+	line := 0
+	for scanner.Scan() {
+		line++
+		text := strings.TrimRight(scanner.Text(), "\r\n")
+		if strings.TrimSpace(text) == "" {
+			continue
+		}
+		fn(InputRecord{Source: source, Line: line, Text: text})
+	}
+	if err := scanner.Err(); err != nil {
+		return ioError("read", source, err)
+	}
+	return nil
+}
+
+// scanNull is a bufio.SplitFunc that splits on NUL bytes.
+func scanNull(data []byte, atEOF bool) (advance int, token []byte, err error) {
+	if atEOF && len(data) == 0 {
+		return 0, nil, nil
+	}
+	if i := bytes.IndexByte(data, 0); i >= 0 {
+		return i + 1, data[:i], nil
+	}
+	if atEOF {
+		return len(data), data, nil
+	}
+	return 0, nil, nil
+}
+
+// batchArgs is a cobra.PositionalArgs that requires a single input unless
+// the inputs are being read from files.
+func batchArgs(cmd *cobra.Command, args []string) error {
+	if len(batch.Files) > 0 && len(args) > 0 && args[0] != stdinName {
+		return fmt.Errorf("cannot use an argument with --file, use - to also read from stdin")
+	}
+	return nil
+}
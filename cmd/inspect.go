@@ -0,0 +1,216 @@
+/*
+Copyright © 2022 Chris Morrow cmmorrow@gmail.com
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program. If not, see <http://www.gnu.org/licenses/>.
+*/
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/cmmorrow/url/urlkit"
+	"github.com/spf13/cobra"
+)
+
+var doubleEncodingFlag bool
+var homographFlag bool
+var brandFlags []string
+var brandsFiles []string
+var redirectsFlag bool
+var trustedHostFlags []string
+var trustedHostsFiles []string
+var differentialFlag bool
+
+// inspectReport is the JSON output of the inspect command.
+type inspectReport struct {
+	URL      string           `json:"url"`
+	Findings []urlkit.Finding `json:"findings"`
+	// Parsers are the components read by each parser if the differential
+	// check is run.
+	Parsers []urlkit.ParserResult `json:"parsers,omitempty"`
+}
+
+// inspectCmd represents the inspect command
+var inspectCmd = &cobra.Command{
+	Use:   "inspect [url|-]",
+	Short: "Check a URL for suspicious features.",
+	Long: `Check a URL for features that are often used to hide or smuggle content.
+
+Select the checks to run with flags. Every check except --differential is
+run if none are given. The other checks need a URL that Go's net/url
+accepts, so a URL that it rejects but another parser accepts is reported by
+--differential instead, even if no checks are given.
+
+	--double-encoding  components that still contain percent encodings after
+	                   they are decoded, such as %252F
+	--homograph        labels of the host that mix scripts or are confusable
+	                   with ASCII per UTS #39, hosts confusable with a brand
+	                   domain given with --brand or --brands, and invisible
+	                   characters anywhere in the URL
+	--redirects        query and fragment parameters that could redirect off
+	                   the host of the URL: absolute and scheme-relative URLs
+	                   to other hosts, backslash tricks such as /\evil.com,
+	                   and javascript: and data: URLs. Values are decoded
+	                   until they stop changing and URLs to trusted hosts in
+	                   values are checked too. Add trusted hosts with
+	                   --trusted-host or --trusted-hosts.
+	--differential     components that Go's net/url, a strict RFC 3986 parser
+	                   and a WHATWG parser, as used by browsers, read
+	                   differently, such as a host that depends on how \ or
+	                   @ is handled, and URLs that only some of them reject.
+	                   With --json, the components read by each parser are
+	                   included.
+
+A URL with findings is displayed followed by one finding per line. Nothing is
+displayed for a URL without findings. The exit status is 5 if there are any
+findings.
+
+Examples:
+
+	url inspect 'https://mysite.com/files/..%252F..%252Fetc/passwd' --double-encoding
+		https://mysite.com/files/..%252F..%252Fetc/passwd
+		  double-encoding: path: encoded 2 times, decodes to /files/../../etc/passwd
+
+	url inspect 'https://xn--pple-43d.com/' --homograph --brand apple.com
+		https://xn--pple-43d.com/
+		  mixed-script: host: label аpple mixes the Cyrillic and Latin scripts
+		  confusable: host: label аpple is confusable with apple
+		  confusable: host: аpple.com is confusable with the brand domain apple.com
+
+	url inspect 'https://mysite.com/login?next=%2F%5Cevil.com' --redirects
+		https://mysite.com/login?next=%2F%5Cevil.com
+		  open-redirect: param next: URL with a backslash that browsers read as a URL to untrusted host evil.com
+
+	url inspect 'http://mysite.com\@evil.com/' --differential
+		http://mysite.com\@evil.com/
+		  parser-differential: rejected by go (net/url: invalid userinfo) and rfc3986 (invalid character '\\' in userinfo), accepted by whatwg
+
+If no URL is given, or the URL is -, newline delimited URLs are read from
+stdin. Use --file to read URLs from one or more files.`,
+	Args: cobra.MatchAll(cobra.MaximumNArgs(1), batchArgs),
+	Run: func(cmd *cobra.Command, args []string) {
+		if err := loadBrands(); err != nil {
+			fail(err)
+		}
+		if err := loadTrustedHosts(); err != nil {
+			fail(err)
+		}
+		if isBatch(args) {
+			runBatch(args, inspectString, false)
+			return
+		}
+		out, err := inspectString(args[0])
+		if err != nil {
+			fail(err)
+		}
+		fmt.Print(out)
+		exitStatus()
+	},
+}
+
+func inspectString(input string) (string, error) {
+	input = unshell(input)
+	findings, parsers, err := urlkit.Inspect(input, urlkit.InspectOptions{
+		DoubleEncoding: doubleEncodingFlag,
+		Homograph:      homographFlag,
+		Brands:         brands,
+		Redirects:      redirectsFlag,
+		TrustedHosts:   trustedHosts,
+		Differential:   differentialFlag,
+	})
+	if err != nil {
+		return "", err
+	}
+	if len(findings) > 0 {
+		markValidationFailed()
+	}
+	if jsonOutputFlag {
+		if findings == nil {
+			findings = []urlkit.Finding{}
+		}
+		b, err := json.Marshal(inspectReport{URL: input, Findings: findings, Parsers: parsers})
+		if err != nil {
+			return "", &urlkit.Error{Kind: urlkit.KindJSON, Op: "marshal", Offset: -1, Err: err}
+		}
+		return string(b) + "\n", nil
+	}
+	if len(findings) == 0 {
+		return "", nil
+	}
+	var b strings.Builder
+	b.WriteString(input + "\n")
+	for _, f := range findings {
+		b.WriteString("  " + f.String() + "\n")
+	}
+	return b.String(), nil
+}
+
+// brands are the domains given with --brand and read from --brands files.
+var brands urlkit.Brands
+
+func loadBrands() error {
+	brands = append(urlkit.Brands{}, brandFlags...)
+	for _, name := range brandsFiles {
+		f, err := os.Open(name)
+		if err != nil {
+			return ioError("open", name, err)
+		}
+		fileBrands, err := urlkit.ParseBrands(f)
+		f.Close()
+		if err != nil {
+			return err
+		}
+		brands = append(brands, fileBrands...)
+	}
+	return nil
+}
+
+// trustedHosts are the hosts given with --trusted-host and read from
+// --trusted-hosts files.
+var trustedHosts urlkit.TrustedHosts
+
+func loadTrustedHosts() error {
+	trustedHosts = append(urlkit.TrustedHosts{}, trustedHostFlags...)
+	for _, name := range trustedHostsFiles {
+		f, err := os.Open(name)
+		if err != nil {
+			return ioError("open", name, err)
+		}
+		fileHosts, err := urlkit.ParseTrustedHosts(f)
+		f.Close()
+		if err != nil {
+			return err
+		}
+		trustedHosts = append(trustedHosts, fileHosts...)
+	}
+	return nil
+}
+
+func init() {
+	rootCmd.AddCommand(inspectCmd)
+
+	inspectCmd.Flags().BoolVar(&doubleEncodingFlag, "double-encoding", false, "Check for components that are percent encoded more than once.")
+	inspectCmd.Flags().BoolVar(&homographFlag, "homograph", false, "Check for mixed scripts, confusable characters and invisible characters.")
+	inspectCmd.Flags().StringArrayVar(&brandFlags, "brand", nil, "Brand domain that hosts must not be confusable with. Can be repeated.")
+	inspectCmd.Flags().StringArrayVar(&brandsFiles, "brands", nil, "Read brand domains from a file, one per line. Can be repeated.")
+	inspectCmd.Flags().BoolVar(&redirectsFlag, "redirects", false, "Check for parameters that redirect to untrusted hosts or javascript: URLs.")
+	inspectCmd.Flags().StringArrayVar(&trustedHostFlags, "trusted-host", nil, "Host pattern that parameters may redirect to, with its subdomains. Can be repeated.")
+	inspectCmd.Flags().StringArrayVar(&trustedHostsFiles, "trusted-hosts", nil, "Read trusted host patterns from a file, one per line. Can be repeated.")
+	inspectCmd.Flags().BoolVar(&differentialFlag, "differential", false, "Check for components that Go, RFC 3986 and WHATWG parsers read differently.")
+	inspectCmd.Flags().BoolVar(&jsonOutputFlag, "json", false, "Output the findings as JSON.")
+	addBatchFlags(inspectCmd)
+}
@@ -0,0 +1,143 @@
+/*
+Copyright © 2022 Chris Morrow cmmorrow@gmail.com
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program. If not, see <http://www.gnu.org/licenses/>.
+*/
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/cmmorrow/url/urlkit"
+	"github.com/spf13/cobra"
+)
+
+var matchTemplates []string
+
+// matchReport is the JSON output of the match command.
+type matchReport struct {
+	Template  string              `json:"template"`
+	Variables urlkit.TemplateVars `json:"variables"`
+}
+
+// matchCmd represents the match command
+var matchCmd = &cobra.Command{
+	Use:   "match --template TEMPLATE [url|-]",
+	Short: "Extract the variables of a URI Template from a URL.",
+	Long: `Match a URL against RFC 6570 URI Templates and display the values of the
+template's variables. This is the inverse of build --template.
+
+If a template has no scheme or host, such as /users/{id}, it is matched
+against the path, query and fragment of the URL. --template can be repeated
+to classify URLs by route, in which case the first template that matches is
+used. Form-style query parameters that a template does not name only match
+an exploded variable, such as rest in {?page,rest*}.
+
+A value with a comma is displayed as a list, and exploded name=value pairs as
+an associative array. With --json, the variables are an object that can be
+given to build --vars. A URL that matches no template is an error, and the
+exit status is 5.
+
+Examples:
+
+	url match --template '/users/{id}/repos{?page}' 'https://api.mysite.com/users/42/repos?page=2'
+		template:	/users/{id}/repos{?page}
+		id:	42
+		page:	2
+
+	url match --template '/users/{id}' --template '/users/{id}/repos{?page}' '/users/42/repos' --json
+		{"template":"/users/{id}/repos{?page}","variables":{"id":"42"}}
+
+If no URL is given, or the URL is -, newline delimited URLs are read from
+stdin. Use --file to read URLs from one or more files.`,
+	Args: cobra.MatchAll(cobra.MaximumNArgs(1), batchArgs),
+	Run: func(cmd *cobra.Command, args []string) {
+		templates, err := parseTemplates()
+		if err != nil {
+			fail(err)
+		}
+		process := func(input string) (string, error) {
+			return matchString(input, templates)
+		}
+		if isBatch(args) {
+			runBatch(args, process, !jsonOutputFlag)
+			return
+		}
+		out, err := process(args[0])
+		if err != nil {
+			fail(err)
+		}
+		fmt.Print(out)
+	},
+}
+
+func matchString(input string, templates []*urlkit.Template) (string, error) {
+	input = unshell(input)
+	for _, tmpl := range templates {
+		vars, ok := tmpl.Match(input)
+		if !ok {
+			continue
+		}
+		if jsonOutputFlag {
+			b, err := json.Marshal(matchReport{Template: tmpl.String(), Variables: vars})
+			if err != nil {
+				return "", &urlkit.Error{Kind: urlkit.KindJSON, Op: "marshal", Offset: -1, Err: err}
+			}
+			return string(b) + "\n", nil
+		}
+		var b strings.Builder
+		displayComponent(&b, "template", tmpl.String())
+		for _, name := range tmpl.Variables() {
+			switch value := vars[name].(type) {
+			case string:
+				displayComponent(&b, name, value)
+			case []string:
+				for _, item := range value {
+					displayComponent(&b, name, item)
+				}
+			case urlkit.Query:
+				for _, p := range value {
+					displayComponent(&b, name, p.Key+"="+p.Value)
+				}
+			}
+		}
+		return b.String(), nil
+	}
+	return "", &urlkit.Error{Kind: urlkit.KindValidation, Op: "match", Input: input, Offset: -1,
+		Err: fmt.Errorf("does not match any template")}
+}
+
+// parseTemplates parses each --template.
+func parseTemplates() ([]*urlkit.Template, error) {
+	var templates []*urlkit.Template
+	for _, t := range matchTemplates {
+		tmpl, err := urlkit.ParseTemplate(t)
+		if err != nil {
+			return nil, err
+		}
+		templates = append(templates, tmpl)
+	}
+	return templates, nil
+}
+
+func init() {
+	rootCmd.AddCommand(matchCmd)
+
+	matchCmd.Flags().StringArrayVar(&matchTemplates, "template", nil, "RFC 6570 URI Template to match. Can be repeated.")
+	matchCmd.MarkFlagRequired("template")
+	matchCmd.Flags().BoolVar(&jsonOutputFlag, "json", false, "Output the template and its variables as JSON.")
+	addBatchFlags(matchCmd)
+}
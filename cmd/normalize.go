@@ -0,0 +1,111 @@
+/*
+Copyright © 2022 Chris Morrow cmmorrow@gmail.com
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program. If not, see <http://www.gnu.org/licenses/>.
+*/
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/cmmorrow/url/urlkit"
+	"github.com/spf13/cobra"
+)
+
+var noLowercaseFlag bool
+var noUppercaseEscapesFlag bool
+var noDecodeUnreservedFlag bool
+var noDotSegmentsFlag bool
+var noDefaultPortFlag bool
+var noRootPathFlag bool
+var dropEmptyQueryFlag bool
+var dropEmptyFragmentFlag bool
+var dropTrailingSlashFlag bool
+
+// normalizeCmd represents the normalize command
+var normalizeCmd = &cobra.Command{
+	Use:   "normalize [string|-]",
+	Short: "Normalize a URL.",
+	Long: `Convert a URL to a canonical form following RFC 3986 normalization.
+
+By default the scheme and host are lowercased, percent encodings are
+uppercased, unreserved characters are decoded, dot segments are removed from
+the path, default ports are removed and an empty path becomes /. Each of these
+can be turned off. Sorting query parameters, dropping an empty query or
+fragment and removing a trailing slash are turned on with flags.
+
+Examples:
+
+	url normalize 'HTTP://My.Site.com:80/a/./b/../c/%7euser?q=%3f'
+		http://my.site.com/a/c/~user?q=%3F
+
+	url normalize 'https://mysite.com/docs/?b=2&a=1' --sort-params --drop-trailing-slash
+		https://mysite.com/docs?a=1&b=2
+
+If no URL is given, or the URL is -, newline delimited URLs are read from
+stdin. Use --file to read URLs from one or more files.`,
+	Args: cobra.MatchAll(cobra.MaximumNArgs(1), batchArgs),
+	Run: func(cmd *cobra.Command, args []string) {
+		if isBatch(args) {
+			runBatch(args, normalizeString, false)
+			return
+		}
+		out, err := normalizeString(args[0])
+		if err != nil {
+			fail(err)
+		}
+		fmt.Print(out)
+	},
+}
+
+func normalizeString(input string) (string, error) {
+	out, err := urlkit.Normalize(unshell(input), normalizeOptions())
+	if err != nil {
+		return "", err
+	}
+	return out + "\n", nil
+}
+
+func normalizeOptions() urlkit.NormalizeOptions {
+	return urlkit.NormalizeOptions{
+		Lowercase:           !noLowercaseFlag,
+		UppercaseEscapes:    !noUppercaseEscapesFlag,
+		DecodeUnreserved:    !noDecodeUnreservedFlag,
+		RemoveDotSegments:   !noDotSegmentsFlag,
+		RemoveDefaultPort:   !noDefaultPortFlag,
+		RootPath:            !noRootPathFlag,
+		SortParams:          sortParamsFlag,
+		RemoveEmptyQuery:    dropEmptyQueryFlag,
+		RemoveEmptyFragment: dropEmptyFragmentFlag,
+		RemoveTrailingSlash: dropTrailingSlashFlag,
+		Puny:                puny,
+		IDNA:                idnaOptions,
+	}
+}
+
+func init() {
+	rootCmd.AddCommand(normalizeCmd)
+
+	normalizeCmd.Flags().BoolVar(&noLowercaseFlag, "no-lowercase", false, "Do not lowercase the scheme and host.")
+	normalizeCmd.Flags().BoolVar(&noUppercaseEscapesFlag, "no-uppercase-escapes", false, "Do not uppercase percent encodings.")
+	normalizeCmd.Flags().BoolVar(&noDecodeUnreservedFlag, "no-decode-unreserved", false, "Do not decode percent encoded unreserved characters.")
+	normalizeCmd.Flags().BoolVar(&noDotSegmentsFlag, "no-dot-segments", false, "Do not remove . and .. segments from the path.")
+	normalizeCmd.Flags().BoolVar(&noDefaultPortFlag, "no-default-port", false, "Do not remove the default port for the scheme.")
+	normalizeCmd.Flags().BoolVar(&noRootPathFlag, "no-root-path", false, "Do not change an empty path to /.")
+	normalizeCmd.Flags().BoolVar(&sortParamsFlag, "sort-params", false, "Sort query parameters by key.")
+	normalizeCmd.Flags().BoolVar(&dropEmptyQueryFlag, "drop-empty-query", false, "Remove a ? with no query.")
+	normalizeCmd.Flags().BoolVar(&dropEmptyFragmentFlag, "drop-empty-fragment", false, "Remove a # with no fragment.")
+	normalizeCmd.Flags().BoolVar(&dropTrailingSlashFlag, "drop-trailing-slash", false, "Remove a trailing / from the path.")
+	addBatchFlags(normalizeCmd)
+}
@@ -17,15 +17,15 @@ along with this program. If not, see <http://www.gnu.org/licenses/>.
 package cmd
 
 import (
-	"encoding/json"
 	"fmt"
-	"net/url"
+	"io"
 	"os"
+	"strconv"
 	"strings"
 
+	"github.com/cmmorrow/url/urlkit"
 	"github.com/fatih/color"
 	"github.com/spf13/cobra"
-	"golang.org/x/net/idna"
 )
 
 var schemeFlag bool
@@ -36,197 +36,303 @@ var portFlag bool
 var pathFlag bool
 var fragmentFlag bool
 var paramsFlag bool
+var tldFlag bool
+var registrableDomainFlag bool
+var subdomainFlag bool
+var labelsFlag bool
+var ipFlag bool
+var ipClassFlag bool
 
 var noColorFlag bool
 var jsonOutputFlag bool
 var noDecodeFlag bool
+var sortParamsFlag bool
+var pslFile string
+var icannOnlyFlag bool
+var parserFlag string
+
+// suffixes is the Public Suffix List the host is split with, set by
+// loadSuffixes.
+var suffixes *urlkit.SuffixList
 
 // parseCmd represents the parse command
 var parseCmd = &cobra.Command{
-	Use:   "parse string",
+	Use:   "parse [string|-]",
 	Short: "Parse a URL into its components.",
 	Long: `Parse a URL into its primary components.
 	
-	Each matching component is displayed on a new line.`,
-	Args: cobra.MatchAll(cobra.MaximumNArgs(1), cobra.MinimumNArgs(1)),
+	Each matching component is displayed on a new line.
+
+	If no URL is given, or the URL is -, newline delimited URLs are read from
+	stdin. Use --file to read URLs from one or more files and --null for NUL
+	delimited input. With --json, one JSON object is output per line.
+
+	The path, fragment and query parameters are decoded as UTF-8 unless
+	--charset names another charset, such as shift_jis, euc-kr, gbk or
+	iso-8859-1. With --detect-charset, the charset of each component that is
+	not valid UTF-8 is guessed.
+
+	A warning is written to stderr if the host mixes scripts or is confusable
+	with an ASCII host, or if the URL contains invisible characters. See the
+	--homograph check of the inspect command.
+
+	The host is split into its public suffix (--tld), registrable domain and
+	subdomain with an embedded snapshot of the Public Suffix List. These are
+	displayed with their flags and included with --json. Use --psl
+	to read a newer public_suffix_list.dat from https://publicsuffix.org/list/
+	and --icann-only to ignore the suffixes in its private section.
+
+		url parse 'https://www.bbc.co.uk/news' --registrable-domain
+			bbc.co.uk
+
+		url parse 'https://me.github.io/' --tld --icann-only
+			io
+
+	A host that is an IP address is displayed in its canonical form with its
+	version and class: public, loopback, private, link-local, multicast,
+	cgnat, documentation, reserved or unspecified. IPv4 addresses are parsed
+	as browsers do, so legacy forms such as 0x7f.1, 0177.0.0.1, 127.1 and
+	2130706433 are all 127.0.0.1.
+
+		url parse 'http://0x7f.1/' --ip
+			127.0.0.1
+
+		url parse 'http://[fe80::1%25eth0]/' --ip-class
+			link-local
+
+	--parser selects how the URL is parsed. go, the default, is Go's
+	net/url. whatwg parses the URL as browsers do, following the WHATWG URL
+	Standard: backslashes are slashes in http and other special URLs, tabs
+	and newlines are removed, default ports are dropped, and hosts are
+	converted to ASCII with IPv4 numbers in any form. rfc3986 is strict and
+	rejects anything that the grammar of RFC 3986 does not allow.
+
+		url parse 'https:\evil.com\login' --parser whatwg --host
+			evil.com
+
+		url parse 'http://mysite.com:80/a b' --parser rfc3986
+			Error: parse-rfc3986 "http://mysite.com:80/a b": invalid character ' ' in path
+
+	Examples:
+
+		url parse 'http://example.jp/search?q=%93%FA%96%7B' --params --charset shift_jis
+			q=日本`,
+	Args: cobra.MatchAll(cobra.MaximumNArgs(1), batchArgs),
 	Run: func(cmd *cobra.Command, args []string) {
-		var input string = args[0]
-		if shell {
-			input = strings.ReplaceAll(input, "\\", "")
+		checkCharset()
+		if _, err := urlkit.LookupParser(parserFlag); err != nil {
+			fail(err)
 		}
-		u, err := url.Parse(input)
-		if err != nil {
-			fmt.Printf("Error parsing %s\n", input)
-			os.Exit(1)
+		if err := loadSuffixes(); err != nil {
+			fail(err)
 		}
-
-		switch {
-		case schemeFlag:
-			displayComponent("", u.Scheme)
-		case opaqueFlag:
-			displayComponent("", u.Opaque)
-		case userFlag:
-			displayComponent("", u.User.String())
-		case domainFlag:
-			displayComponent("", hostname(u))
-		case portFlag:
-			displayComponent("", u.Port())
-		case pathFlag:
-			if noDecodeFlag {
-				displayComponent("", u.EscapedPath())
-			} else {
-				displayComponent("", u.Path)
-			}
-		case fragmentFlag:
-			if noDecodeFlag {
-				displayComponent("", u.EscapedFragment())
-			} else {
-				displayComponent("", u.Fragment)
-			}
-		case paramsFlag:
-			for key, vals := range u.Query() {
-				for i := range vals {
-					if noDecodeFlag {
-						displayComponent("", url.QueryEscape(key)+"="+url.QueryEscape(vals[i]))
-					} else {
-						displayComponent("", key+"="+vals[i])
-					}
-				}
-			}
-		default:
-			displayAll(u)
+		if isBatch(args) {
+			runBatchWarnings(args, parseRecord, isMultiline())
+			return
 		}
+		out, warnings, err := parseRecord(args[0])
+		for _, f := range warnings {
+			reportWarning(batch.Stderr, f, args[0], nil)
+		}
+		if err != nil {
+			fail(err)
+		}
+		fmt.Print(out)
 	},
 }
 
-func displayAll(u *url.URL) {
-	if jsonOutputFlag {
-		b := buildJSON(u)
-		fmt.Println(b)
-		return
-	}
+// parseRecord parses a single URL and returns the text to display for it
+// and any homographs in it.
+func parseRecord(input string) (string, []urlkit.Finding, error) {
+	return displayString(unshell(input))
+}
 
-	displayComponent(schemeLabel, u.Scheme)
-	displayComponent(opaqueLabel, u.Opaque)
-	displayComponent(userLabel, u.User.String())
-	displayComponent(hostLabel, hostname(u))
-	displayComponent(portLabel, u.Port())
-	if noDecodeFlag {
-		displayComponent(pathLabel, u.EscapedPath())
-		displayComponent(fragmentLabel, u.EscapedFragment())
-	} else {
-		displayComponent(pathLabel, u.Path)
-		displayComponent(fragmentLabel, u.Fragment)
+// displayString parses a URL and returns the text to display for it and any
+// homographs in it.
+func displayString(input string) (string, []urlkit.Finding, error) {
+	u, err := urlkit.ParseWith(parserFlag, input)
+	if err != nil {
+		return "", nil, err
 	}
-
-	q := u.Query()
-	if len(q) == 0 {
-		displayComponent(paramLabel, "")
-		return
+	findings := append(urlkit.FindHostHomographs(u, nil), urlkit.FindInvisible(input)...)
+	c, err := urlkit.Split(u, parseOptions())
+	if err != nil {
+		return "", findings, err
 	}
-	for key, vals := range q {
-		for i := range vals {
-			if noDecodeFlag {
-				displayComponent(paramLabel, url.QueryEscape(key)+"="+url.QueryEscape(vals[i]))
-			} else {
-				displayComponent(paramLabel, key+"="+vals[i])
-			}
-		}
+	var b strings.Builder
+	if err := displayURL(&b, c); err != nil {
+		return "", findings, err
 	}
+	return b.String(), findings, nil
 }
 
-func buildJSON(u *url.URL) string {
-	jsonData := make(map[string]interface{})
+func parseOptions() urlkit.Options {
+	return urlkit.Options{Puny: puny, IDNA: idnaOptions, NoDecode: noDecodeFlag, SortParams: sortParamsFlag,
+		Charset: charsetFlag, DetectCharset: detectCharsetFlag, Suffixes: suffixes}
+}
 
-	jsonData[schemeLabel] = u.Scheme
-	jsonData["uriPath"] = u.Opaque
-	jsonData[userLabel] = u.User.String()
-	jsonData[hostLabel] = hostname(u)
-	jsonData[portLabel] = u.Port()
+// isMultiline returns true if a parsed URL is displayed over several lines,
+// in which case records are separated by a blank line in batch mode.
+func isMultiline() bool {
+	return !jsonOutputFlag && !(schemeFlag || opaqueFlag || userFlag || domainFlag ||
+		portFlag || pathFlag || fragmentFlag || paramsFlag ||
+		tldFlag || registrableDomainFlag || subdomainFlag || labelsFlag || ipFlag || ipClassFlag)
+}
 
-	if noDecodeFlag {
-		jsonData[pathLabel] = u.EscapedPath()
-		jsonData[fragmentLabel] = u.EscapedFragment()
+// loadSuffixes sets suffixes to the embedded Public Suffix List, or to the
+// list read from --psl.
+func loadSuffixes() error {
+	if pslFile == "" {
+		suffixes = urlkit.DefaultSuffixList()
 	} else {
-		jsonData[pathLabel] = u.Path
-		jsonData[fragmentLabel] = u.Fragment
+		f, err := os.Open(pslFile)
+		if err != nil {
+			return ioError("open", pslFile, err)
+		}
+		defer f.Close()
+		if suffixes, err = urlkit.ParseSuffixList(f); err != nil {
+			return err
+		}
 	}
+	suffixes.ICANNOnly = icannOnlyFlag
+	return nil
+}
 
-	for label := range jsonData {
-		if label != paramsLabel {
-			if jsonData[label] == "" {
-				jsonData[label] = nil
+func displayURL(w io.Writer, c urlkit.Components) error {
+	switch {
+	case schemeFlag:
+		displayComponent(w, "", c.Scheme)
+	case opaqueFlag:
+		displayComponent(w, "", c.UriPath)
+	case userFlag:
+		displayComponent(w, "", c.User)
+	case domainFlag:
+		displayComponent(w, "", c.Host)
+	case portFlag:
+		displayComponent(w, "", c.Port)
+	case pathFlag:
+		displayComponent(w, "", c.Path)
+	case fragmentFlag:
+		displayComponent(w, "", c.Fragment)
+	case paramsFlag:
+		for _, p := range c.Params {
+			displayComponent(w, "", p.Key+"="+p.Value)
+		}
+	case tldFlag, registrableDomainFlag, subdomainFlag:
+		var d urlkit.DomainParts
+		if c.Domain != nil {
+			d = *c.Domain
+		}
+		switch {
+		case tldFlag:
+			displayComponent(w, "", d.TLD)
+		case registrableDomainFlag:
+			displayComponent(w, "", d.RegistrableDomain)
+		default:
+			displayComponent(w, "", d.Subdomain)
+		}
+	case ipFlag, ipClassFlag:
+		var ip urlkit.IPHost
+		if c.IP != nil {
+			ip = *c.IP
+		}
+		if ipFlag {
+			displayComponent(w, "", ip.Canonical)
+		} else {
+			displayComponent(w, "", ip.Class)
+		}
+	case labelsFlag:
+		if c.Domain != nil {
+			for _, label := range c.Domain.Labels {
+				displayComponent(w, "", label)
 			}
 		}
+	default:
+		return displayAll(w, c)
 	}
+	return nil
+}
 
-	q := u.Query()
-	queryParameters := make(map[string]interface{})
-	if len(q) > 0 {
-		for key, vals := range q {
-			if noDecodeFlag {
-				key = url.QueryEscape(key)
-				for i, v := range vals {
-					vals[i] = url.QueryEscape(v)
-				}
-			}
-			if len(vals) > 1 {
-				queryParameters[key] = vals
-			} else {
-				queryParameters[key] = vals[0]
-			}
+func displayAll(w io.Writer, c urlkit.Components) error {
+	if jsonOutputFlag {
+		b, err := c.JSON()
+		if err != nil {
+			return err
 		}
+		fmt.Fprintln(w, string(b))
+		return nil
 	}
-	jsonData[paramsLabel] = queryParameters
-	b, err := json.Marshal(jsonData)
-	if err != nil {
-		fmt.Println("Cannot convert to JSON.")
-		os.Exit(1)
+
+	displayComponent(w, schemeLabel, c.Scheme)
+	displayComponent(w, opaqueLabel, c.UriPath)
+	displayComponent(w, userLabel, c.User)
+	displayComponent(w, hostLabel, c.Host)
+	if c.IP != nil {
+		displayComponent(w, ipLabel, c.IP.Canonical)
+		displayComponent(w, ipVersionLabel, strconv.Itoa(c.IP.Version))
+		displayComponent(w, ipClassLabel, c.IP.Class)
+		if c.IP.Zone != "" {
+			displayComponent(w, zoneLabel, c.IP.Zone)
+		}
+	}
+	displayComponent(w, portLabel, c.Port)
+	displayComponent(w, pathLabel, c.Path)
+	displayComponent(w, fragmentLabel, c.Fragment)
+
+	if len(c.Params) == 0 {
+		displayComponent(w, paramLabel, "")
+		return nil
+	}
+	for _, p := range c.Params {
+		displayComponent(w, paramLabel, p.Key+"="+p.Value)
 	}
-	return string(b)
+	return nil
 }
 
-func displayComponent(displayName string, value string) {
+func displayComponent(w io.Writer, displayName string, value string) {
 	if displayName == "" {
-		fmt.Printf("%s\n", value)
+		fmt.Fprintf(w, "%s\n", value)
 		return
 	}
 	if noColorFlag {
-		fmt.Printf("%s: %s\n", displayName, value)
+		fmt.Fprintf(w, "%s: %s\n", displayName, value)
 		return
 	}
 	blue := color.New(color.Bold, color.FgBlue).SprintFunc()
 	green := color.New(color.FgGreen).SprintFunc()
-	fmt.Printf("%s:	%s\n", blue(displayName), green(value))
-}
-
-func hostname(u *url.URL) string {
-	if puny {
-		var p *idna.Profile = idna.New()
-		host := u.Hostname()
-		out, err := p.ToASCII(host)
-		if err != nil {
-			fmt.Printf("Error coverting %s to punycode.\n", host)
-			os.Exit(1)
-		}
-		return out
-	} else {
-		return u.Hostname()
-	}
+	fmt.Fprintf(w, "%s:	%s\n", blue(displayName), green(value))
 }
 
 func init() {
 	rootCmd.AddCommand(parseCmd)
 
-	parseCmd.Flags().BoolVar(&schemeFlag, schemeLabel, false, "Only display the scheme.")
-	parseCmd.Flags().BoolVar(&opaqueFlag, opaqueLabel, false, "Only display the uri-path.")
-	parseCmd.Flags().BoolVar(&userFlag, userLabel, false, "Only display the user:password.")
-	parseCmd.Flags().BoolVar(&domainFlag, hostLabel, false, "Only display the host/domain.")
-	parseCmd.Flags().BoolVar(&portFlag, portLabel, false, "Only display the port number.")
-	parseCmd.Flags().BoolVar(&pathFlag, pathLabel, false, "Only display the path.")
-	parseCmd.Flags().BoolVar(&fragmentFlag, fragmentLabel, false, "Only display the URL fragment.")
-	parseCmd.Flags().BoolVar(&paramsFlag, paramsLabel, false, "Only display the query parameters.")
-	parseCmd.Flags().BoolVar(&noColorFlag, "no-color", false, "Suppress color text output.")
-	parseCmd.Flags().BoolVar(&jsonOutputFlag, "json", false, "Output as JSON.")
-	parseCmd.Flags().BoolVar(&noDecodeFlag, "no-decode", false, "Do not URL decode paths and query parameters.")
+	addDisplayFlags(parseCmd)
+	addCharsetFlags(parseCmd, "Charset of the percent encoded path, fragment and query parameters.")
+	parseCmd.Flags().BoolVar(&detectCharsetFlag, "detect-charset", false, "Guess the charset of components that are not valid UTF-8.")
+	parseCmd.Flags().StringVar(&parserFlag, "parser", urlkit.ParserGo, "Parser to use: go, whatwg or rfc3986.")
+	addBatchFlags(parseCmd)
+}
+
+// addDisplayFlags adds the flags that select how a parsed URL is displayed.
+func addDisplayFlags(cmd *cobra.Command) {
+	cmd.Flags().BoolVar(&schemeFlag, schemeLabel, false, "Only display the scheme.")
+	cmd.Flags().BoolVar(&opaqueFlag, opaqueLabel, false, "Only display the uri-path.")
+	cmd.Flags().BoolVar(&userFlag, userLabel, false, "Only display the user:password.")
+	cmd.Flags().BoolVar(&domainFlag, hostLabel, false, "Only display the host/domain.")
+	cmd.Flags().BoolVar(&portFlag, portLabel, false, "Only display the port number.")
+	cmd.Flags().BoolVar(&pathFlag, pathLabel, false, "Only display the path.")
+	cmd.Flags().BoolVar(&fragmentFlag, fragmentLabel, false, "Only display the URL fragment.")
+	cmd.Flags().BoolVar(&paramsFlag, paramsLabel, false, "Only display the query parameters.")
+	cmd.Flags().BoolVar(&tldFlag, tldLabel, false, "Only display the public suffix of the host.")
+	cmd.Flags().BoolVar(&registrableDomainFlag, registrableDomainLabel, false, "Only display the registrable domain of the host.")
+	cmd.Flags().BoolVar(&subdomainFlag, subdomainLabel, false, "Only display the subdomain of the host.")
+	cmd.Flags().BoolVar(&labelsFlag, labelsLabel, false, "Only display the labels of the host, one per line.")
+	cmd.Flags().BoolVar(&ipFlag, ipLabel, false, "Only display the canonical form of a host that is an IP address.")
+	cmd.Flags().BoolVar(&ipClassFlag, ipClassLabel, false, "Only display the class of a host that is an IP address.")
+	cmd.Flags().BoolVar(&noColorFlag, "no-color", false, "Suppress color text output.")
+	cmd.Flags().BoolVar(&jsonOutputFlag, "json", false, "Output as JSON.")
+	cmd.Flags().BoolVar(&noDecodeFlag, "no-decode", false, "Do not URL decode paths and query parameters.")
+	cmd.Flags().BoolVar(&sortParamsFlag, "sort-params", false, "Sort query parameters by key instead of keeping their order.")
+	cmd.Flags().StringVar(&pslFile, "psl", "", "Read the Public Suffix List from a file instead of the embedded snapshot.")
+	cmd.Flags().BoolVar(&icannOnlyFlag, "icann-only", false, "Ignore the private section of the Public Suffix List.")
 }
@@ -0,0 +1,111 @@
+/*
+Copyright © 2022 Chris Morrow cmmorrow@gmail.com
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program. If not, see <http://www.gnu.org/licenses/>.
+*/
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/cmmorrow/url/urlkit"
+	"github.com/spf13/cobra"
+)
+
+var patternBase string
+var ignoreCaseFlag bool
+
+// patternCmd represents the pattern command
+var patternCmd = &cobra.Command{
+	Use:   "pattern PATTERN [url...|-]",
+	Short: "Match URLs against a WHATWG URLPattern.",
+	Long: `Match URLs against a pattern in the syntax of the WHATWG URLPattern
+Standard, which is used by service workers and edge routers.
+
+The pattern is split into its protocol, username, password, hostname, port,
+pathname, search and hash, and each component of a URL is matched against
+the pattern of that component. A component that the pattern does not give
+matches anything. In each component:
+
+	:name        a named group that matches up to the next delimiter, which
+	             is / in the pathname and . in the hostname
+	:name(\d+)   a named group that matches a regular expression
+	*            an unnamed group that matches anything
+	{...}        a group of text and groups that a modifier applies to
+	? + *        make the preceding group optional, repeated or both
+	\            escapes the next character, such as \: or \?
+
+A relative pattern such as /books/:id must be given a base URL with --base,
+from which the components before the pathname are taken.
+
+For each URL that matches, the input and named groups of each component are
+output as a JSON object, as URLPattern.exec() returns them. Unnamed groups
+are numbered from 0 and a group that did not take part in the match is null.
+A URL that does not match is an error, and the exit status is 5.
+
+Examples:
+
+	url pattern 'https://*.example.com/books/:id(\d+)' https://api.example.com/books/123
+		{"inputs":["https://api.example.com/books/123"],...,"hostname":{"input":"api.example.com","groups":{"0":"api"}},...,"pathname":{"input":"/books/123","groups":{"id":"123"}},...}
+
+	url pattern '/files/*.:ext' --base https://example.com https://example.com/files/a.pdf https://example.com/files/a
+		{"inputs":["https://example.com/files/a.pdf"],...,"pathname":{"input":"/files/a.pdf","groups":{"0":"a","ext":"pdf"}},...}
+		arg:2: match "https://example.com/files/a": does not match the pattern
+
+If no URLs are given, or the URL is -, newline delimited URLs are read from
+stdin. Use --file to read them from files.`,
+	Args: cobra.MatchAll(cobra.MinimumNArgs(1), func(cmd *cobra.Command, args []string) error {
+		return batchArgs(cmd, args[1:])
+	}),
+	Run: func(cmd *cobra.Command, args []string) {
+		p, err := urlkit.ParseURLPattern(args[0], urlkit.URLPatternOptions{BaseURL: patternBase, IgnoreCase: ignoreCaseFlag})
+		if err != nil {
+			fail(err)
+		}
+		process := func(input string) (string, error) {
+			return patternString(p, input)
+		}
+		if urls := args[1:]; isBatch(urls) {
+			runBatch(urls, process, false)
+		} else {
+			runArgs(urls, process, false)
+		}
+	},
+}
+
+func patternString(p *urlkit.URLPattern, input string) (string, error) {
+	input = unshell(input)
+	result, err := p.Exec(input)
+	if err != nil {
+		return "", err
+	}
+	if result == nil {
+		return "", &urlkit.Error{Kind: urlkit.KindValidation, Op: "match", Input: input, Offset: -1,
+			Err: fmt.Errorf("does not match the pattern")}
+	}
+	b, err := json.Marshal(result)
+	if err != nil {
+		return "", &urlkit.Error{Kind: urlkit.KindJSON, Op: "marshal", Offset: -1, Err: err}
+	}
+	return string(b) + "\n", nil
+}
+
+func init() {
+	rootCmd.AddCommand(patternCmd)
+
+	patternCmd.Flags().StringVar(&patternBase, "base", "", "Base URL of a relative pattern.")
+	patternCmd.Flags().BoolVar(&ignoreCaseFlag, "ignore-case", false, "Match letters without regard to case.")
+	addBatchFlags(patternCmd)
+}
@@ -0,0 +1,251 @@
+/*
+Copyright © 2022 Chris Morrow cmmorrow@gmail.com
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program. If not, see <http://www.gnu.org/licenses/>.
+*/
+package cmd
+
+import (
+	"bufio"
+	"io"
+	"os"
+	"runtime"
+	"sync"
+
+	"github.com/cmmorrow/url/urlkit"
+	"github.com/spf13/cobra"
+)
+
+// queueFactor is the number of inputs per worker that may be in flight at
+// once. It bounds memory use when one slow input holds up ordered output.
+const queueFactor = 16
+
+// ProcessFunc converts a single input into the text to output for it.
+type ProcessFunc func(input string) (string, error)
+
+// WarnFunc is a ProcessFunc that also returns findings to warn about in an
+// input that was processed.
+type WarnFunc func(input string) (string, []urlkit.Finding, error)
+
+// withWarnings returns process as a WarnFunc that never warns.
+func (process ProcessFunc) withWarnings() WarnFunc {
+	return func(input string) (string, []urlkit.Finding, error) {
+		output, err := process(input)
+		return output, nil, err
+	}
+}
+
+// Result is the output for an input, or the error processing it, and any
+// warnings about the input.
+type Result struct {
+	Record   InputRecord
+	Output   string
+	Warnings []urlkit.Finding
+	Err      error
+}
+
+type job struct {
+	rec InputRecord
+	out chan Result
+}
+
+// runBatch reads every input and processes them with batch, then exits with
+// the exit status of the batch.
+func runBatch(args []string, process ProcessFunc, sep bool) {
+	runBatchWarnings(args, process.withWarnings(), sep)
+}
+
+// runBatchWarnings is runBatch for a process that warns about inputs.
+func runBatchWarnings(args []string, process WarnFunc, sep bool) {
+	exitWith(batch.RunWarnings(args, process, sep))
+}
+
+// runArgs processes each of the command-line arguments in turn, reporting
+// errors and exiting like runBatch.
+func runArgs(args []string, process ProcessFunc, sep bool) {
+	runArgsWarnings(args, process.withWarnings(), sep)
+}
+
+// runArgsWarnings is runArgs for a process that warns about inputs.
+func runArgsWarnings(args []string, process WarnFunc, sep bool) {
+	out := newOutputWriter(batch.Stdout, batch.Stderr, sep)
+	for i, arg := range args {
+		output, warnings, err := process(arg)
+		out.emit(Result{Record: InputRecord{Source: "arg", Line: i + 1, Text: arg}, Output: output,
+			Warnings: warnings, Err: err})
+	}
+	exitWith(out.finish(nil))
+}
+
+// exitWith exits with status, or reports err and exits with its exit code.
+func exitWith(status int, err error) {
+	if err != nil {
+		fail(err)
+	}
+	if status == exitOK {
+		exitStatus()
+	}
+	os.Exit(status)
+}
+
+// Run reads every input and processes them with a pool of workers. Outputs
+// are written to Stdout in input order unless Unordered is set and errors
+// are reported on Stderr without stopping the batch. If sep is true, outputs
+// are separated by a blank line. It returns the worst exit status of the
+// inputs, see worseStatus, and any error reading the inputs or writing the
+// outputs.
+func (b *Batch) Run(args []string, process ProcessFunc, sep bool) (int, error) {
+	return b.RunWarnings(args, process.withWarnings(), sep)
+}
+
+// RunWarnings is Run for a process that warns about inputs. Warnings are
+// reported on Stderr, with the location of the input, before its output.
+func (b *Batch) RunWarnings(args []string, process WarnFunc, sep bool) (int, error) {
+	out := newOutputWriter(b.Stdout, b.Stderr, sep)
+	err := b.ProcessWarnings(args, process, out.emit)
+	return out.finish(err)
+}
+
+// outputWriter writes results to an output and keeps the exit status.
+type outputWriter struct {
+	w      *bufio.Writer
+	errw   io.Writer
+	sep    bool
+	first  bool
+	status int
+}
+
+func newOutputWriter(w io.Writer, errw io.Writer, sep bool) *outputWriter {
+	return &outputWriter{w: bufio.NewWriter(w), errw: errw, sep: sep, first: true, status: exitOK}
+}
+
+func (o *outputWriter) emit(r Result) {
+	if len(r.Warnings) > 0 {
+		o.w.Flush()
+		for _, f := range r.Warnings {
+			reportWarning(o.errw, f, r.Record.Text, &r.Record)
+		}
+	}
+	if r.Err != nil {
+		o.w.Flush()
+		reportError(o.errw, r.Err, &r.Record)
+		o.status = worseStatus(o.status, exitCode(r.Err))
+		return
+	}
+	if o.sep && !o.first {
+		o.w.WriteString("\n")
+	}
+	o.first = false
+	o.w.WriteString(r.Output)
+}
+
+// finish flushes the output and returns the exit status. err is an error
+// reading the input.
+func (o *outputWriter) finish(err error) (int, error) {
+	if flushErr := o.w.Flush(); flushErr != nil {
+		return o.status, ioError("write", "stdout", flushErr)
+	}
+	return o.status, err
+}
+
+// worseStatus returns the worse of two exit statuses. A validation failure
+// is an input that was processed but failed a check, so it is better than
+// any other failure. Other failures are worse the higher their exit code.
+func worseStatus(a int, b int) int {
+	severity := func(status int) int {
+		switch status {
+		case exitOK:
+			return 0
+		case exitValidation:
+			return 1
+		}
+		return status + 1
+	}
+	if severity(b) > severity(a) {
+		return b
+	}
+	return a
+}
+
+// Process fans the inputs out to Jobs workers and calls emit with each
+// result from a single goroutine. If Jobs is less than 1, there is a worker
+// for every CPU.
+func (b *Batch) Process(args []string, process ProcessFunc, emit func(Result)) error {
+	return b.ProcessWarnings(args, process.withWarnings(), emit)
+}
+
+// ProcessWarnings is Process for a process that warns about inputs.
+func (b *Batch) ProcessWarnings(args []string, process WarnFunc, emit func(Result)) error {
+	workers := b.Jobs
+	if workers < 1 {
+		workers = runtime.NumCPU()
+	}
+	jobs := make(chan job, workers)
+	// queue holds the pending results in input order. Its capacity is what
+	// applies back-pressure to the reader.
+	queue := make(chan chan Result, workers*queueFactor)
+	unordered := make(chan Result, workers)
+
+	var wg sync.WaitGroup
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := range jobs {
+				output, warnings, err := process(j.rec.Text)
+				r := Result{Record: j.rec, Output: output, Warnings: warnings, Err: err}
+				if b.Unordered {
+					unordered <- r
+				} else {
+					j.out <- r
+				}
+			}
+		}()
+	}
+
+	var readErr error
+	go func() {
+		readErr = b.ReadInputs(args, func(rec InputRecord) {
+			j := job{rec: rec}
+			if !b.Unordered {
+				j.out = make(chan Result, 1)
+				queue <- j.out
+			}
+			jobs <- j
+		})
+		close(jobs)
+		close(queue)
+		wg.Wait()
+		close(unordered)
+	}()
+
+	if b.Unordered {
+		for r := range unordered {
+			emit(r)
+		}
+	} else {
+		for out := range queue {
+			emit(<-out)
+		}
+	}
+	return readErr
+}
+
+// addBatchFlags adds the flags for reading and processing inputs in batch mode.
+func addBatchFlags(cmd *cobra.Command) {
+	cmd.Flags().StringArrayVar(&batch.Files, "file", nil, "Read inputs from a file. Can be repeated.")
+	cmd.Flags().BoolVarP(&batch.Null, "null", "0", false, "Inputs are delimited by NUL instead of newline.")
+	cmd.Flags().IntVarP(&batch.Jobs, "jobs", "j", 1, "Number of inputs to process in parallel. 0 uses every CPU.")
+	cmd.Flags().BoolVar(&batch.Unordered, "unordered", false, "Output results as they finish instead of in input order.")
+}
@@ -0,0 +1,199 @@
+/*
+Copyright © 2022 Chris Morrow cmmorrow@gmail.com
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program. If not, see <http://www.gnu.org/licenses/>.
+*/
+package cmd
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/cmmorrow/url/urlkit"
+	"github.com/spf13/cobra"
+)
+
+var queryGetFlags []string
+var querySetFlags []string
+var queryAddFlags []string
+var queryDelFlags []string
+var queryRenameFlags []string
+var globFlag bool
+var regexFlag bool
+
+// queryCmd represents the query command
+var queryCmd = &cobra.Command{
+	Use:   "query url|-",
+	Short: "Get or edit the query parameters of a URL.",
+	Long: `Get, set, add, delete and rename the query parameters of a URL.
+
+The query string is edited without decoding it, so parameters that are not
+changed keep their original encoding and order. The edits are applied in the
+order --del, --rename, --set, --add. Each flag can be repeated.
+
+Keys are matched exactly unless --glob or --regex is set. A glob or regular
+expression must match the whole key. --set changes the first matching
+parameter and removes the others, or adds the parameter if there is none.
+With --glob or --regex, --set changes every matching parameter.
+
+With --get, the values of the matching parameters are displayed instead of
+the URL.
+
+Examples:
+
+	url query 'https://mysite.com/?q=a%20b&page=1' --set page=2 --add tag=x
+		https://mysite.com/?q=a%20b&page=2&tag=x
+
+	url query 'https://mysite.com/?utm_source=x&utm_medium=y&id=1' --del 'utm_*' --glob
+		https://mysite.com/?id=1
+
+	url query 'https://mysite.com/?q=books' --rename q=query
+		https://mysite.com/?query=books
+
+	url query 'https://mysite.com/?token=abc' --get token
+		abc
+
+If no URL is given, or the URL is -, newline delimited URLs are read from
+stdin. Use --file to read URLs from one or more files.`,
+	Args: cobra.MatchAll(cobra.MaximumNArgs(1), batchArgs),
+	Run: func(cmd *cobra.Command, args []string) {
+		if isBatch(args) {
+			runBatch(args, queryString, false)
+			return
+		}
+		out, err := queryString(args[0])
+		if err != nil {
+			fail(err)
+		}
+		fmt.Print(out)
+	},
+}
+
+func queryString(input string) (string, error) {
+	var got urlkit.Query
+	out, err := urlkit.EditQuery(unshell(input), func(q urlkit.RawQuery) (urlkit.RawQuery, error) {
+		q, err := editQuery(q)
+		if err != nil {
+			return nil, err
+		}
+		for _, pattern := range queryGetFlags {
+			match, err := keyMatcher(pattern)
+			if err != nil {
+				return nil, err
+			}
+			got = append(got, q.Get(match)...)
+		}
+		return q, nil
+	})
+	if err != nil {
+		return "", err
+	}
+	if len(queryGetFlags) == 0 {
+		return out + "\n", nil
+	}
+	if jsonOutputFlag {
+		b, err := got.MarshalJSON()
+		if err != nil {
+			return "", &urlkit.Error{Kind: urlkit.KindJSON, Op: "marshal", Offset: -1, Err: err}
+		}
+		return string(b) + "\n", nil
+	}
+	var b strings.Builder
+	for _, p := range got {
+		b.WriteString(p.Value + "\n")
+	}
+	return b.String(), nil
+}
+
+// editQuery applies the --del, --rename, --set and --add flags to q.
+func editQuery(q urlkit.RawQuery) (urlkit.RawQuery, error) {
+	for _, pattern := range queryDelFlags {
+		match, err := keyMatcher(pattern)
+		if err != nil {
+			return nil, err
+		}
+		q = q.Delete(match)
+	}
+	for _, rename := range queryRenameFlags {
+		from, to, err := splitEdit("rename", rename)
+		if err != nil {
+			return nil, err
+		}
+		match, err := keyMatcher(from)
+		if err != nil {
+			return nil, err
+		}
+		q = q.Rename(match, to)
+	}
+	for _, set := range querySetFlags {
+		key, value, err := splitEdit("set", set)
+		if err != nil {
+			return nil, err
+		}
+		if matchMode() == urlkit.MatchExact {
+			q = q.Set(key, value)
+			continue
+		}
+		match, err := keyMatcher(key)
+		if err != nil {
+			return nil, err
+		}
+		q = q.SetMatching(match, value)
+	}
+	for _, add := range queryAddFlags {
+		key, value, err := splitEdit("add", add)
+		if err != nil {
+			return nil, err
+		}
+		q = q.Add(key, value)
+	}
+	return q, nil
+}
+
+// splitEdit splits the key=value argument of an edit flag.
+func splitEdit(op string, s string) (string, string, error) {
+	p := strings.SplitN(s, "=", 2)
+	if len(p) != 2 {
+		return "", "", &urlkit.Error{Kind: urlkit.KindInvalidInput, Op: op, Input: s, Offset: -1, Err: fmt.Errorf("expected key=value")}
+	}
+	return p[0], p[1], nil
+}
+
+func matchMode() string {
+	switch {
+	case regexFlag:
+		return urlkit.MatchRegex
+	case globFlag:
+		return urlkit.MatchGlob
+	}
+	return urlkit.MatchExact
+}
+
+func keyMatcher(pattern string) (urlkit.KeyMatcher, error) {
+	return urlkit.MatchKey(pattern, matchMode())
+}
+
+func init() {
+	rootCmd.AddCommand(queryCmd)
+
+	queryCmd.Flags().StringArrayVar(&queryGetFlags, "get", nil, "Display the values of the parameters with a key.")
+	queryCmd.Flags().StringArrayVar(&querySetFlags, "set", nil, "Set a key=value parameter.")
+	queryCmd.Flags().StringArrayVar(&queryAddFlags, "add", nil, "Add a key=value parameter.")
+	queryCmd.Flags().StringArrayVar(&queryDelFlags, "del", nil, "Delete the parameters with a key.")
+	queryCmd.Flags().StringArrayVar(&queryRenameFlags, "rename", nil, "Rename the parameters with a key, as old=new.")
+	queryCmd.Flags().BoolVar(&globFlag, "glob", false, "Match keys with shell-style patterns such as utm_*.")
+	queryCmd.Flags().BoolVar(&regexFlag, "regex", false, "Match keys with regular expressions that match the whole key.")
+	queryCmd.Flags().BoolVar(&jsonOutputFlag, "json", false, "Output the --get parameters as JSON.")
+	addBatchFlags(queryCmd)
+}
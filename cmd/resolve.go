@@ -0,0 +1,98 @@
+/*
+Copyright © 2022 Chris Morrow cmmorrow@gmail.com
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program. If not, see <http://www.gnu.org/licenses/>.
+*/
+package cmd
+
+import (
+	"github.com/cmmorrow/url/urlkit"
+	"github.com/spf13/cobra"
+)
+
+var relativizeFlag bool
+
+// resolveCmd represents the resolve command
+var resolveCmd = &cobra.Command{
+	Use:   "resolve base [reference...|-]",
+	Short: "Resolve a relative reference against a base URL.",
+	Long: `Resolve one or more references against a base URL following RFC 3986
+section 5.
+
+The resolved URL is displayed. Use --json or a component flag such as --path
+to display it like the parse command.
+
+With --relativize, each reference must be an absolute URL and the shortest
+relative reference from the base URL to it is displayed instead.
+
+Examples:
+
+	url resolve http://a/b/c/d;p?q ../g ?y '#s'
+		http://a/b/g
+		http://a/b/c/d;p?y
+		http://a/b/c/d;p?q#s
+
+	url resolve http://a/b/c/d;p?q ../img/a.png?x=1 --path
+		/b/img/a.png
+
+	url resolve --relativize http://a/b/c/d;p?q http://a/b/img/a.png
+		../img/a.png
+
+If no references are given, or the reference is -, newline delimited
+references are read from stdin. Use --file to read them from files.`,
+	Args: cobra.MatchAll(cobra.MinimumNArgs(1), func(cmd *cobra.Command, args []string) error {
+		return batchArgs(cmd, args[1:])
+	}),
+	Run: func(cmd *cobra.Command, args []string) {
+		if err := loadSuffixes(); err != nil {
+			fail(err)
+		}
+		base, refs := unshell(args[0]), args[1:]
+		process := func(ref string) (string, []urlkit.Finding, error) {
+			return resolveString(base, ref)
+		}
+		if isBatch(refs) {
+			runBatchWarnings(refs, process, false)
+		} else {
+			runArgsWarnings(refs, process, false)
+		}
+	},
+}
+
+func resolveString(base string, ref string) (string, []urlkit.Finding, error) {
+	var out string
+	var err error
+	if relativizeFlag {
+		out, err = urlkit.Relativize(base, unshell(ref))
+	} else {
+		out, err = urlkit.Resolve(base, unshell(ref))
+	}
+	if err != nil {
+		return "", nil, err
+	}
+	// Without --json or a component flag, only the URL is displayed rather
+	// than every component.
+	if isMultiline() {
+		return out + "\n", nil, nil
+	}
+	return displayString(out)
+}
+
+func init() {
+	rootCmd.AddCommand(resolveCmd)
+
+	resolveCmd.Flags().BoolVar(&relativizeFlag, "relativize", false, "Display the relative reference from the base URL to each URL.")
+	addDisplayFlags(resolveCmd)
+	addBatchFlags(resolveCmd)
+}
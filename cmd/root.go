@@ -17,117 +17,37 @@ along with this program. If not, see <http://www.gnu.org/licenses/>.
 package cmd
 
 import (
-	"net/url"
+	"fmt"
+	"io"
 	"os"
 	"strings"
 
+	"github.com/cmmorrow/url/urlkit"
 	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
 )
 
-const schemeLabel = "scheme"
-const opaqueLabel = "uri-path"
-const userLabel = "user"
-const hostLabel = "host"
-const portLabel = "port"
-const pathLabel = "path"
-const fragmentLabel = "fragment"
-const paramsLabel = "params"
-const paramLabel = "param"
+const schemeLabel = urlkit.SchemeLabel
+const opaqueLabel = urlkit.OpaqueLabel
+const userLabel = urlkit.UserLabel
+const hostLabel = urlkit.HostLabel
+const portLabel = urlkit.PortLabel
+const pathLabel = urlkit.PathLabel
+const fragmentLabel = urlkit.FragmentLabel
+const paramsLabel = urlkit.ParamsLabel
+const paramLabel = urlkit.ParamLabel
+const tldLabel = urlkit.TLDLabel
+const registrableDomainLabel = urlkit.RegistrableDomainLabel
+const subdomainLabel = urlkit.SubdomainLabel
+const labelsLabel = urlkit.LabelsLabel
+const ipLabel = urlkit.IPLabel
+const ipClassLabel = urlkit.IPClassLabel
+const ipVersionLabel = urlkit.IPVersionLabel
+const zoneLabel = urlkit.ZoneLabel
 
 var puny bool
 var shell bool
 
-type URI struct {
-	Scheme    string
-	UriPath   string
-	User      string
-	Host      string
-	Port      string
-	Path      string
-	Fragment  string
-	Query     string
-	RawParams []string
-	Params    map[string]interface{}
-}
-
-func (u *URI) AsURL() url.URL {
-	var output = url.URL{
-		Scheme:   u.Scheme,
-		Opaque:   u.UriPath,
-		Host:     u.BuildHostname(),
-		Path:     u.Path,
-		Fragment: u.Fragment,
-	}
-	if u.User != "" {
-		output.User = u.SetUser()
-	}
-	if u.Query != "" {
-		output.RawQuery = u.Query
-	} else {
-		output.RawQuery = u.BuildValues().Encode()
-	}
-	return output
-}
-
-func (u *URI) SetUser() *url.Userinfo {
-	var username, password string
-	if u.User != "" {
-		user := strings.Split(u.User, ":")
-		if len(user) == 1 {
-			user = append(user, "")
-		}
-		username, password = user[0], user[1]
-	}
-	if password != "" {
-		return url.UserPassword(username, password)
-	} else {
-		return url.User(username)
-	}
-}
-
-func (u *URI) BuildHostname() string {
-	if u.Host == "" {
-		return ""
-	}
-	if u.Port != "" {
-		splitHost := strings.SplitN(u.Host, ":", 2)
-		return splitHost[0] + ":" + u.Port
-	} else {
-		return u.Host
-	}
-}
-
-func (u *URI) BuildValues() url.Values {
-	values := url.Values{}
-	switch {
-	case u.Params != nil:
-
-		for key, val := range u.Params {
-			switch v := val.(type) {
-			case string:
-				values[key] = []string{v}
-			case []interface{}:
-				var vv []string
-				for i := range v {
-					vv = append(vv, v[i].(string))
-				}
-				values[key] = vv
-			}
-		}
-		return values
-	case u.RawParams != nil:
-		for i := range u.RawParams {
-			p := strings.SplitN(u.RawParams[i], "=", 2)
-			if len(p) == 1 {
-				p = append(p, "")
-			}
-			values[p[0]] = append(values[p[0]], p[1])
-		}
-	}
-
-	return values
-}
-
 // rootCmd represents the base command when called without any subcommands
 var rootCmd = &cobra.Command{
 	Use:   "url",
@@ -141,17 +61,66 @@ var rootCmd = &cobra.Command{
 											
    
 
-	A command-line tool for working with URLs.`,
+	A command-line tool for working with URLs.
+
+Errors are written to stderr. The exit status is:
+
+	0  success
+	1  usage error
+	2  invalid input
+	3  IDNA (punycode) failure
+	4  JSON error
+	5  validation failure
+	6  I/O error
+
+--puny converts domains with the UTS #46 profile given by --idna-profile.
+The punycode profile, the default, converts labels as they are. The lookup
+and display profiles map characters as browsers do, and registration only
+accepts domains that are already in their mapped form. --idna-transitional,
+--idna-std3, --idna-bidi and --idna-contextj turn the profile's options on or
+off, for example --idna-std3=false to allow underscores. Errors name the
+UTS #46 rule a label violates.`,
+	PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+		if errorFormat != textErrorFormat && errorFormat != jsonErrorFormat {
+			return fmt.Errorf("invalid error format %q, use text or json", errorFormat)
+		}
+		return setIDNAOptions(cmd)
+	},
 }
 
 func Execute() {
+	// Usage errors are found before the flags are parsed so --error-format is
+	// looked up early to keep JSON error output free of usage text.
+	errorFormat = errorFormatFromArgs(os.Args[1:])
+	if errorFormat == jsonErrorFormat {
+		rootCmd.SilenceUsage = true
+	}
 	err := rootCmd.Execute()
 	if err != nil {
-		os.Exit(1)
+		fail(err)
+	}
+}
+
+func errorFormatFromArgs(args []string) string {
+	flags := pflag.NewFlagSet("", pflag.ContinueOnError)
+	flags.ParseErrorsWhitelist.UnknownFlags = true
+	flags.SetOutput(io.Discard)
+	format := flags.String("error-format", textErrorFormat, "")
+	flags.Parse(args)
+	return *format
+}
+
+// unshell removes shell escape characters from input if --shell is set.
+func unshell(input string) string {
+	if shell {
+		return strings.ReplaceAll(input, "\\", "")
 	}
+	return input
 }
 
 func init() {
 	rootCmd.PersistentFlags().BoolVar(&puny, "puny", false, "Convert the domain/host to punycode (IDNA).")
 	rootCmd.PersistentFlags().BoolVar(&shell, "shell", false, "Remove shell escape characters before processing.")
+	rootCmd.PersistentFlags().StringVar(&errorFormat, "error-format", textErrorFormat, "Format of errors written to stderr: text or json.")
+	rootCmd.SilenceErrors = true
 }
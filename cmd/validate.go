@@ -0,0 +1,157 @@
+/*
+Copyright © 2022 Chris Morrow cmmorrow@gmail.com
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program. If not, see <http://www.gnu.org/licenses/>.
+*/
+package cmd
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/cmmorrow/url/urlkit"
+	"github.com/spf13/cobra"
+)
+
+var policyFile string
+
+// validateReport is the JSON output of the validate command.
+type validateReport struct {
+	URL        string             `json:"url"`
+	Valid      bool               `json:"valid"`
+	Violations []urlkit.Violation `json:"violations"`
+}
+
+// validateCmd represents the validate command
+var validateCmd = &cobra.Command{
+	Use:   "validate --policy FILE [url|-]",
+	Short: "Check a URL against a policy.",
+	Long: `Check a URL against a policy of allowed schemes, hosts, ports and IP
+addresses, such as the URLs a server may send requests to.
+
+The policy is a YAML or JSON file. Every key is optional and a rule that is
+not given allows everything, except allowUserinfo, which is false unless it
+is set. For example:
+
+	schemes: [https]
+	# Shell-style patterns. A denied host is denied even if it is allowed.
+	allowHosts: ["*.example.com", example.com]
+	denyHosts: [internal.example.com]
+	# Ports and port ranges. A URL without a port uses the default port for
+	# its scheme, and fails if its scheme has no default port.
+	ports: [443, 8000-8999]
+	denyIPClasses: [unspecified, loopback, private, link-local, cgnat, reserved]
+	allowUserinfo: false
+	maxLength: 2048
+
+IP address classes are public, unspecified, loopback, private, link-local,
+multicast, cgnat, documentation and reserved. Hosts are parsed the way
+browsers parse them, so legacy IPv4 forms such as 0x7f.1 and 2130706433 are
+recognised as 127.0.0.1. Domains are not resolved, so a domain that resolves
+to a denied IP address is not found.
+
+A URL that breaks the policy is displayed followed by one violation per line.
+Nothing is displayed for a URL that is allowed. With --json, a verdict is
+displayed for every URL. The exit status is 5 if any URL breaks the policy.
+
+Examples:
+
+	url validate --policy policy.yaml 'http://admin:pw@0x7f.1:22/'
+		http://admin:pw@0x7f.1:22/
+		  scheme: scheme "http" is not one of https
+		  userinfo: URL contains a user or password
+		  host-allow: host 127.0.0.1 is not in the allowed hosts
+		  ip-class: host 127.0.0.1 is in the loopback IP class, written as 0x7f.1
+		  port: port 22 is not one of 443, 8000-8999
+
+If no URL is given, or the URL is -, newline delimited URLs are read from
+stdin. Use --file to read URLs from one or more files.`,
+	Args: cobra.MatchAll(cobra.MaximumNArgs(1), batchArgs),
+	Run: func(cmd *cobra.Command, args []string) {
+		policy, err := loadPolicy()
+		if err != nil {
+			fail(err)
+		}
+		process := func(input string) (string, error) {
+			return validateString(input, policy)
+		}
+		if isBatch(args) {
+			runBatch(args, process, false)
+			return
+		}
+		out, err := process(args[0])
+		if err != nil {
+			fail(err)
+		}
+		fmt.Print(out)
+		exitStatus()
+	},
+}
+
+func validateString(input string, policy *urlkit.Policy) (string, error) {
+	input = unshell(input)
+	violations, err := policy.Validate(input)
+	if err != nil {
+		return "", err
+	}
+	if len(violations) > 0 {
+		markValidationFailed()
+	}
+	if jsonOutputFlag {
+		if violations == nil {
+			violations = []urlkit.Violation{}
+		}
+		b, err := json.Marshal(validateReport{URL: input, Valid: len(violations) == 0, Violations: violations})
+		if err != nil {
+			return "", &urlkit.Error{Kind: urlkit.KindJSON, Op: "marshal", Offset: -1, Err: err}
+		}
+		return string(b) + "\n", nil
+	}
+	if len(violations) == 0 {
+		return "", nil
+	}
+	var b strings.Builder
+	b.WriteString(input + "\n")
+	for _, v := range violations {
+		b.WriteString("  " + v.String() + "\n")
+	}
+	return b.String(), nil
+}
+
+// loadPolicy reads the --policy file.
+func loadPolicy() (*urlkit.Policy, error) {
+	f, err := os.Open(policyFile)
+	if err != nil {
+		return nil, ioError("open", policyFile, err)
+	}
+	defer f.Close()
+	policy, err := urlkit.ParsePolicy(f)
+	var e *urlkit.Error
+	if errors.As(err, &e) && e.Input == "" {
+		e.Input = policyFile
+	}
+	return policy, err
+}
+
+func init() {
+	rootCmd.AddCommand(validateCmd)
+
+	validateCmd.Flags().StringVar(&policyFile, "policy", "", "Read the policy from a YAML or JSON file.")
+	validateCmd.MarkFlagRequired("policy")
+	validateCmd.Flags().BoolVar(&jsonOutputFlag, "json", false, "Output a verdict listing the violations as JSON.")
+	addBatchFlags(validateCmd)
+}
@@ -0,0 +1,129 @@
+/*
+Copyright © 2022 Chris Morrow cmmorrow@gmail.com
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program. If not, see <http://www.gnu.org/licenses/>.
+*/
+package cmd_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/cmmorrow/url/urlkit"
+)
+
+type charsetTest struct {
+	text    string
+	charset string
+	encoded string
+}
+
+var charsetTests = []charsetTest{
+	{"日本語", "shift_jis", "%93%FA%96%7B%8C%EA"},
+	{"ア", "shift_jis", "%83A"},
+	{"日本語", "euc-jp", "%C6%FC%CB%DC%B8%EC"},
+	{"한국어", "euc-kr", "%C7%D1%B1%B9%BE%EE"},
+	{"中文", "gbk", "%D6%D0%CE%C4"},
+	{"中文", "big5", "%A4%A4%A4%E5"},
+	{"café", "iso-8859-1", "caf%E9"},
+	{"café", "utf-8", "caf%C3%A9"},
+}
+
+func TestEncodeCharset(t *testing.T) {
+	for _, test := range charsetTests {
+		converted, err := urlkit.ToCharset(test.text, test.charset)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if out := urlkit.Encode(converted); out != test.encoded {
+			t.Fatalf("Expected '%s', got %s", test.encoded, out)
+		}
+	}
+}
+
+func TestDecodeCharset(t *testing.T) {
+	for _, test := range charsetTests {
+		out, err := urlkit.DecodeComponent(test.encoded, urlkit.DecodeOptions{Charset: test.charset})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if out != test.text {
+			t.Fatalf("Expected '%s', got %s", test.text, out)
+		}
+	}
+}
+
+var detectCharsetTests = []charsetTest{
+	{"日本語のテキスト", "shift_jis", ""},
+	{"日本語のテキスト", "euc-jp", ""},
+	{"서울특별시", "euc-kr", ""},
+	{"中华人民共和国", "gbk", ""},
+	{"臺灣的天氣", "big5", ""},
+	{"Müller", "windows-1252", ""},
+	{"Müller", "utf-8", ""},
+}
+
+func TestDetectCharset(t *testing.T) {
+	for _, test := range detectCharsetTests {
+		converted, err := urlkit.ToCharset(test.text, test.charset)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if out := urlkit.DetectCharset(converted); out != test.charset {
+			t.Fatalf("Expected '%s', got %s", test.charset, out)
+		}
+		out, err := urlkit.DecodeComponent(urlkit.Encode(converted), urlkit.DecodeOptions{DetectCharset: true})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if out != test.text {
+			t.Fatalf("Expected '%s', got %s", test.text, out)
+		}
+	}
+}
+
+func TestSplitCharset(t *testing.T) {
+	u, err := urlkit.Parse("http://example.jp/%93%FA%96%7B?q=%8C%EA#%83A")
+	if err != nil {
+		t.Fatal(err)
+	}
+	c, err := urlkit.Split(u, urlkit.Options{Charset: "shift_jis"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c.Path != "/日本" || c.Params.Get("q") != "語" || c.Fragment != "ア" {
+		t.Fatalf("Expected '/日本', '語' and 'ア', got %s, %s and %s", c.Path, c.Params.Get("q"), c.Fragment)
+	}
+}
+
+func TestDecodeLayersCharset(t *testing.T) {
+	layers, _, err := urlkit.DecodeLayers("%2593%25FA", urlkit.DecodeOptions{Component: urlkit.ComponentPath, Charset: "shift_jis"}, urlkit.DefaultMaxDepth)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if layers[len(layers)-1] != "日" {
+		t.Fatalf("Expected '日', got %s", layers[len(layers)-1])
+	}
+}
+
+func TestToCharsetErrors(t *testing.T) {
+	_, err := urlkit.ToCharset("ab日本😀", "shift_jis")
+	var e *urlkit.Error
+	if !errors.As(err, &e) || e.Kind != urlkit.KindInvalidInput || e.Offset != 8 {
+		t.Fatalf("Expected an invalid input error at 8, got %v", err)
+	}
+	if _, err := urlkit.ToCharset("abc", "no-such-charset"); urlkit.KindOf(err) != urlkit.KindInvalidInput {
+		t.Fatalf("Expected an invalid input error, got %v", err)
+	}
+}
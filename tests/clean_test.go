@@ -0,0 +1,83 @@
+package cmd_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/cmmorrow/url/urlkit"
+)
+
+type cleanTest struct {
+	input    string
+	expected string
+	removed  string
+}
+
+var cleanTests = []cleanTest{
+	{"https://mysite.com/post?id=1&utm_source=news&fbclid=abc", "https://mysite.com/post?id=1", "utm_source=news&fbclid=abc"},
+	{"https://mysite.com/post?q=a%20b&GCLID=x#top", "https://mysite.com/post?q=a%20b#top", "GCLID=x"},
+	{"https://www.amazon.co.uk/dp/X?pd_rd_w=1&th=1", "https://www.amazon.co.uk/dp/X?th=1", "pd_rd_w=1"},
+	{"https://mysite.com/dp/X?pd_rd_w=1", "https://mysite.com/dp/X?pd_rd_w=1", ""},
+	{"https://mysite.com/?utm_source=x", "https://mysite.com/", "utm_source=x"},
+}
+
+func TestClean(t *testing.T) {
+	rules := urlkit.DefaultRules()
+	for _, test := range cleanTests {
+		out, removed, err := urlkit.Clean(test.input, rules)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if out != test.expected {
+			t.Fatalf("Expected '%s', got %s", test.expected, out)
+		}
+		if removed.Encode() != test.removed {
+			t.Fatalf("Expected to remove '%s', removed %s", test.removed, removed.Encode())
+		}
+	}
+}
+
+func TestParseRules(t *testing.T) {
+	rules, err := urlkit.ParseRules(strings.NewReader("# comment\n\nref\nshop.com tag\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	matches := []struct {
+		host, key string
+		expected  bool
+	}{
+		{"mysite.com", "ref", true},
+		{"shop.com", "tag", true},
+		{"www.shop.com", "tag", true},
+		{"myshop.com", "tag", false},
+		{"mysite.com", "tag", false},
+	}
+	for _, m := range matches {
+		if rules.Match(m.host, m.key) != m.expected {
+			t.Fatalf("Expected match of %s on %s to be %v", m.key, m.host, m.expected)
+		}
+	}
+	if _, err := urlkit.ParseRules(strings.NewReader("a b c\n")); err == nil {
+		t.Fatal("Expected an error reading 'a b c'")
+	}
+}
+
+func TestURIClean(t *testing.T) {
+	uri, err := urlkit.FromJSON([]byte(`{"scheme":"https","host":"mysite.com","params":{"z":"1","utm_source":"x","a":"2"}}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	removed := uri.Clean(urlkit.DefaultRules())
+	if out := uri.String(); out != "https://mysite.com?z=1&a=2" {
+		t.Fatalf("Expected 'https://mysite.com?z=1&a=2', got %s", out)
+	}
+	if removed.Encode() != "utm_source=x" {
+		t.Fatalf("Expected to remove 'utm_source=x', removed %s", removed.Encode())
+	}
+
+	uri = urlkit.URI{Scheme: "https", Host: "mysite.com:8080", RawParams: []string{"fbclid=1", "id=2"}, Query: "gclid=3&x=4"}
+	uri.Clean(urlkit.DefaultRules())
+	if len(uri.RawParams) != 1 || uri.Query != "x=4" {
+		t.Fatalf("Expected tracking parameters to be removed, got %v and %s", uri.RawParams, uri.Query)
+	}
+}
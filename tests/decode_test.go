@@ -0,0 +1,74 @@
+package cmd_test
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/cmmorrow/url/urlkit"
+)
+
+type decodeTest struct {
+	input    string
+	opts     urlkit.DecodeOptions
+	expected string
+}
+
+var decodeTests = []decodeTest{
+	{"a%2Fb+c%2Bd", urlkit.DecodeOptions{}, "a/b c+d"},
+	{"a%2Fb+c%2Bd", urlkit.DecodeOptions{Component: urlkit.ComponentForm}, "a/b c+d"},
+	{"a%2Fb+c%2Bd", urlkit.DecodeOptions{Component: urlkit.ComponentPath}, "a/b+c+d"},
+	{"a%2fb+c", urlkit.DecodeOptions{Component: urlkit.ComponentQueryValue}, "a/b+c"},
+	{"100%+off%2", urlkit.DecodeOptions{Component: urlkit.ComponentPath, Lenient: true}, "100%+off%2"},
+	{"%E4%BD%A0%zz", urlkit.DecodeOptions{Lenient: true}, "你%zz"},
+	{"a+b%20c", urlkit.DecodeOptions{Component: urlkit.ComponentForm, Strict: true}, "a b c"},
+	{"a/b:c@d", urlkit.DecodeOptions{Component: urlkit.ComponentPath, Strict: true}, "a/b:c@d"},
+	{"a~b", urlkit.DecodeOptions{Strict: true}, "a~b"},
+	{"a~b+c/d?e", urlkit.DecodeOptions{Strict: true}, "a~b c/d?e"},
+}
+
+func TestDecodeComponent(t *testing.T) {
+	for _, test := range decodeTests {
+		out, err := urlkit.DecodeComponent(test.input, test.opts)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if out != test.expected {
+			t.Fatalf("Expected '%s', got %s", test.expected, out)
+		}
+	}
+}
+
+var decodeErrorTests = []errorTest{
+	{decodeComponentErr("ab%zzcd", urlkit.DecodeOptions{}), urlkit.KindInvalidInput, 2},
+	{decodeComponentErr("a b", urlkit.DecodeOptions{Component: urlkit.ComponentPath, Strict: true}), urlkit.KindInvalidInput, 1},
+	{decodeComponentErr("a/b", urlkit.DecodeOptions{Component: urlkit.ComponentPathSegment, Strict: true}), urlkit.KindInvalidInput, 1},
+	{decodeComponentErr("a?b", urlkit.DecodeOptions{Component: urlkit.ComponentPath, Strict: true}), urlkit.KindInvalidInput, 1},
+}
+
+func decodeComponentErr(s string, opts urlkit.DecodeOptions) error {
+	_, err := urlkit.DecodeComponent(s, opts)
+	return err
+}
+
+func TestDecodeComponentErrors(t *testing.T) {
+	for i, test := range decodeErrorTests {
+		var e *urlkit.Error
+		if !errors.As(test.err, &e) {
+			t.Fatalf("%d: expected *urlkit.Error, got %v", i, test.err)
+		}
+		if e.Kind != test.kind || e.Offset != test.offset {
+			t.Fatalf("%d: expected %s at %d, got %s at %d", i, test.kind, test.offset, e.Kind, e.Offset)
+		}
+	}
+}
+
+func TestInvalidEscapes(t *testing.T) {
+	expected := []urlkit.InvalidEscape{{Offset: 3, Escape: "%+o"}, {Offset: 11, Escape: "%2"}}
+	if out := urlkit.InvalidEscapes("100%+off%20%2"); !reflect.DeepEqual(out, expected) {
+		t.Fatalf("Expected %v, got %v", expected, out)
+	}
+	if out := urlkit.InvalidEscapes("a%20b"); out != nil {
+		t.Fatalf("Expected no invalid escapes, got %v", out)
+	}
+}
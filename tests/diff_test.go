@@ -0,0 +1,52 @@
+package cmd_test
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/cmmorrow/url/urlkit"
+)
+
+func split(t *testing.T, rawURL string) urlkit.Components {
+	u, err := urlkit.Parse(rawURL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c, err := urlkit.Split(u, urlkit.Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return c
+}
+
+type diffTest struct {
+	a, b     string
+	expected []urlkit.Change
+}
+
+var diffTests = []diffTest{
+	{"https://mysite.com/a?x=1", "https://mysite.com/a?x=1", nil},
+	{"https://mysite.com:8080/a#top", "http://mysite.com/a?x=1", []urlkit.Change{
+		{Component: "scheme", Kind: urlkit.Changed, Old: "https", New: "http"},
+		{Component: "port", Kind: urlkit.Removed, Old: "8080"},
+		{Component: "fragment", Kind: urlkit.Removed, Old: "top"},
+		{Component: "param", Key: "x", Kind: urlkit.Added, New: "1"},
+	}},
+	{"https://mysite.com/?page=1&tag=x&tag=y", "https://mysite.com/?tag=y&page=2&tag=z&tag=w", []urlkit.Change{
+		{Component: "param", Key: "page", Kind: urlkit.Changed, Old: "1", New: "2"},
+		{Component: "param", Key: "tag", Kind: urlkit.Changed, Old: "x", New: "z"},
+		{Component: "param", Key: "tag", Kind: urlkit.Added, New: "w"},
+	}},
+	{"https://mysite.com/?t=1&t=2", "https://mysite.com/?t=2&t=1", []urlkit.Change{
+		{Component: "param", Key: "t", Kind: urlkit.Reordered},
+	}},
+}
+
+func TestDiff(t *testing.T) {
+	for _, test := range diffTests {
+		changes := urlkit.Diff(split(t, test.a), split(t, test.b))
+		if !reflect.DeepEqual(changes, test.expected) {
+			t.Fatalf("Diff of '%s' and '%s': expected %v, got %v", test.a, test.b, test.expected, changes)
+		}
+	}
+}
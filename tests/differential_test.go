@@ -0,0 +1,133 @@
+/*
+Copyright © 2022 Chris Morrow cmmorrow@gmail.com
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program. If not, see <http://www.gnu.org/licenses/>.
+*/
+package cmd_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/cmmorrow/url/urlkit"
+)
+
+type differentialTest struct {
+	input    string
+	expected []string
+}
+
+var differentialTests = []differentialTest{
+	{"https://mysite.com/a/b?q=1#top", nil},
+	{"HTTPS://MySite.COM:443/%7euser", nil},
+	{"http://mysite.com\\@evil.com/", []string{
+		`parser-differential: rejected by go (net/url: invalid userinfo) and rfc3986 (invalid character '\\' in userinfo), accepted by whatwg`,
+	}},
+	{"http://mysite.com/a/../admin", []string{
+		`parser-differential: path: go and rfc3986 read "/a/../admin", whatwg read "/admin"`,
+	}},
+	{"http://0x7f.1/", []string{
+		`parser-differential: host: go and rfc3986 read "0x7f.1", whatwg read "127.0.0.1"`,
+	}},
+	{"http:/mysite.com/x", []string{
+		`parser-differential: host: go and rfc3986 read "", whatwg read "mysite.com"`,
+		`parser-differential: path: go and rfc3986 read "/mysite.com/x", whatwg read "/x"`,
+	}},
+	{"http://mysite.com/a\\b", []string{
+		`parser-differential: rejected by rfc3986 (invalid character '\\' in path), accepted by go and whatwg`,
+		`parser-differential: path: go read "/a%5Cb", whatwg read "/a/b"`,
+	}},
+	{"http://mysite.com/a b", []string{
+		`parser-differential: rejected by rfc3986 (invalid character ' ' in path), accepted by go and whatwg`,
+	}},
+	{"http://my site.com/", nil},
+}
+
+func TestFindParserDifferentials(t *testing.T) {
+	for _, test := range differentialTests {
+		findings := urlkit.FindParserDifferentials(urlkit.CompareParsers(test.input))
+		if len(findings) != len(test.expected) {
+			t.Fatalf("Expected %d findings for %s, got %v", len(test.expected), test.input, findings)
+		}
+		for i, f := range findings {
+			if f.String() != test.expected[i] {
+				t.Fatalf("Expected '%s', got %s", test.expected[i], f)
+			}
+		}
+	}
+}
+
+func TestCompareParsers(t *testing.T) {
+	results := urlkit.CompareParsers("http://0x7f.1:80/")
+	if len(results) != len(urlkit.ParserNames) {
+		t.Fatalf("Expected a result for each parser, got %v", results)
+	}
+	for _, r := range results {
+		if r.Components == nil {
+			t.Fatalf("Expected %s to accept the URL, got %s", r.Parser, r.Error)
+		}
+	}
+	if c := results[2].Components; results[2].Parser != urlkit.ParserWHATWG || c.Host != "127.0.0.1" || c.Port != "" {
+		t.Fatalf("Expected whatwg to read 127.0.0.1 without the default port, got %+v", c)
+	}
+	if c := results[0].Components; c.Host != "0x7f.1" || c.Port != "80" {
+		t.Fatalf("Expected go to read 0x7f.1:80, got %+v", c)
+	}
+}
+
+type inspectTest struct {
+	input  string
+	opts   urlkit.InspectOptions
+	checks []string
+}
+
+var inspectTests = []inspectTest{
+	{"http://mysite.com\\@evil.com/", urlkit.InspectOptions{Differential: true, Redirects: true}, []string{urlkit.CheckParserDifferential}},
+	{"http://mysite.com\\@evil.com/", urlkit.InspectOptions{Differential: true, DoubleEncoding: true, Homograph: true}, []string{urlkit.CheckParserDifferential}},
+	{"http://mysite.com\\@evil.com/", urlkit.InspectOptions{}, []string{urlkit.CheckParserDifferential}},
+	{"https://mysite.com/a/../b?next=//evil.com", urlkit.InspectOptions{Differential: true, Redirects: true}, []string{urlkit.CheckParserDifferential, urlkit.CheckOpenRedirect}},
+	{"https://mysite.com/?next=//evil.com", urlkit.InspectOptions{}, []string{urlkit.CheckOpenRedirect}},
+}
+
+func TestInspect(t *testing.T) {
+	for _, test := range inspectTests {
+		findings, parsers, err := urlkit.Inspect(test.input, test.opts)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var checks []string
+		for _, f := range findings {
+			checks = append(checks, f.Check)
+		}
+		if strings.Join(checks, ",") != strings.Join(test.checks, ",") {
+			t.Fatalf("Expected '%v' for %s, got %v", test.checks, test.input, findings)
+		}
+		if (parsers != nil) != (checks[0] == urlkit.CheckParserDifferential) {
+			t.Fatalf("Expected the parser results only with a differential check, got %v", parsers)
+		}
+	}
+
+	// Without --differential, a URL that Go rejects is an error for the
+	// checks that need it, as is a URL that every parser rejects.
+	if _, _, err := urlkit.Inspect("http://mysite.com\\@evil.com/", urlkit.InspectOptions{Redirects: true}); urlkit.KindOf(err) != urlkit.KindInvalidInput {
+		t.Fatalf("Expected an invalid input error, got %v", err)
+	}
+	if _, _, err := urlkit.Inspect("http://my site.com/", urlkit.InspectOptions{Differential: true, Redirects: true}); urlkit.KindOf(err) != urlkit.KindInvalidInput {
+		t.Fatalf("Expected an invalid input error, got %v", err)
+	}
+	findings, parsers, err := urlkit.Inspect("http://my site.com/", urlkit.InspectOptions{Differential: true})
+	if err != nil || len(findings) != 0 || len(parsers) != len(urlkit.ParserNames) {
+		t.Fatalf("Expected no findings and the parser results, got %v, %v, %v", findings, parsers, err)
+	}
+}
@@ -0,0 +1,55 @@
+package cmd_test
+
+import (
+	"testing"
+
+	"github.com/cmmorrow/url/urlkit"
+)
+
+const encodeInput = "a/b c+d&e=f?g#h:i@j ~é"
+
+type encodeTest struct {
+	component string
+	standard  string
+	expected  string
+}
+
+var encodeTests = []encodeTest{
+	{urlkit.ComponentPath, urlkit.StandardRFC3986, "a/b%20c+d&e=f%3Fg%23h:i@j%20~%C3%A9"},
+	{urlkit.ComponentPathSegment, urlkit.StandardRFC3986, "a%2Fb%20c+d&e=f%3Fg%23h:i@j%20~%C3%A9"},
+	{urlkit.ComponentQuery, urlkit.StandardRFC3986, "a/b%20c+d&e=f?g%23h:i@j%20~%C3%A9"},
+	{urlkit.ComponentQueryValue, urlkit.StandardRFC3986, "a/b%20c%2Bd%26e%3Df?g%23h:i@j%20~%C3%A9"},
+	{urlkit.ComponentFragment, urlkit.StandardRFC3986, "a/b%20c+d&e=f?g%23h:i@j%20~%C3%A9"},
+	{urlkit.ComponentUserinfo, urlkit.StandardRFC3986, "a%2Fb%20c+d&e=f%3Fg%23h:i%40j%20~%C3%A9"},
+	{urlkit.ComponentHost, urlkit.StandardRFC3986, "a%2Fb%20c+d&e=f%3Fg%23h%3Ai%40j%20~%C3%A9"},
+	{urlkit.ComponentForm, urlkit.StandardRFC3986, "a%2Fb+c%2Bd%26e%3Df%3Fg%23h%3Ai%40j+%7E%C3%A9"},
+	{urlkit.ComponentQuery, urlkit.StandardWHATWG, "a/b%20c+d&e=f?g%23h:i@j%20~%C3%A9"},
+	{urlkit.ComponentQueryValue, urlkit.StandardWHATWG, "a%2Fb%20c%2Bd%26e%3Df%3Fg%23h%3Ai%40j%20~%C3%A9"},
+	{urlkit.ComponentFragment, urlkit.StandardWHATWG, "a/b%20c+d&e=f?g#h:i@j%20~%C3%A9"},
+	{urlkit.ComponentUserinfo, urlkit.StandardWHATWG, "a%2Fb%20c+d&e%3Df%3Fg%23h%3Ai%40j%20~%C3%A9"},
+}
+
+func TestEncodeComponent(t *testing.T) {
+	for _, test := range encodeTests {
+		set, err := urlkit.ComponentSet(test.component, test.standard)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if out := urlkit.EncodeComponent(encodeInput, set); out != test.expected {
+			t.Fatalf("Encoding %s (%s): expected '%s', got %s", test.component, test.standard, test.expected, out)
+		}
+	}
+}
+
+func TestEncodeSetOverrides(t *testing.T) {
+	set, _ := urlkit.ComponentSet(urlkit.ComponentPath, urlkit.StandardRFC3986)
+	if out := urlkit.EncodeComponent("a/b c+d", set.Without("/+").With(" ")); out != "a%2Fb c%2Bd" {
+		t.Fatalf("Expected 'a%%2Fb c%%2Bd', got %s", out)
+	}
+	if _, err := urlkit.ComponentSet("bogus", urlkit.StandardRFC3986); err == nil {
+		t.Fatal("Expected an error for an unknown component")
+	}
+	if _, err := urlkit.ComponentSet(urlkit.ComponentPath, "bogus"); err == nil {
+		t.Fatal("Expected an error for an unknown standard")
+	}
+}
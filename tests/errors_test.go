@@ -0,0 +1,60 @@
+package cmd_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/cmmorrow/url/urlkit"
+)
+
+type errorTest struct {
+	err    error
+	kind   urlkit.Kind
+	offset int
+}
+
+func parseErr(s string) error {
+	_, err := urlkit.Parse(s)
+	return err
+}
+
+func decodeErr(s string) error {
+	_, err := urlkit.Decode(s)
+	return err
+}
+
+func asciiErr(s string) error {
+	_, err := urlkit.ToASCII(s)
+	return err
+}
+
+func jsonErr(s string) error {
+	_, err := urlkit.FromJSON([]byte(s))
+	return err
+}
+
+var errorTests = []errorTest{
+	{parseErr("::bad"), urlkit.KindInvalidInput, 0},
+	{parseErr("http://mysite.com/%zz"), urlkit.KindInvalidInput, 18},
+	{parseErr("http://mysite.com:80x/"), urlkit.KindInvalidInput, 17},
+	{parseErr("http://[::1/"), urlkit.KindInvalidInput, 7},
+	{parseErr("http://mysite.com/\x7f"), urlkit.KindInvalidInput, 18},
+	{decodeErr("ab%2"), urlkit.KindInvalidInput, 2},
+	{asciiErr("mysite.xn--zz.com"), urlkit.KindIDNA, 7},
+	{jsonErr(`{"scheme": 1}`), urlkit.KindJSON, 12},
+}
+
+func TestErrors(t *testing.T) {
+	for i, test := range errorTests {
+		var e *urlkit.Error
+		if !errors.As(test.err, &e) {
+			t.Fatalf("%d: expected *urlkit.Error, got %v", i, test.err)
+		}
+		if e.Kind != test.kind || e.Offset != test.offset {
+			t.Fatalf("%d: expected %s at %d, got %s at %d", i, test.kind, test.offset, e.Kind, e.Offset)
+		}
+		if urlkit.KindOf(test.err) != test.kind {
+			t.Fatalf("%d: KindOf returned %s", i, urlkit.KindOf(test.err))
+		}
+	}
+}
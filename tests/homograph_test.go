@@ -0,0 +1,110 @@
+/*
+Copyright © 2022 Chris Morrow cmmorrow@gmail.com
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program. If not, see <http://www.gnu.org/licenses/>.
+*/
+package cmd_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/cmmorrow/url/urlkit"
+)
+
+var skeletonTests = []struct {
+	a, b string
+}{
+	{"аpple", "apple"},
+	{"ѕсоре", "scope"},
+	{"rnicrosoft", "microsoft"},
+	{"paypaI", "paypal"},
+	{"ｇｏｏｇｌｅ", "google"},
+}
+
+func TestSkeleton(t *testing.T) {
+	for _, test := range skeletonTests {
+		if a, b := urlkit.Skeleton(test.a), urlkit.Skeleton(test.b); a != b {
+			t.Fatalf("Expected '%s' to be confusable with %s, got %s and %s", test.a, test.b, a, b)
+		}
+	}
+	if urlkit.Skeleton("apple") == urlkit.Skeleton("apples") {
+		t.Fatal("Expected 'apple' not to be confusable with apples")
+	}
+}
+
+type homographTest struct {
+	input  string
+	checks []string
+}
+
+var homographTests = []homographTest{
+	{"https://аpple.com/", []string{urlkit.CheckMixedScript, urlkit.CheckConfusable, urlkit.CheckConfusable}},
+	{"https://xn--pple-43d.com/", []string{urlkit.CheckMixedScript, urlkit.CheckConfusable, urlkit.CheckConfusable}},
+	{"https://ѕсоре.com/", []string{urlkit.CheckConfusable, urlkit.CheckConfusable}},
+	{"https://login.pаypal.com.evil.net/", []string{urlkit.CheckMixedScript, urlkit.CheckConfusable, urlkit.CheckConfusable}},
+	{"https://rnicrosoft.com/", []string{urlkit.CheckConfusable}},
+	{"https://mysite.com/a%E2%80%8Bb?q=x‍y", []string{urlkit.CheckInvisible, urlkit.CheckInvisible}},
+	{"https://apple.com/", nil},
+	{"https://www.paypal.com/", nil},
+	{"https://日本語テキスト.jp/", nil},
+	{"https://bücher.de/", nil},
+	{"https://пример.рф/", nil},
+}
+
+func TestFindHomographs(t *testing.T) {
+	brands := urlkit.Brands{"apple.com", "paypal.com", "microsoft.com", "scope.com"}
+	for _, test := range homographTests {
+		findings, err := urlkit.FindHomographs(test.input, brands)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var checks []string
+		for _, f := range findings {
+			checks = append(checks, f.Check)
+		}
+		if strings.Join(checks, ",") != strings.Join(test.checks, ",") {
+			t.Fatalf("Expected '%v' for %s, got %v", test.checks, test.input, findings)
+		}
+	}
+}
+
+func TestFindHostHomographsPlainASCII(t *testing.T) {
+	for _, input := range []string{"https://rnicrosoft.com/", "https://www.paypal.com/"} {
+		u, err := urlkit.Parse(input)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if findings := urlkit.FindHostHomographs(u, nil); findings != nil {
+			t.Fatalf("Expected no findings for %s, got %v", input, findings)
+		}
+	}
+	u, err := urlkit.Parse("https://rnicrosoft.com/")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if findings := urlkit.FindHostHomographs(u, urlkit.Brands{"microsoft.com"}); len(findings) != 1 {
+		t.Fatalf("Expected rnicrosoft.com to be confusable with microsoft.com, got %v", findings)
+	}
+}
+
+func TestParseBrands(t *testing.T) {
+	brands, err := urlkit.ParseBrands(strings.NewReader("# brands\nApple.com\n\n  paypal.com  \n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Join(brands, ",") != "apple.com,paypal.com" {
+		t.Fatalf("Expected 'apple.com,paypal.com', got %v", brands)
+	}
+}
@@ -0,0 +1,122 @@
+/*
+Copyright © 2022 Chris Morrow cmmorrow@gmail.com
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program. If not, see <http://www.gnu.org/licenses/>.
+*/
+package cmd_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/cmmorrow/url/urlkit"
+)
+
+type idnaTest struct {
+	profile      string
+	transitional bool
+	input        string
+	expected     string
+}
+
+var idnaTests = []idnaTest{
+	{urlkit.IDNAPunycode, false, "Bücher.de", "xn--Bcher-kva.de"},
+	{urlkit.IDNAPunycode, false, "a_b.com", "a_b.com"},
+	{urlkit.IDNALookup, false, "Bücher.de", "xn--bcher-kva.de"},
+	{urlkit.IDNALookup, false, "faß.de", "xn--fa-hia.de"},
+	{urlkit.IDNALookup, true, "faß.de", "fass.de"},
+	{urlkit.IDNALookup, false, "βόλος.gr", "xn--nxasmm1c.gr"},
+	{urlkit.IDNALookup, true, "βόλος.gr", "xn--nxasmq6b.gr"},
+	{urlkit.IDNADisplay, false, "ＥＸＡＭＰＬＥ.com", "example.com"},
+	{urlkit.IDNARegistration, false, "bücher.de", "xn--bcher-kva.de"},
+	{urlkit.IDNALookup, false, "א1.com", "xn--1-zhc.com"},
+}
+
+func TestIDNAProfiles(t *testing.T) {
+	for _, test := range idnaTests {
+		opts, err := urlkit.DefaultIDNAOptions(test.profile)
+		if err != nil {
+			t.Fatal(err)
+		}
+		opts.Transitional = test.transitional
+		out, err := opts.ToASCII(test.input)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if out != test.expected {
+			t.Fatalf("Expected '%s', got %s", test.expected, out)
+		}
+	}
+}
+
+type idnaErrorTest struct {
+	opts   urlkit.IDNAOptions
+	input  string
+	rule   string
+	offset int
+}
+
+func idnaProfile(profile string) urlkit.IDNAOptions {
+	opts, _ := urlkit.DefaultIDNAOptions(profile)
+	return opts
+}
+
+var idnaErrorTests = []idnaErrorTest{
+	{idnaProfile(urlkit.IDNALookup), "my_site.com", "STD3", 2},
+	{urlkit.IDNAOptions{STD3: true}, "my_site.com", "STD3", 0},
+	{idnaProfile(urlkit.IDNALookup), "www.ab--cd.com", "V2", 6},
+	{idnaProfile(urlkit.IDNALookup), "-ab.com", "V3", 0},
+	{idnaProfile(urlkit.IDNALookup), "ab-.com", "V3", 2},
+	{idnaProfile(urlkit.IDNALookup), "́a.com", "V5", 0},
+	{idnaProfile(urlkit.IDNALookup), "xn--a-wbb.com", "V5", 0},
+	{idnaProfile(urlkit.IDNALookup), "a‍b.com", "C", 1},
+	{idnaProfile(urlkit.IDNALookup), "www.aא.com", "B", 4},
+	{idnaProfile(urlkit.IDNALookup), "mysite.xn--zz.com", "P4", 7},
+	{idnaProfile(urlkit.IDNALookup), "a⒈.com", "P1", 1},
+	{idnaProfile(urlkit.IDNARegistration), "Bücher.de", "P1", 0},
+	{idnaProfile(urlkit.IDNARegistration), "a..com", "A4", 2},
+}
+
+func TestIDNAErrors(t *testing.T) {
+	for i, test := range idnaErrorTests {
+		_, err := test.opts.ToASCII(test.input)
+		var e *urlkit.Error
+		if !errors.As(err, &e) {
+			t.Fatalf("%d: expected *urlkit.Error, got %v", i, err)
+		}
+		var ie *urlkit.IDNAError
+		if !errors.As(err, &ie) {
+			t.Fatalf("%d: expected *urlkit.IDNAError, got %v", i, err)
+		}
+		if e.Kind != urlkit.KindIDNA || ie.Rule != test.rule || e.Offset != test.offset {
+			t.Fatalf("%d: expected %s at %d, got %s at %d: %v", i, test.rule, test.offset, ie.Rule, e.Offset, err)
+		}
+	}
+}
+
+func TestIDNAOptionsOverride(t *testing.T) {
+	opts := idnaProfile(urlkit.IDNALookup)
+	opts.STD3 = false
+	if out, err := opts.ToASCII("my_site.com"); err != nil || out != "my_site.com" {
+		t.Fatalf("Expected 'my_site.com', got %s, %v", out, err)
+	}
+	opts = idnaProfile(urlkit.IDNALookup)
+	opts.CheckJoiners = false
+	if _, err := opts.ToASCII("a‍b.com"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := urlkit.DefaultIDNAOptions("browser"); err == nil {
+		t.Fatal("Expected an error for an unknown profile")
+	}
+}
@@ -0,0 +1,112 @@
+/*
+Copyright © 2022 Chris Morrow cmmorrow@gmail.com
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program. If not, see <http://www.gnu.org/licenses/>.
+*/
+package cmd_test
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/cmmorrow/url/cmd"
+	"github.com/cmmorrow/url/urlkit"
+)
+
+type inputTest struct {
+	input    string
+	null     bool
+	expected []cmd.InputRecord
+}
+
+var inputTests = []inputTest{
+	{"a\nb\n", false, []cmd.InputRecord{{Source: "-", Line: 1, Text: "a"}, {Source: "-", Line: 2, Text: "b"}}},
+	{"a\r\nb\r\n", false, []cmd.InputRecord{{Source: "-", Line: 1, Text: "a"}, {Source: "-", Line: 2, Text: "b"}}},
+	{"a\nb", false, []cmd.InputRecord{{Source: "-", Line: 1, Text: "a"}, {Source: "-", Line: 2, Text: "b"}}},
+	{"\na\n\n \t\nb\n\n", false, []cmd.InputRecord{{Source: "-", Line: 2, Text: "a"}, {Source: "-", Line: 5, Text: "b"}}},
+	{" a b \n", false, []cmd.InputRecord{{Source: "-", Line: 1, Text: " a b "}}},
+	{"", false, nil},
+	{"a\x00b\x00", true, []cmd.InputRecord{{Source: "-", Line: 1, Text: "a"}, {Source: "-", Line: 2, Text: "b"}}},
+	{"a\x00\x00b", true, []cmd.InputRecord{{Source: "-", Line: 1, Text: "a"}, {Source: "-", Line: 3, Text: "b"}}},
+	{"a b\nc\x00d\n", true, []cmd.InputRecord{{Source: "-", Line: 1, Text: "a b\nc"}, {Source: "-", Line: 2, Text: "d"}}},
+	{"a\nb\n", true, []cmd.InputRecord{{Source: "-", Line: 1, Text: "a\nb"}}},
+}
+
+func readInputs(b cmd.Batch, args []string) ([]cmd.InputRecord, error) {
+	var records []cmd.InputRecord
+	err := b.ReadInputs(args, func(rec cmd.InputRecord) {
+		records = append(records, rec)
+	})
+	return records, err
+}
+
+func TestReadInputs(t *testing.T) {
+	for _, test := range inputTests {
+		records, err := readInputs(cmd.Batch{Stdin: strings.NewReader(test.input), Null: test.null}, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(records, test.expected) {
+			t.Fatalf("Expected '%v' for %q, got %v", test.expected, test.input, records)
+		}
+	}
+}
+
+func TestReadInputsFiles(t *testing.T) {
+	dir := t.TempDir()
+	first := filepath.Join(dir, "first.txt")
+	second := filepath.Join(dir, "second.txt")
+	if err := os.WriteFile(first, []byte("a\r\n\nb"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(second, []byte("c\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	b := cmd.Batch{Files: []string{first, second}, Stdin: strings.NewReader("s\n")}
+
+	// Stdin is only read with - and is read before the files.
+	records, err := readInputs(b, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []cmd.InputRecord{{Source: first, Line: 1, Text: "a"}, {Source: first, Line: 3, Text: "b"}, {Source: second, Line: 1, Text: "c"}}
+	if !reflect.DeepEqual(records, expected) {
+		t.Fatalf("Expected '%v', got %v", expected, records)
+	}
+	records, err = readInputs(b, []string{"-"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected = append([]cmd.InputRecord{{Source: "-", Line: 1, Text: "s"}}, expected...)
+	if !reflect.DeepEqual(records, expected) {
+		t.Fatalf("Expected '%v', got %v", expected, records)
+	}
+
+	b.Files = append(b.Files, filepath.Join(dir, "missing.txt"))
+	records, err = readInputs(b, nil)
+	if urlkit.KindOf(err) != urlkit.KindIO || len(records) != 3 {
+		t.Fatalf("Expected an I/O error after 3 records, got %v, %v", records, err)
+	}
+}
+
+func TestReadInputsTooLong(t *testing.T) {
+	input := strings.Repeat("a", 2*1024*1024) + "\n"
+	_, err := readInputs(cmd.Batch{Stdin: strings.NewReader(input)}, nil)
+	if urlkit.KindOf(err) != urlkit.KindIO {
+		t.Fatalf("Expected an I/O error, got %v", err)
+	}
+}
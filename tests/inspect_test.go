@@ -0,0 +1,52 @@
+package cmd_test
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/cmmorrow/url/urlkit"
+)
+
+func TestDecodeLayers(t *testing.T) {
+	opts := urlkit.DecodeOptions{Component: urlkit.ComponentPath, Lenient: true}
+	layers, fixed, err := urlkit.DecodeLayers("%2525252F", opts, urlkit.DefaultMaxDepth)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []string{"%2525252F", "%25252F", "%252F", "%2F", "/"}
+	if !reflect.DeepEqual(layers, expected) || !fixed {
+		t.Fatalf("Expected %v, got %v (fixed point %v)", expected, layers, fixed)
+	}
+
+	layers, fixed, _ = urlkit.DecodeLayers("%2525252F", opts, 2)
+	if len(layers) != 3 || fixed {
+		t.Fatalf("Expected 2 layers without a fixed point, got %v (fixed point %v)", layers, fixed)
+	}
+
+	layers, fixed, err = urlkit.DecodeLayers("100%25", urlkit.DecodeOptions{}, urlkit.DefaultMaxDepth)
+	if err != nil || !reflect.DeepEqual(layers, []string{"100%25", "100%"}) || !fixed {
+		t.Fatalf("Expected to stop at '100%%', got %v (fixed point %v, %v)", layers, fixed, err)
+	}
+
+	if _, _, err := urlkit.DecodeLayers("%zz", urlkit.DecodeOptions{}, urlkit.DefaultMaxDepth); err == nil {
+		t.Fatal("Expected an error decoding '%zz'")
+	}
+}
+
+func TestFindDoubleEncoding(t *testing.T) {
+	findings, err := urlkit.FindDoubleEncoding("https://mysite.com/files/..%252Fetc?a=%2541&b=x+y&c=%2B#top")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(findings) != 2 {
+		t.Fatalf("Expected 2 findings, got %v", findings)
+	}
+	if findings[0].Component != "path" || findings[1].Component != "param" || findings[1].Key != "a" {
+		t.Fatalf("Unexpected findings %v", findings)
+	}
+
+	findings, err = urlkit.FindDoubleEncoding("https://mysite.com/a%20b?x=%2B&y=a+b")
+	if err != nil || len(findings) != 0 {
+		t.Fatalf("Expected no findings, got %v, %v", findings, err)
+	}
+}
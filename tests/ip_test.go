@@ -0,0 +1,117 @@
+/*
+Copyright © 2022 Chris Morrow cmmorrow@gmail.com
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program. If not, see <http://www.gnu.org/licenses/>.
+*/
+package cmd_test
+
+import (
+	"testing"
+
+	"github.com/cmmorrow/url/urlkit"
+)
+
+type ipHostTest struct {
+	host      string
+	version   int
+	canonical string
+	zone      string
+	legacy    bool
+	class     string
+}
+
+var ipHostTests = []ipHostTest{
+	{"127.0.0.1", 4, "127.0.0.1", "", false, urlkit.ClassLoopback},
+	{"0x7f.1", 4, "127.0.0.1", "", true, urlkit.ClassLoopback},
+	{"0177.0.0.1", 4, "127.0.0.1", "", true, urlkit.ClassLoopback},
+	{"127.1", 4, "127.0.0.1", "", true, urlkit.ClassLoopback},
+	{"2130706433", 4, "127.0.0.1", "", true, urlkit.ClassLoopback},
+	{"0xC0.0250.01", 4, "192.168.0.1", "", true, urlkit.ClassPrivate},
+	{"10.1.2.3.", 4, "10.1.2.3", "", true, urlkit.ClassPrivate},
+	{"172.31.255.255", 4, "172.31.255.255", "", false, urlkit.ClassPrivate},
+	{"172.32.0.1", 4, "172.32.0.1", "", false, urlkit.ClassPublic},
+	{"100.64.0.1", 4, "100.64.0.1", "", false, urlkit.ClassCGNAT},
+	{"169.254.169.254", 4, "169.254.169.254", "", false, urlkit.ClassLinkLocal},
+	{"224.0.0.1", 4, "224.0.0.1", "", false, urlkit.ClassMulticast},
+	{"198.51.100.7", 4, "198.51.100.7", "", false, urlkit.ClassDocumentation},
+	{"255.255.255.255", 4, "255.255.255.255", "", false, urlkit.ClassReserved},
+	{"0.0.0.0", 4, "0.0.0.0", "", false, urlkit.ClassUnspecified},
+	{"0", 4, "0.0.0.0", "", true, urlkit.ClassUnspecified},
+	{"8.8.8.8", 4, "8.8.8.8", "", false, urlkit.ClassPublic},
+	{"[::1]", 6, "::1", "", false, urlkit.ClassLoopback},
+	{"::", 6, "::", "", false, urlkit.ClassUnspecified},
+	{"fe80::1%eth0", 6, "fe80::1", "eth0", false, urlkit.ClassLinkLocal},
+	{"[fe80::1%25eth0]", 6, "fe80::1", "eth0", false, urlkit.ClassLinkLocal},
+	{"FD00:0:0:0:0:0:0:1", 6, "fd00::1", "", false, urlkit.ClassPrivate},
+	{"2001:db8::1", 6, "2001:db8::1", "", false, urlkit.ClassDocumentation},
+	{"ff02::1", 6, "ff02::1", "", false, urlkit.ClassMulticast},
+	{"::ffff:192.168.1.1", 6, "::ffff:192.168.1.1", "", false, urlkit.ClassPrivate},
+	{"2606:4700::1111", 6, "2606:4700::1111", "", false, urlkit.ClassPublic},
+	{"[::127.0.0.1]", 6, "::7f00:1", "", false, urlkit.ClassReserved},
+	{"[64:ff9b::192.0.2.1]", 6, "64:ff9b::c000:201", "", false, urlkit.ClassReserved},
+	{"[::ffff:127.0.0.1]", 6, "::ffff:127.0.0.1", "", false, urlkit.ClassLoopback},
+}
+
+func TestParseIPHost(t *testing.T) {
+	for _, test := range ipHostTests {
+		ip, err := urlkit.ParseIPHost(test.host)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if ip == nil {
+			t.Fatalf("Expected an IP address for %s, got nil", test.host)
+		}
+		if ip.Version != test.version || ip.Canonical != test.canonical || ip.Zone != test.zone ||
+			ip.Legacy != test.legacy || ip.Class != test.class {
+			t.Fatalf("Expected '%+v', got %+v", test, *ip)
+		}
+	}
+}
+
+func TestParseIPHostDomains(t *testing.T) {
+	for _, host := range []string{"example.com", "1.2.3.com", "0x7g", "localhost", ""} {
+		ip, err := urlkit.ParseIPHost(host)
+		if err != nil || ip != nil {
+			t.Fatalf("Expected '%s' to be a domain, got %v, %v", host, ip, err)
+		}
+	}
+}
+
+func TestParseIPHostErrors(t *testing.T) {
+	for _, host := range []string{"1.2.3.4.5", "256.1.1.1", "1.2.3.09", "1.2.3.4294967296", "1.0x100.1", "[fe80::zz]", "[::1.2.3.04]", "[::1.2.3]"} {
+		if _, err := urlkit.ParseIPHost(host); urlkit.KindOf(err) != urlkit.KindInvalidInput {
+			t.Fatalf("Expected an invalid input error for '%s', got %v", host, err)
+		}
+	}
+}
+
+func TestSplitIP(t *testing.T) {
+	u, err := urlkit.Parse("http://0x7f.1:8080/")
+	if err != nil {
+		t.Fatal(err)
+	}
+	c, err := urlkit.Split(u, urlkit.Options{Suffixes: urlkit.DefaultSuffixList()})
+	if err != nil {
+		t.Fatal(err)
+	}
+	out, err := c.JSON()
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := `{"fragment":null,"host":"0x7f.1","ip":{"version":4,"canonical":"127.0.0.1","legacy":true,"class":"loopback"},` +
+		`"params":{},"path":"/","port":"8080","scheme":"http","uriPath":null,"user":null}`
+	if string(out) != expected {
+		t.Fatalf("Expected '%s', got %s", expected, out)
+	}
+}
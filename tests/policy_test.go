@@ -0,0 +1,173 @@
+/*
+Copyright © 2022 Chris Morrow cmmorrow@gmail.com
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program. If not, see <http://www.gnu.org/licenses/>.
+*/
+package cmd_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/cmmorrow/url/urlkit"
+)
+
+const testPolicy = `
+schemes: [https]
+allowHosts: ["*.example.com", example.com, "10.*"]
+denyHosts: [internal.example.com]
+ports: [443, "8000-8999"]
+denyIPClasses: [loopback, private, link-local]
+maxLength: 64
+`
+
+type policyTest struct {
+	input string
+	rules []string
+}
+
+var policyTests = []policyTest{
+	{"https://example.com/hook", nil},
+	{"https://api.example.com:8443/hook", nil},
+	{"HTTPS://API.Example.com./hook", nil},
+	{"http://example.com/", []string{urlkit.RuleScheme, urlkit.RulePort}},
+	{"https://evil.com/", []string{urlkit.RuleHostAllow}},
+	{"https://internal.example.com/", []string{urlkit.RuleHostDeny}},
+	{"https://example.com:22/", []string{urlkit.RulePort}},
+	{"https://user:pw@example.com/", []string{urlkit.RuleUserinfo}},
+	{"https://example.com/" + strings.Repeat("a", 64), []string{urlkit.RuleMaxLength}},
+	{"https://0x7f.1/", []string{urlkit.RuleHostAllow, urlkit.RuleIPClass}},
+	{"https://2130706433/", []string{urlkit.RuleHostAllow, urlkit.RuleIPClass}},
+	{"https://012.0.0.1/", []string{urlkit.RuleIPClass}},
+	{"https://[::1]/", []string{urlkit.RuleHostAllow, urlkit.RuleIPClass}},
+	{"https://[fe80::1%25eth0]/", []string{urlkit.RuleHostAllow, urlkit.RuleIPClass}},
+	{"https://8.8.8.8/", []string{urlkit.RuleHostAllow}},
+	{"https://１２７.０.０.１/", []string{urlkit.RuleHostAllow, urlkit.RuleIPClass}},
+	{"https://127.0.0.1./", []string{urlkit.RuleHostAllow, urlkit.RuleIPClass}},
+	{"https://ｉｎｔｅｒｎａｌ.example.com/", []string{urlkit.RuleHostDeny}},
+	{"https://INTERNAL.example.com./", []string{urlkit.RuleHostDeny}},
+	{"https://inter\u00ADnal.example.com/", []string{urlkit.RuleHostDeny}},
+	{"https://internal.exam\u200Bple.com/", []string{urlkit.RuleHostDeny}},
+	{"https://ａｐｉ。example.com/", nil},
+}
+
+func TestPolicyValidate(t *testing.T) {
+	policy, err := urlkit.ParsePolicy(strings.NewReader(testPolicy))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, test := range policyTests {
+		violations, err := policy.Validate(test.input)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var rules []string
+		for _, v := range violations {
+			rules = append(rules, v.Rule)
+		}
+		if strings.Join(rules, ",") != strings.Join(test.rules, ",") {
+			t.Fatalf("Expected '%v' for %s, got %v", test.rules, test.input, violations)
+		}
+	}
+
+	if _, err := policy.Validate("https://1.2.3.4.5/"); urlkit.KindOf(err) != urlkit.KindInvalidInput {
+		t.Fatalf("Expected an invalid input error, got %v", err)
+	}
+}
+
+func TestPolicyNoPort(t *testing.T) {
+	policy, err := urlkit.ParsePolicy(strings.NewReader("ports: [443]\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, input := range []string{"foo://example.com/", "mailto:a@example.com"} {
+		violations, err := policy.Validate(input)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(violations) != 1 || violations[0].Rule != urlkit.RulePort {
+			t.Fatalf("Expected a port violation for %s, got %v", input, violations)
+		}
+	}
+	if violations, err := policy.Validate("foo://example.com:443/"); err != nil || len(violations) != 0 {
+		t.Fatalf("Expected no violations, got %v, %v", violations, err)
+	}
+}
+
+func TestPolicyMappedHosts(t *testing.T) {
+	policy, err := urlkit.ParsePolicy(strings.NewReader("denyHosts: [localhost]\ndenyIPClasses: [loopback]\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, input := range []string{
+		"http://localhost/",
+		"http://ｌocalhost/",
+		"http://LOCALHOST./",
+		"http://ｌｏｃａｌｈｏｓｔ．/",
+	} {
+		violations, err := policy.Validate(input)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(violations) != 1 || violations[0].Rule != urlkit.RuleHostDeny || violations[0].Value != "localhost" {
+			t.Fatalf("Expected localhost to be denied for %s, got %v", input, violations)
+		}
+	}
+	violations, err := policy.Validate("http://１２７.０.０.１/")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expected := "ip-class: host 127.0.0.1 is in the loopback IP class, written as １２７.０.０.１"; len(violations) != 1 || violations[0].String() != expected {
+		t.Fatalf("Expected '%s', got %v", expected, violations)
+	}
+	if _, err := policy.Validate("http://a\u00A0b/"); urlkit.KindOf(err) != urlkit.KindInvalidInput {
+		t.Fatalf("Expected an invalid input error, got %v", err)
+	}
+}
+
+func TestPolicyEmpty(t *testing.T) {
+	policy, err := urlkit.ParsePolicy(strings.NewReader(""))
+	if err != nil {
+		t.Fatal(err)
+	}
+	violations, err := policy.Validate("gopher://127.0.0.1:1/")
+	if err != nil || len(violations) != 0 {
+		t.Fatalf("Expected no violations, got %v, %v", violations, err)
+	}
+	violations, _ = policy.Validate("https://user@example.com/")
+	if len(violations) != 1 || violations[0].Rule != urlkit.RuleUserinfo {
+		t.Fatalf("Expected a userinfo violation, got %v", violations)
+	}
+}
+
+func TestParsePolicyErrors(t *testing.T) {
+	for _, policy := range []string{
+		"schemes: https\n",
+		"allowedHosts: [example.com]\n",
+		"ports: [99999]\n",
+		"ports: [9000-8000]\n",
+		"ports: [http]\n",
+		"denyIPClasses: [internal]\n",
+		"allowHosts: ['[a-']\n",
+		"maxLength: -1\n",
+	} {
+		if _, err := urlkit.ParsePolicy(strings.NewReader(policy)); urlkit.KindOf(err) != urlkit.KindInvalidInput {
+			t.Fatalf("Expected an invalid input error for '%s', got %v", policy, err)
+		}
+	}
+	policy, err := urlkit.ParsePolicy(strings.NewReader(`{"schemes":["HTTPS:"],"ports":[443]}`))
+	if err != nil || policy.Schemes[0] != "https" {
+		t.Fatalf("Expected a JSON policy to be read, got %v, %v", policy, err)
+	}
+}
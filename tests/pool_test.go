@@ -0,0 +1,213 @@
+/*
+Copyright © 2022 Chris Morrow cmmorrow@gmail.com
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program. If not, see <http://www.gnu.org/licenses/>.
+*/
+package cmd_test
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/cmmorrow/url/cmd"
+	"github.com/cmmorrow/url/urlkit"
+)
+
+// waitFor waits for ch to be closed, failing instead of hanging the tests if
+// a worker never gets to close it.
+func waitFor(ch chan struct{}) error {
+	select {
+	case <-ch:
+		return nil
+	case <-time.After(5 * time.Second):
+		return errors.New("timed out")
+	}
+}
+
+func TestBatchOrdered(t *testing.T) {
+	// a does not finish until c has finished, so the results are out of order.
+	cDone := make(chan struct{})
+	process := func(input string) (string, error) {
+		switch input {
+		case "a":
+			if err := waitFor(cDone); err != nil {
+				return "", err
+			}
+		case "c":
+			close(cDone)
+		}
+		return strings.ToUpper(input) + "\n", nil
+	}
+	var stdout, stderr bytes.Buffer
+	b := cmd.Batch{Jobs: 3, Stdin: strings.NewReader("a\nb\nc\n"), Stdout: &stdout, Stderr: &stderr}
+	status, err := b.Run(nil, process, false)
+	if err != nil || status != 0 {
+		t.Fatalf("Expected a status of 0, got %d, %v", status, err)
+	}
+	if expected := "A\nB\nC\n"; stdout.String() != expected || stderr.Len() != 0 {
+		t.Fatalf("Expected '%s', got %s (%s)", expected, stdout.String(), stderr.String())
+	}
+}
+
+func TestBatchUnordered(t *testing.T) {
+	// a does not finish until the result of c has been emitted.
+	cEmitted := make(chan struct{})
+	process := func(input string) (string, error) {
+		if input == "a" {
+			if err := waitFor(cEmitted); err != nil {
+				return "", err
+			}
+		}
+		return input, nil
+	}
+	var emitted []string
+	emit := func(r cmd.Result) {
+		if r.Err != nil {
+			t.Fatal(r.Err)
+		}
+		emitted = append(emitted, r.Record.Text)
+		if r.Record.Text == "c" {
+			close(cEmitted)
+		}
+	}
+	b := cmd.Batch{Jobs: 3, Unordered: true, Stdin: strings.NewReader("a\nb\nc\n")}
+	if err := b.Process(nil, process, emit); err != nil {
+		t.Fatal(err)
+	}
+	order := strings.Join(emitted, "")
+	if len(order) != 3 || strings.Index(order, "c") > strings.Index(order, "a") {
+		t.Fatalf("Expected c to be emitted before a, got %v", emitted)
+	}
+}
+
+func TestBatchSeparator(t *testing.T) {
+	var stdout bytes.Buffer
+	b := cmd.Batch{Jobs: 2, Stdin: strings.NewReader("a\nb\nc\n"), Stdout: &stdout, Stderr: &bytes.Buffer{}}
+	process := func(input string) (string, error) {
+		if input == "b" {
+			return "", &urlkit.Error{Kind: urlkit.KindInvalidInput, Op: "parse", Input: input, Offset: -1, Err: errors.New("bad")}
+		}
+		return input + "\n", nil
+	}
+	if _, err := b.Run(nil, process, true); err != nil {
+		t.Fatal(err)
+	}
+	if expected := "a\n\nc\n"; stdout.String() != expected {
+		t.Fatalf("Expected '%s', got %s", expected, stdout.String())
+	}
+}
+
+func TestBatchWarnings(t *testing.T) {
+	process := func(input string) (string, []urlkit.Finding, error) {
+		u, err := urlkit.Parse(input)
+		if err != nil {
+			return "", nil, err
+		}
+		return input + "\n", urlkit.FindHostHomographs(u, nil), nil
+	}
+	var stdout, stderr bytes.Buffer
+	b := cmd.Batch{Jobs: 2, Stdin: strings.NewReader("https://apple.com/\nhttps://аpple.com/\n"), Stdout: &stdout, Stderr: &stderr}
+	status, err := b.RunWarnings(nil, process, false)
+	if err != nil || status != 0 {
+		t.Fatalf("Expected a status of 0, got %d, %v", status, err)
+	}
+	if expected := "https://apple.com/\nhttps://аpple.com/\n"; stdout.String() != expected {
+		t.Fatalf("Expected '%s', got %s", expected, stdout.String())
+	}
+	lines := strings.Split(strings.TrimSuffix(stderr.String(), "\n"), "\n")
+	for _, line := range lines {
+		if !strings.HasPrefix(line, "-:2: Warning: ") {
+			t.Fatalf("Expected '-:2: Warning: ' prefix, got %s", stderr.String())
+		}
+	}
+	if len(lines) != 2 {
+		t.Fatalf("Expected 2 warnings, got %s", stderr.String())
+	}
+}
+
+type batchStatusTest struct {
+	kinds    []urlkit.Kind
+	expected int
+}
+
+var batchStatusTests = []batchStatusTest{
+	{nil, 0},
+	{[]urlkit.Kind{urlkit.KindValidation}, 5},
+	{[]urlkit.Kind{urlkit.KindValidation, urlkit.KindInvalidInput}, 2},
+	{[]urlkit.Kind{urlkit.KindInvalidInput, urlkit.KindValidation}, 2},
+	{[]urlkit.Kind{urlkit.KindInvalidInput, urlkit.KindIDNA, urlkit.KindInvalidInput}, 3},
+	{[]urlkit.Kind{urlkit.KindJSON, urlkit.KindIO, urlkit.KindIDNA}, 6},
+}
+
+func TestBatchStatus(t *testing.T) {
+	for _, test := range batchStatusTests {
+		// Every input but the last fails with one of the kinds, and the
+		// workers finish them in reverse order.
+		var input strings.Builder
+		for i := range test.kinds {
+			fmt.Fprintf(&input, "%d\n", i)
+		}
+		input.WriteString("ok\n")
+		done := make([]chan struct{}, len(test.kinds)+1)
+		for i := range done {
+			done[i] = make(chan struct{})
+		}
+		process := func(input string) (string, error) {
+			if input == "ok" {
+				close(done[len(test.kinds)])
+				return "ok\n", nil
+			}
+			var i int
+			fmt.Sscan(input, &i)
+			if err := waitFor(done[i+1]); err != nil {
+				return "", err
+			}
+			close(done[i])
+			return "", &urlkit.Error{Kind: test.kinds[i], Op: "test", Input: input, Offset: -1, Err: errors.New("failed")}
+		}
+
+		for _, unordered := range []bool{false, true} {
+			for i := range done {
+				done[i] = make(chan struct{})
+			}
+			var stdout, stderr bytes.Buffer
+			b := cmd.Batch{Jobs: len(done), Unordered: unordered, Stdin: strings.NewReader(input.String()), Stdout: &stdout, Stderr: &stderr}
+			status, err := b.Run(nil, process, false)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if status != test.expected {
+				t.Fatalf("Expected a status of %d for %v, got %d", test.expected, test.kinds, status)
+			}
+			if stdout.String() != "ok\n" || strings.Count(stderr.String(), "\n") != len(test.kinds) {
+				t.Fatalf("Expected ok and %d errors, got %s (%s)", len(test.kinds), stdout.String(), stderr.String())
+			}
+		}
+	}
+}
+
+func TestBatchReadError(t *testing.T) {
+	var records []string
+	b := cmd.Batch{Jobs: 2, Files: []string{"testdata/missing.txt"}}
+	err := b.Process(nil, func(input string) (string, error) { return input, nil }, func(r cmd.Result) {
+		records = append(records, r.Record.Text)
+	})
+	if urlkit.KindOf(err) != urlkit.KindIO || len(records) != 0 {
+		t.Fatalf("Expected an I/O error and no records, got %v, %v", records, err)
+	}
+}
@@ -0,0 +1,206 @@
+/*
+Copyright © 2022 Chris Morrow cmmorrow@gmail.com
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program. If not, see <http://www.gnu.org/licenses/>.
+*/
+package cmd_test
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/cmmorrow/url/urlkit"
+)
+
+// registrableDomainTests are the checkPublicSuffix tests of the Public Suffix
+// List, from https://raw.githubusercontent.com/publicsuffix/list/master/tests/test_psl.txt.
+var registrableDomainTests = []struct {
+	domain   string
+	expected string
+}{
+	{"COM", ""},
+	{"example.COM", "example.com"},
+	{"WwW.example.COM", "example.com"},
+	{".com", ""},
+	{".example", ""},
+	{".example.com", ""},
+	{".example.example", ""},
+	{"example", ""},
+	{"example.example", "example.example"},
+	{"b.example.example", "example.example"},
+	{"a.b.example.example", "example.example"},
+	{"biz", ""},
+	{"domain.biz", "domain.biz"},
+	{"b.domain.biz", "domain.biz"},
+	{"a.b.domain.biz", "domain.biz"},
+	{"com", ""},
+	{"example.com", "example.com"},
+	{"b.example.com", "example.com"},
+	{"a.b.example.com", "example.com"},
+	{"uk.com", ""},
+	{"example.uk.com", "example.uk.com"},
+	{"b.example.uk.com", "example.uk.com"},
+	{"a.b.example.uk.com", "example.uk.com"},
+	{"test.ac", "test.ac"},
+	{"mm", ""},
+	{"c.mm", ""},
+	{"b.c.mm", "b.c.mm"},
+	{"a.b.c.mm", "b.c.mm"},
+	{"jp", ""},
+	{"test.jp", "test.jp"},
+	{"www.test.jp", "test.jp"},
+	{"ac.jp", ""},
+	{"test.ac.jp", "test.ac.jp"},
+	{"www.test.ac.jp", "test.ac.jp"},
+	{"kyoto.jp", ""},
+	{"test.kyoto.jp", "test.kyoto.jp"},
+	{"ide.kyoto.jp", ""},
+	{"b.ide.kyoto.jp", "b.ide.kyoto.jp"},
+	{"a.b.ide.kyoto.jp", "b.ide.kyoto.jp"},
+	{"c.kobe.jp", ""},
+	{"b.c.kobe.jp", "b.c.kobe.jp"},
+	{"a.b.c.kobe.jp", "b.c.kobe.jp"},
+	{"city.kobe.jp", "city.kobe.jp"},
+	{"www.city.kobe.jp", "city.kobe.jp"},
+	{"ck", ""},
+	{"test.ck", ""},
+	{"b.test.ck", "b.test.ck"},
+	{"a.b.test.ck", "b.test.ck"},
+	{"www.ck", "www.ck"},
+	{"www.www.ck", "www.ck"},
+	{"us", ""},
+	{"test.us", "test.us"},
+	{"www.test.us", "test.us"},
+	{"ak.us", ""},
+	{"test.ak.us", "test.ak.us"},
+	{"www.test.ak.us", "test.ak.us"},
+	{"k12.ak.us", ""},
+	{"test.k12.ak.us", "test.k12.ak.us"},
+	{"www.test.k12.ak.us", "test.k12.ak.us"},
+	{"食狮.com.cn", "食狮.com.cn"},
+	{"食狮.公司.cn", "食狮.公司.cn"},
+	{"www.食狮.公司.cn", "食狮.公司.cn"},
+	{"shishi.公司.cn", "shishi.公司.cn"},
+	{"公司.cn", ""},
+	{"食狮.中国", "食狮.中国"},
+	{"www.食狮.中国", "食狮.中国"},
+	{"shishi.中国", "shishi.中国"},
+	{"中国", ""},
+	{"xn--85x722f.com.cn", "xn--85x722f.com.cn"},
+	{"xn--85x722f.xn--55qx5d.cn", "xn--85x722f.xn--55qx5d.cn"},
+	{"www.xn--85x722f.xn--55qx5d.cn", "xn--85x722f.xn--55qx5d.cn"},
+	{"shishi.xn--55qx5d.cn", "shishi.xn--55qx5d.cn"},
+	{"xn--55qx5d.cn", ""},
+	{"xn--85x722f.xn--fiqs8s", "xn--85x722f.xn--fiqs8s"},
+	{"www.xn--85x722f.xn--fiqs8s", "xn--85x722f.xn--fiqs8s"},
+	{"shishi.xn--fiqs8s", "shishi.xn--fiqs8s"},
+	{"xn--fiqs8s", ""},
+}
+
+func TestRegistrableDomain(t *testing.T) {
+	list := urlkit.DefaultSuffixList()
+	for _, test := range registrableDomainTests {
+		out := ""
+		if parts := list.Split(test.domain); parts != nil {
+			out = strings.ToLower(parts.RegistrableDomain)
+		}
+		if out != test.expected {
+			t.Fatalf("Expected '%s' for %s, got %s", test.expected, test.domain, out)
+		}
+	}
+}
+
+type domainPartsTest struct {
+	domain    string
+	icannOnly bool
+	expected  urlkit.DomainParts
+}
+
+var domainPartsTests = []domainPartsTest{
+	{"www.bbc.co.uk", false, urlkit.DomainParts{TLD: "co.uk", RegistrableDomain: "bbc.co.uk", Subdomain: "www", Section: urlkit.SectionICANN}},
+	{"a.b.me.github.io", false, urlkit.DomainParts{TLD: "github.io", RegistrableDomain: "me.github.io", Subdomain: "a.b", Section: urlkit.SectionPrivate}},
+	{"a.b.me.github.io", true, urlkit.DomainParts{TLD: "io", RegistrableDomain: "github.io", Subdomain: "a.b.me", Section: urlkit.SectionICANN}},
+	{"shop.食狮.中国", false, urlkit.DomainParts{TLD: "中国", RegistrableDomain: "食狮.中国", Subdomain: "shop", Section: urlkit.SectionICANN}},
+	{"example.unknown", false, urlkit.DomainParts{TLD: "unknown", RegistrableDomain: "example.unknown", Section: ""}},
+	{"co.uk", false, urlkit.DomainParts{TLD: "co.uk", Section: urlkit.SectionICANN}},
+	{"www.example.com.", false, urlkit.DomainParts{TLD: "com", RegistrableDomain: "example.com", Subdomain: "www", Section: urlkit.SectionICANN}},
+}
+
+func TestSplitDomain(t *testing.T) {
+	for _, test := range domainPartsTests {
+		list := urlkit.DefaultSuffixList()
+		list.ICANNOnly = test.icannOnly
+		parts := list.Split(test.domain)
+		if parts == nil {
+			t.Fatalf("Expected parts for %s, got nil", test.domain)
+		}
+		parts.Labels = nil
+		if !reflect.DeepEqual(*parts, test.expected) {
+			t.Fatalf("Expected '%+v', got %+v", test.expected, *parts)
+		}
+	}
+	for _, host := range []string{"", "127.0.0.1", "::1", "[::1]", ".com", "a..com"} {
+		if parts := urlkit.DefaultSuffixList().Split(host); parts != nil {
+			t.Fatalf("Expected nil for '%s', got %+v", host, *parts)
+		}
+	}
+}
+
+func TestParseSuffixList(t *testing.T) {
+	list, err := urlkit.ParseSuffixList(strings.NewReader(`// ===BEGIN ICANN DOMAINS===
+example
+*.wild.example
+!keep.wild.example
+// ===END ICANN DOMAINS===
+// ===BEGIN PRIVATE DOMAINS===
+hosted.example
+// ===END PRIVATE DOMAINS===
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := map[string]string{
+		"a.b.example":         "b.example",
+		"a.b.wild.example":    "a.b.wild.example",
+		"a.keep.wild.example": "keep.wild.example",
+		"me.hosted.example":   "me.hosted.example",
+		"www.other.invalid":   "other.invalid",
+	}
+	for domain, expected := range tests {
+		if out := list.Split(domain).RegistrableDomain; out != expected {
+			t.Fatalf("Expected '%s' for %s, got %s", expected, domain, out)
+		}
+	}
+}
+
+func TestSplitSuffixes(t *testing.T) {
+	u, err := urlkit.Parse("https://www.example.co.uk/")
+	if err != nil {
+		t.Fatal(err)
+	}
+	c, err := urlkit.Split(u, urlkit.Options{Suffixes: urlkit.DefaultSuffixList()})
+	if err != nil {
+		t.Fatal(err)
+	}
+	out, err := c.JSON()
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := `{"domain":{"tld":"co.uk","registrableDomain":"example.co.uk","subdomain":"www","labels":["www","example","co","uk"],"section":"icann"},` +
+		`"fragment":null,"host":"www.example.co.uk","params":{},"path":"/","port":null,"scheme":"https","uriPath":null,"user":null}`
+	if string(out) != expected {
+		t.Fatalf("Expected '%s', got %s", expected, out)
+	}
+}
@@ -0,0 +1,59 @@
+package cmd_test
+
+import (
+	"testing"
+
+	"github.com/cmmorrow/url/urlkit"
+)
+
+type queryTest struct {
+	rawQuery string
+	encoded  string
+	sorted   string
+}
+
+var queryTests = []queryTest{
+	{"foo=bar&bar=baz", "foo=bar&bar=baz", "bar=baz&foo=bar"},
+	{"b=1&a=2&b=3", "b=1&a=2&b=3", "a=2&b=1&b=3"},
+	{"foo=my+file&x=%2F", "foo=my+file&x=%2F", "foo=my+file&x=%2F"},
+	{"foo&&bar=", "foo=&bar=", "bar=&foo="},
+	{"", "", ""},
+}
+
+func TestQuery(t *testing.T) {
+	for _, test := range queryTests {
+		q, err := urlkit.ParseQuery(test.rawQuery)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if out := q.Encode(); out != test.encoded {
+			t.Fatalf("Expected '%s', got %s", test.encoded, out)
+		}
+		if out := q.Sorted().Encode(); out != test.sorted {
+			t.Fatalf("Expected '%s', got %s", test.sorted, out)
+		}
+		if out := q.URLValues().Encode(); out != test.sorted {
+			t.Fatalf("Expected '%s', got %s", test.sorted, out)
+		}
+	}
+}
+
+func TestParseQueryError(t *testing.T) {
+	q, err := urlkit.ParseQuery("a=1&b=%zz&c=3")
+	if err == nil {
+		t.Fatal("Expected an error decoding 'b=%zz'")
+	}
+	if out := q.Encode(); out != "a=1&c=3" {
+		t.Fatalf("Expected 'a=1&c=3', got %s", out)
+	}
+}
+
+func TestBuildQueryFromJSON(t *testing.T) {
+	uri, err := urlkit.FromJSON([]byte(`{"scheme":"http","host":"mysite.com","Params":{"z":"1","a":["2","3"],"m":""}}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if out := uri.BuildQuery().Encode(); out != "z=1&a=2&a=3&m=" {
+		t.Fatalf("Expected 'z=1&a=2&a=3&m=', got %s", out)
+	}
+}
@@ -0,0 +1,81 @@
+package cmd_test
+
+import (
+	"testing"
+
+	"github.com/cmmorrow/url/urlkit"
+)
+
+func matcher(t *testing.T, pattern string, mode string) urlkit.KeyMatcher {
+	match, err := urlkit.MatchKey(pattern, mode)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return match
+}
+
+type editTest struct {
+	input    string
+	edit     func(urlkit.RawQuery) urlkit.RawQuery
+	expected string
+}
+
+func TestEditQuery(t *testing.T) {
+	tests := []editTest{
+		{"https://mysite.com/?q=a%20b&page=1&x", func(q urlkit.RawQuery) urlkit.RawQuery {
+			return q.Set("page", "2")
+		}, "https://mysite.com/?q=a%20b&page=2&x"},
+		{"https://mysite.com/?a=1&b=2&a=3", func(q urlkit.RawQuery) urlkit.RawQuery {
+			return q.Set("a", "x y")
+		}, "https://mysite.com/?a=x+y&b=2"},
+		{"https://mysite.com/?a=1", func(q urlkit.RawQuery) urlkit.RawQuery {
+			return q.Set("b", "2").Add("a", "&")
+		}, "https://mysite.com/?a=1&b=2&a=%26"},
+		{"https://mysite.com/?utm_source=x&id=1&utm_medium=y#top", func(q urlkit.RawQuery) urlkit.RawQuery {
+			return q.Delete(matcher(t, "utm_*", urlkit.MatchGlob))
+		}, "https://mysite.com/?id=1#top"},
+		{"https://mysite.com/?utm_source=x", func(q urlkit.RawQuery) urlkit.RawQuery {
+			return q.Delete(matcher(t, "utm_.*", urlkit.MatchRegex))
+		}, "https://mysite.com/"},
+		{"https://mysite.com/?q=books&Q=x", func(q urlkit.RawQuery) urlkit.RawQuery {
+			return q.Rename(matcher(t, "q", urlkit.MatchExact), "query")
+		}, "https://mysite.com/?query=books&Q=x"},
+		{"https://mysite.com/?", func(q urlkit.RawQuery) urlkit.RawQuery {
+			return q
+		}, "https://mysite.com/?"},
+	}
+	for _, test := range tests {
+		out, err := urlkit.EditQuery(test.input, func(q urlkit.RawQuery) (urlkit.RawQuery, error) {
+			return test.edit(q), nil
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if out != test.expected {
+			t.Fatalf("Expected '%s', got %s", test.expected, out)
+		}
+	}
+}
+
+func TestRawQueryGet(t *testing.T) {
+	q := urlkit.SplitRawQuery("token=abc&x=1&token=d+e")
+	got := q.Get(matcher(t, "token", urlkit.MatchExact))
+	if out := got.Encode(); out != "token=abc&token=d+e" {
+		t.Fatalf("Expected 'token=abc&token=d+e', got %s", out)
+	}
+	if _, err := urlkit.MatchKey("(", urlkit.MatchRegex); err == nil {
+		t.Fatal("Expected an error compiling '('")
+	}
+}
+
+func TestMatchKeyRegex(t *testing.T) {
+	match := matcher(t, "utm|ref", urlkit.MatchRegex)
+	for key, expected := range map[string]bool{"utm": true, "ref": true, "notutm_x": false, "utm_x": false, "refs": false} {
+		if match(key) != expected {
+			t.Fatalf("Expected '%t' for %s, got %t", expected, key, !expected)
+		}
+	}
+	if _, err := urlkit.MatchKey("utm", "fuzzy"); urlkit.KindOf(err) != urlkit.KindInvalidInput {
+		t.Fatalf("Expected an invalid input error, got %v", err)
+	}
+}
@@ -0,0 +1,105 @@
+/*
+Copyright © 2022 Chris Morrow cmmorrow@gmail.com
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program. If not, see <http://www.gnu.org/licenses/>.
+*/
+package cmd_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/cmmorrow/url/urlkit"
+)
+
+type redirectTest struct {
+	input     string
+	component string
+	key       string
+	message   string
+}
+
+var redirectTests = []redirectTest{
+	{"https://mysite.com/?next=https://evil.com/x", "param", "next", "absolute URL to untrusted host evil.com"},
+	{"https://mysite.com/?next=HTTPS%3A%2F%2Fuser%40evil.com%3A8080", "param", "next", "absolute URL to untrusted host evil.com"},
+	{"https://mysite.com/?next=https:evil.com", "param", "next", "absolute URL to untrusted host evil.com"},
+	{"https://mysite.com/?return_to=//evil.com/", "param", "return_to", "scheme-relative URL to untrusted host evil.com"},
+	{"https://mysite.com/?next=/%5Cevil.com", "param", "next", "URL with a backslash that browsers read as a URL to untrusted host evil.com"},
+	{"https://mysite.com/?next=%5C%5Cevil.com", "param", "next", "URL with a backslash that browsers read as a URL to untrusted host evil.com"},
+	{"https://mysite.com/?next=https:/%5Cevil.com", "param", "next", "URL with a backslash that browsers read as a URL to untrusted host evil.com"},
+	{"https://mysite.com/?next=javascript:alert(1)", "param", "next", "javascript: URL"},
+	{"https://mysite.com/?next=%01%20java%0Ascript:alert(1)", "param", "next", "javascript: URL"},
+	{"https://mysite.com/?next=DATA:text/html,x", "param", "next", "data: URL"},
+	{"https://mysite.com/?next=https%25253A%25252F%25252Fevil.com", "param", "next", "absolute URL to untrusted host evil.com, after decoding 3 times"},
+	{"https://mysite.com/?next=https%3A%2F%2Fmysite.com%2Flogin%3Fredirect_uri%3D%252F%252Fevil.com", "param", "next > redirect_uri", "scheme-relative URL to untrusted host evil.com"},
+	{"https://mysite.com/#next=//evil.com", "fragment", "next", "scheme-relative URL to untrusted host evil.com"},
+	{"https://mysite.com/#!/login?next=//evil.com", "fragment", "next", "scheme-relative URL to untrusted host evil.com"},
+	{"https://mysite.com/#//evil.com", "fragment", "", "scheme-relative URL to untrusted host evil.com"},
+	{"https://mysite.com/?next=//0x7f.1", "param", "next", "scheme-relative URL to untrusted host 127.0.0.1"},
+	{"/login?next=https://evil.com", "param", "next", "absolute URL to untrusted host evil.com"},
+	{"login?next=//evil.com", "param", "next", "scheme-relative URL to untrusted host evil.com"},
+	{"?next=/%5Cevil.com", "param", "next", "URL with a backslash that browsers read as a URL to untrusted host evil.com"},
+	{"#next=https://evil.com", "fragment", "next", "absolute URL to untrusted host evil.com"},
+}
+
+func TestFindRedirects(t *testing.T) {
+	for _, test := range redirectTests {
+		findings, err := urlkit.FindRedirects(test.input, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(findings) != 1 {
+			t.Fatalf("Expected 1 finding for %s, got %v", test.input, findings)
+		}
+		f := findings[0]
+		if f.Check != urlkit.CheckOpenRedirect || f.Component != test.component || f.Key != test.key || f.Message != test.message {
+			t.Fatalf("Expected '%s', got %s", test.message, f)
+		}
+	}
+}
+
+func TestFindRedirectsTrusted(t *testing.T) {
+	trusted, err := urlkit.ParseTrustedHosts(strings.NewReader("# partners\npartner.com\n*.cdn.net\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	safe := []string{
+		"https://mysite.com/?next=/home&q=a+b&n=1",
+		"https://mysite.com/?next=https://MySite.com./account",
+		"https://mysite.com/?next=https://api.partner.com/x",
+		"https://mysite.com/?img=//img.eu.cdn.net/a.png",
+		"https://mysite.com/?to=mailto:me@evil.com",
+		"https://mysite.com/?next=relative/path#top",
+	}
+	for _, input := range safe {
+		findings, err := urlkit.FindRedirects(input, trusted)
+		if err != nil || len(findings) != 0 {
+			t.Fatalf("Expected no findings for %s, got %v, %v", input, findings, err)
+		}
+	}
+
+	findings, _ := urlkit.FindRedirects("/login?next=/home", urlkit.TrustedHosts{""})
+	if len(findings) != 0 {
+		t.Fatalf("Expected no findings for a relative URL, got %v", findings)
+	}
+	findings, _ = urlkit.FindRedirects("/login?next=https://evil.com", urlkit.TrustedHosts{""})
+	if len(findings) != 1 {
+		t.Fatalf("Expected an empty trusted host not to trust evil.com, got %v", findings)
+	}
+
+	findings, _ = urlkit.FindRedirects("https://mysite.com/?a=https://partner.com.evil.com/&b=https://cdn.net/", trusted)
+	if len(findings) != 2 || findings[0].Key != "a" || findings[1].Key != "b" {
+		t.Fatalf("Expected 2 findings, got %v", findings)
+	}
+}
@@ -0,0 +1,107 @@
+package cmd_test
+
+import (
+	"testing"
+
+	"github.com/cmmorrow/url/urlkit"
+)
+
+const rfc3986Base = "http://a/b/c/d;p?q"
+
+// resolveTests are the examples from RFC 3986 section 5.4.
+var resolveTests = [][2]string{
+	// 5.4.1. Normal Examples
+	{"g:h", "g:h"},
+	{"g", "http://a/b/c/g"},
+	{"./g", "http://a/b/c/g"},
+	{"g/", "http://a/b/c/g/"},
+	{"/g", "http://a/g"},
+	{"//g", "http://g"},
+	{"?y", "http://a/b/c/d;p?y"},
+	{"g?y", "http://a/b/c/g?y"},
+	{"#s", "http://a/b/c/d;p?q#s"},
+	{"g#s", "http://a/b/c/g#s"},
+	{"g?y#s", "http://a/b/c/g?y#s"},
+	{";x", "http://a/b/c/;x"},
+	{"g;x", "http://a/b/c/g;x"},
+	{"g;x?y#s", "http://a/b/c/g;x?y#s"},
+	{"", "http://a/b/c/d;p?q"},
+	{".", "http://a/b/c/"},
+	{"./", "http://a/b/c/"},
+	{"..", "http://a/b/"},
+	{"../", "http://a/b/"},
+	{"../g", "http://a/b/g"},
+	{"../..", "http://a/"},
+	{"../../", "http://a/"},
+	{"../../g", "http://a/g"},
+	// 5.4.2. Abnormal Examples
+	{"../../../g", "http://a/g"},
+	{"../../../../g", "http://a/g"},
+	{"/./g", "http://a/g"},
+	{"/../g", "http://a/g"},
+	{"g.", "http://a/b/c/g."},
+	{".g", "http://a/b/c/.g"},
+	{"g..", "http://a/b/c/g.."},
+	{"..g", "http://a/b/c/..g"},
+	{"./../g", "http://a/b/g"},
+	{"./g/.", "http://a/b/c/g/"},
+	{"g/./h", "http://a/b/c/g/h"},
+	{"g/../h", "http://a/b/c/h"},
+	{"g;x=1/./y", "http://a/b/c/g;x=1/y"},
+	{"g;x=1/../y", "http://a/b/c/y"},
+	{"g?y/./x", "http://a/b/c/g?y/./x"},
+	{"g?y/../x", "http://a/b/c/g?y/../x"},
+	{"g#s/./x", "http://a/b/c/g#s/./x"},
+	{"g#s/../x", "http://a/b/c/g#s/../x"},
+	{"http:g", "http:g"},
+}
+
+func TestResolve(t *testing.T) {
+	for _, test := range resolveTests {
+		out, err := urlkit.Resolve(rfc3986Base, test[0])
+		if err != nil {
+			t.Fatal(err)
+		}
+		if out != test[1] {
+			t.Fatalf("Resolving '%s': expected '%s', got %s", test[0], test[1], out)
+		}
+	}
+}
+
+func TestResolveRelativeBase(t *testing.T) {
+	if _, err := urlkit.Resolve("/b/c", "g"); err == nil {
+		t.Fatal("Expected an error resolving against a relative base")
+	}
+}
+
+var relativizeTests = [][2]string{
+	{"http://a/b/c/g", "g"},
+	{"http://a/b/c/", "./"},
+	{"http://a/b/", "../"},
+	{"http://a/b/g", "../g"},
+	{"http://a/", "/"},
+	{"http://a/b/c/d;p?y", "?y"},
+	{"http://a/b/c/d;p?q#s", "#s"},
+	{"http://a/b/c/d;p?q", ""},
+	{"http://a/b/c/d;p", "d;p"},
+	{"http://a/b/c/g:h", "./g:h"},
+	{"http://g/x", "//g/x"},
+	{"https://a/b/c/g", "https://a/b/c/g"},
+	{"http://a/x/y/z?k=v", "/x/y/z?k=v"},
+}
+
+func TestRelativize(t *testing.T) {
+	for _, test := range relativizeTests {
+		out, err := urlkit.Relativize(rfc3986Base, test[0])
+		if err != nil {
+			t.Fatal(err)
+		}
+		if out != test[1] {
+			t.Fatalf("Relativizing '%s': expected '%s', got %s", test[0], test[1], out)
+		}
+		resolved, _ := urlkit.Resolve(rfc3986Base, out)
+		if resolved != test[0] {
+			t.Fatalf("'%s' resolves to %s, not %s", out, resolved, test[0])
+		}
+	}
+}
@@ -0,0 +1,216 @@
+/*
+Copyright © 2022 Chris Morrow cmmorrow@gmail.com
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program. If not, see <http://www.gnu.org/licenses/>.
+*/
+package cmd_test
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"reflect"
+	"testing"
+
+	"github.com/cmmorrow/url/urlkit"
+)
+
+// templateExamples are groups of the examples in RFC 6570, in the format of
+// the uritemplate-test suite.
+type templateExamples struct {
+	Level     int             `json:"level"`
+	Variables json.RawMessage `json:"variables"`
+	Testcases [][2]string     `json:"testcases"`
+}
+
+func TestTemplateExpandRFC6570(t *testing.T) {
+	data, err := os.ReadFile("testdata/rfc6570-examples.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var groups map[string]templateExamples
+	if err := json.Unmarshal(data, &groups); err != nil {
+		t.Fatal(err)
+	}
+	for name, group := range groups {
+		vars, err := urlkit.ParseTemplateVars(group.Variables)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, test := range group.Testcases {
+			tmpl, err := urlkit.ParseTemplate(test[0])
+			if err != nil {
+				t.Fatalf("%s: %v", name, err)
+			}
+			out, err := tmpl.Expand(vars)
+			if err != nil {
+				t.Fatalf("%s: %v", name, err)
+			}
+			if out != test[1] {
+				t.Fatalf("%s: expected '%s' for %s, got %s", name, test[1], test[0], out)
+			}
+		}
+	}
+}
+
+func TestTemplateExpand(t *testing.T) {
+	tmpl, err := urlkit.ParseTemplate("https://api.example.com/users/{id}/repos{?page,per_page}{#section}")
+	if err != nil {
+		t.Fatal(err)
+	}
+	out, err := tmpl.Expand(urlkit.TemplateVars{"id": "42", "page": "2", "section": "top list"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expected := "https://api.example.com/users/42/repos?page=2#top%20list"; out != expected {
+		t.Fatalf("Expected '%s', got %s", expected, out)
+	}
+	if vars := tmpl.Variables(); len(vars) != 4 || vars[0] != "id" || vars[3] != "section" {
+		t.Fatalf("Expected [id page per_page section], got %v", vars)
+	}
+
+	tmpl, _ = urlkit.ParseTemplate("/a b/{x}/é")
+	if out, _ := tmpl.Expand(urlkit.TemplateVars{"x": "€"}); out != "/a%20b/%E2%82%AC/%C3%A9" {
+		t.Fatalf("Expected '/a%%20b/%%E2%%82%%AC/%%C3%%A9', got %s", out)
+	}
+
+	tmpl, _ = urlkit.ParseTemplate("{list:2}")
+	if _, err := tmpl.Expand(urlkit.TemplateVars{"list": []string{"a"}}); urlkit.KindOf(err) != urlkit.KindInvalidInput {
+		t.Fatalf("Expected an invalid input error, got %v", err)
+	}
+}
+
+func templateErr(s string) error {
+	_, err := urlkit.ParseTemplate(s)
+	return err
+}
+
+var templateErrorTests = []errorTest{
+	{templateErr("/users/{id"), urlkit.KindInvalidInput, 7},
+	{templateErr("/users/id}"), urlkit.KindInvalidInput, 9},
+	{templateErr("/users/{}"), urlkit.KindInvalidInput, 8},
+	{templateErr("/users/{=id}"), urlkit.KindInvalidInput, 8},
+	{templateErr("/users/{id:0}"), urlkit.KindInvalidInput, 10},
+	{templateErr("/users/{id:10000}"), urlkit.KindInvalidInput, 10},
+	{templateErr("/users/{?a,b c}"), urlkit.KindInvalidInput, 11},
+	{templateErr("/users/{a..b}"), urlkit.KindInvalidInput, 8},
+}
+
+func TestParseTemplateErrors(t *testing.T) {
+	for i, test := range templateErrorTests {
+		var e *urlkit.Error
+		if !errors.As(test.err, &e) || e.Kind != test.kind || e.Offset != test.offset {
+			t.Fatalf("%d: expected %s at %d, got %v", i, test.kind, test.offset, test.err)
+		}
+	}
+}
+
+func TestParseTemplateVars(t *testing.T) {
+	vars, err := urlkit.ParseTemplateVars([]byte(`{"n":42,"b":true,"u":null,"l":["a",1],"k":{"z":"1","a":"2"}}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if vars["n"] != "42" || vars["b"] != "true" || vars["u"] != nil {
+		t.Fatalf("Unexpected variables %v", vars)
+	}
+	if k := vars["k"].(urlkit.Query); len(k) != 2 || k[0].Key != "z" {
+		t.Fatalf("Expected the keys of k in order, got %v", k)
+	}
+	for _, bad := range []string{`[1]`, `{"a":[[1]]}`, `{"a":{"b":{}}}`, `{"a":1} x`} {
+		if _, err := urlkit.ParseTemplateVars([]byte(bad)); urlkit.KindOf(err) != urlkit.KindJSON {
+			t.Fatalf("Expected a JSON error for %s, got %v", bad, err)
+		}
+	}
+}
+
+func TestTemplateMatchRFC6570(t *testing.T) {
+	data, err := os.ReadFile("testdata/rfc6570-examples.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var groups map[string]templateExamples
+	if err := json.Unmarshal(data, &groups); err != nil {
+		t.Fatal(err)
+	}
+	for name, group := range groups {
+		for _, test := range group.Testcases {
+			// The label operator cannot tell the dots of an associative
+			// array's values from the dots that separate them.
+			if test[0] == "X{.keys}" || test[0] == "X{.keys*}" {
+				continue
+			}
+			tmpl, err := urlkit.ParseTemplate(test[0])
+			if err != nil {
+				t.Fatal(err)
+			}
+			vars, ok := tmpl.Match(test[1])
+			if !ok {
+				t.Fatalf("%s: expected %s to match %s", name, test[1], test[0])
+			}
+			if out, err := tmpl.Expand(vars); out != test[1] {
+				t.Fatalf("%s: expected '%s' from the variables of %s, got %s (%v)", name, test[1], test[0], out, err)
+			}
+		}
+	}
+}
+
+type templateMatchTest struct {
+	template string
+	input    string
+	expected string
+}
+
+var templateMatchTests = []templateMatchTest{
+	{"/users/{id}/repos{?page,per_page}", "/users/42/repos?per_page=10&page=2", `{"id":"42","page":"2","per_page":"10"}`},
+	{"/users/{id}/repos{?page,per_page}", "https://api.example.com/users/42/repos", `{"id":"42"}`},
+	{"/search{?q,tag*}", "/search?q=a%20b&tag=x&tag=y", `{"q":"a b","tag":["x","y"]}`},
+	{"/search{?q,rest*}", "/search?q=go&sort=asc&n=1", `{"q":"go","rest":{"sort":"asc","n":"1"}}`},
+	{"/files{/path*}", "/files/a/b%2Fc/d", `{"path":["a","b/c","d"]}`},
+	{"/dictionary/{term:1}/{term}", "/dictionary/c/cat", `{"term":"cat"}`},
+	{"{+base}/{id}{#section}", "https://mysite.com/docs/7#top", `{"base":"https://mysite.com/docs","id":"7","section":"top"}`},
+	{"/map{;x,y}", "/map;x=1024;y=768", `{"x":"1024","y":"768"}`},
+}
+
+func TestTemplateMatch(t *testing.T) {
+	for _, test := range templateMatchTests {
+		tmpl, err := urlkit.ParseTemplate(test.template)
+		if err != nil {
+			t.Fatal(err)
+		}
+		vars, ok := tmpl.Match(test.input)
+		if !ok {
+			t.Fatalf("Expected %s to match %s", test.input, test.template)
+		}
+		b, _ := json.Marshal(vars)
+		var expected, got interface{}
+		json.Unmarshal([]byte(test.expected), &expected)
+		json.Unmarshal(b, &got)
+		if !reflect.DeepEqual(expected, got) {
+			t.Fatalf("Expected '%s', got %s", test.expected, b)
+		}
+	}
+
+	noMatch := []templateMatchTest{
+		{"/users/{id}/repos", "/users/42/gists", ""},
+		{"/users/{id}", "/users/4/2", ""},
+		{"/users/{id}{?page}", "/users/42?page=2&sort=asc", ""},
+		{"https://mysite.com/{id}", "https://other.com/42", ""},
+	}
+	for _, test := range noMatch {
+		tmpl, _ := urlkit.ParseTemplate(test.template)
+		if vars, ok := tmpl.Match(test.input); ok {
+			t.Fatalf("Expected %s not to match %s, got %v", test.input, test.template, vars)
+		}
+	}
+}
@@ -0,0 +1,93 @@
+package cmd_test
+
+import (
+	"testing"
+
+	"github.com/cmmorrow/url/urlkit"
+)
+
+type splitTest struct {
+	input    string
+	opts     urlkit.Options
+	expected string
+}
+
+var splitTests = []splitTest{
+	{"https://mysite.com:8000/my%20documents?file=my+file", urlkit.Options{},
+		`{"fragment":null,"host":"mysite.com","params":{"file":"my file"},"path":"/my documents","port":"8000","scheme":"https","uriPath":null,"user":null}`},
+	{"https://mysite.com:8000/my%20documents?file=my+file", urlkit.Options{NoDecode: true},
+		`{"fragment":null,"host":"mysite.com","params":{"file":"my+file"},"path":"/my%20documents","port":"8000","scheme":"https","uriPath":null,"user":null}`},
+	{"http://wanda:1234@你好.com/#top", urlkit.Options{Puny: true},
+		`{"fragment":"top","host":"xn--6qq79v.com","params":{},"path":"/","port":null,"scheme":"http","uriPath":null,"user":"wanda:1234"}`},
+	{"mailto:nobody@email.com?a=1&a=2", urlkit.Options{},
+		`{"fragment":null,"host":null,"params":{"a":["1","2"]},"path":null,"port":null,"scheme":"mailto","uriPath":"nobody@email.com","user":null}`},
+	{"http://mysite.com/?z=1&a=2&z=3&m", urlkit.Options{},
+		`{"fragment":null,"host":"mysite.com","params":{"z":["1","3"],"a":"2","m":""},"path":"/","port":null,"scheme":"http","uriPath":null,"user":null}`},
+	{"http://mysite.com/?z=1&a=2&z=3&m", urlkit.Options{SortParams: true},
+		`{"fragment":null,"host":"mysite.com","params":{"a":"2","m":"","z":["1","3"]},"path":"/","port":null,"scheme":"http","uriPath":null,"user":null}`},
+}
+
+func TestSplitJSON(t *testing.T) {
+	for _, test := range splitTests {
+		u, err := urlkit.Parse(test.input)
+		if err != nil {
+			t.Fatal(err)
+		}
+		c, err := urlkit.Split(u, test.opts)
+		if err != nil {
+			t.Fatal(err)
+		}
+		out, err := c.JSON()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(out) != test.expected {
+			t.Fatalf("Expected '%s', got %s", test.expected, out)
+		}
+	}
+}
+
+func TestParseError(t *testing.T) {
+	if _, err := urlkit.Parse("::bad"); err == nil {
+		t.Fatal("Expected an error parsing '::bad'")
+	}
+}
+
+func TestFromJSON(t *testing.T) {
+	uri, err := urlkit.FromJSON([]byte(`{"scheme":"http","host":"mysite.com","params":{"foo":"bar","bar":"baz"}}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := "http://mysite.com?foo=bar&bar=baz"
+	if out := uri.String(); out != expected {
+		t.Fatalf("Expected '%s', got %s", expected, out)
+	}
+	if _, err := urlkit.FromJSON([]byte(`{"scheme":`)); err == nil {
+		t.Fatal("Expected an error reading invalid JSON")
+	}
+}
+
+func TestEncodeDecode(t *testing.T) {
+	encoded := urlkit.Encode("this is my ^message))")
+	if encoded != "this%20is%20my%20%5Emessage%29%29" {
+		t.Fatalf("Unexpected encoding %s", encoded)
+	}
+	decoded, err := urlkit.Decode(encoded)
+	if err != nil || decoded != "this is my ^message))" {
+		t.Fatalf("Unexpected decoding %s, %v", decoded, err)
+	}
+	if _, err := urlkit.Decode("%zz"); err == nil {
+		t.Fatal("Expected an error decoding '%zz'")
+	}
+}
+
+func TestPunycode(t *testing.T) {
+	ascii, err := urlkit.ToASCII("你好")
+	if err != nil || ascii != "xn--6qq79v" {
+		t.Fatalf("Unexpected punycode %s, %v", ascii, err)
+	}
+	unicode, err := urlkit.ToUnicode(ascii)
+	if err != nil || unicode != "你好" {
+		t.Fatalf("Unexpected unicode %s, %v", unicode, err)
+	}
+}
@@ -0,0 +1,123 @@
+package cmd_test
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+
+	"github.com/cmmorrow/url/urlkit"
+)
+
+type urlPatternTest struct {
+	pattern  string
+	base     string
+	input    string
+	expected string
+}
+
+// urlPatternTests give the groups of the components that have any, or an
+// empty string if the input does not match.
+var urlPatternTests = []urlPatternTest{
+	{"https://*.example.com/books/:id(\\d+)", "", "https://api.example.com/books/123", `{"hostname":{"0":"api"},"pathname":{"id":"123"}}`},
+	{"https://*.example.com/books/:id(\\d+)", "", "https://api.example.com/books/abc", ""},
+	{"https://*.example.com/books/:id(\\d+)", "", "https://example.com/books/123", ""},
+	{"/books/:id?", "https://example.com", "https://example.com/books", `{"pathname":{"id":null}}`},
+	{"/books/:id?", "https://example.com", "https://example.org/books/1", ""},
+	{"https://example.com/:path+", "", "https://example.com/a/b/c", `{"pathname":{"path":"a/b/c"}}`},
+	{"https://example.com/files/:path*", "", "https://example.com/files", `{"pathname":{"path":null}}`},
+	{"https://example.com/:id", "", "https://example.com/a/b", ""},
+	{"https://example.com/files/*.:ext", "", "https://example.com/files/report.v2.pdf", `{"pathname":{"0":"report.v2","ext":"pdf"}}`},
+	{"https://example.com/{foo/}?bar", "", "https://example.com/bar", `{}`},
+	{"https://example.com/{foo/}?bar", "", "https://example.com/foo/bar", `{}`},
+	{"https://example.com/search?q=:query", "", "https://example.com/search?q=cats", `{"search":{"query":"cats"}}`},
+	{"https://example.com/search?q=:query", "", "https://example.com/search?q=cats&page=2", `{"search":{"query":"cats&page=2"}}`},
+	{"https://example.com/#/route/:id", "", "https://example.com/#/route/7", `{"hash":{"id":"7"}}`},
+	{"http{s}?://example.com/", "", "http://example.com", `{}`},
+	{"https://example.com:443/", "", "https://example.com/", `{}`},
+	{"https://bücher.example/", "", "https://xn--bcher-kva.example/", `{}`},
+	{"https://example.com/caf%C3%A9", "", "https://example.com/café", `{}`},
+	{"data\\:text/*", "", "data:text/plain,hello", `{"pathname":{"0":"plain,hello"}}`},
+}
+
+func TestURLPattern(t *testing.T) {
+	for _, test := range urlPatternTests {
+		p, err := urlkit.ParseURLPattern(test.pattern, urlkit.URLPatternOptions{BaseURL: test.base})
+		if err != nil {
+			t.Fatal(err)
+		}
+		result, err := p.Exec(test.input)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if result == nil {
+			if test.expected != "" {
+				t.Fatalf("Expected %s to match %s", test.input, test.pattern)
+			}
+			continue
+		}
+		if test.expected == "" {
+			t.Fatalf("Expected %s not to match %s", test.input, test.pattern)
+		}
+		components := map[string]urlkit.URLPatternComponentResult{
+			"hostname": result.Hostname,
+			"pathname": result.Pathname,
+			"search":   result.Search,
+			"hash":     result.Hash,
+		}
+		groups := map[string]map[string]*string{}
+		for name, c := range components {
+			// Components that the pattern does not give are * and
+			// have an unnamed group.
+			if p.Component(name) != "*" && len(c.Groups) > 0 {
+				groups[name] = c.Groups
+			}
+		}
+		b, _ := json.Marshal(groups)
+		var expected, got interface{}
+		json.Unmarshal([]byte(test.expected), &expected)
+		json.Unmarshal(b, &got)
+		if !reflect.DeepEqual(expected, got) {
+			t.Fatalf("Expected '%s', got %s", test.expected, b)
+		}
+	}
+}
+
+func TestURLPatternComponents(t *testing.T) {
+	p, err := urlkit.ParseURLPattern("https://example.com/books/:id", urlkit.URLPatternOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []string{"https", "*", "*", "example.com", "", "/books/:id", "*", "*"}
+	for i, name := range urlkit.PatternComponents {
+		if got := p.Component(name); got != expected[i] {
+			t.Fatalf("Expected '%s' for the %s, got %s", expected[i], name, got)
+		}
+	}
+}
+
+func TestURLPatternIgnoreCase(t *testing.T) {
+	p, err := urlkit.ParseURLPattern("https://example.com/Books/:id", urlkit.URLPatternOptions{IgnoreCase: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ok, _ := p.Test("https://example.com/books/1"); !ok {
+		t.Fatalf("Expected a match without regard to case")
+	}
+}
+
+func TestURLPatternErrors(t *testing.T) {
+	for _, pattern := range []string{
+		"/books/:id",
+		"https://example.com/:id(",
+		"https://example.com/(a(b))",
+		"https://example.com/(?=a)",
+		"https://example.com/:a/:a",
+		"https://example.com/{a",
+		"https://[::1]/",
+		"https://example.com:8x/",
+	} {
+		if _, err := urlkit.ParseURLPattern(pattern, urlkit.URLPatternOptions{}); urlkit.KindOf(err) != urlkit.KindInvalidInput {
+			t.Fatalf("Expected an invalid input error for '%s', got %v", pattern, err)
+		}
+	}
+}
@@ -0,0 +1,190 @@
+/*
+Copyright © 2022 Chris Morrow cmmorrow@gmail.com
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program. If not, see <http://www.gnu.org/licenses/>.
+*/
+package urlkit
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"golang.org/x/text/encoding"
+	"golang.org/x/text/encoding/htmlindex"
+)
+
+// detectCharsets are the charsets DetectCharset chooses between, in order
+// of preference when they fit equally well. Hangul in EUC-KR uses lead bytes
+// that are frequent in GBK and EUC-JP too, so EUC-KR is preferred; Chinese
+// and Japanese text usually has other lead bytes as well.
+var detectCharsets = []string{"shift_jis", "euc-kr", "gbk", "euc-jp", "big5", "windows-1252"}
+
+// LookupCharset returns the encoding with the WHATWG Encoding Standard name
+// or label, such as shift_jis, euc-kr, gbk or iso-8859-1.
+func LookupCharset(name string) (encoding.Encoding, error) {
+	enc, err := htmlindex.Get(name)
+	if err != nil {
+		return nil, &Error{Kind: KindInvalidInput, Op: "charset", Input: name, Offset: -1, Err: fmt.Errorf("unknown charset")}
+	}
+	return enc, nil
+}
+
+// ToCharset converts the UTF-8 string s to charset. The offset in the error
+// is of the first character that charset cannot represent.
+func ToCharset(s string, charset string) (string, error) {
+	enc, err := LookupCharset(charset)
+	if err != nil {
+		return "", err
+	}
+	encoder := enc.NewEncoder()
+	out, err := encoder.String(s)
+	if err != nil {
+		offset := -1
+		for i, r := range s {
+			if _, rerr := enc.NewEncoder().String(string(r)); rerr != nil {
+				offset = i
+				break
+			}
+		}
+		return "", &Error{Kind: KindInvalidInput, Op: "charset", Input: s, Offset: offset,
+			Err: fmt.Errorf("cannot be represented in %s", charset)}
+	}
+	return out, nil
+}
+
+// FromCharset converts the bytes in s from charset to UTF-8. Bytes that are
+// not valid in charset become U+FFFD.
+func FromCharset(s string, charset string) (string, error) {
+	enc, err := LookupCharset(charset)
+	if err != nil {
+		return "", err
+	}
+	out, err := enc.NewDecoder().String(s)
+	if err != nil {
+		return "", &Error{Kind: KindInvalidInput, Op: "charset", Input: s, Offset: -1, Err: err}
+	}
+	return out, nil
+}
+
+// DetectCharset guesses the charset of s. It returns utf-8 if s is valid
+// UTF-8, otherwise the legacy charset that decodes s without errors into the
+// most characters of the scripts it is used for.
+func DetectCharset(s string) string {
+	if utf8.ValidString(s) {
+		return "utf-8"
+	}
+	best, bestScore := "", -1.0
+	for _, charset := range detectCharsets {
+		decoded, err := FromCharset(s, charset)
+		if err != nil || strings.ContainsRune(decoded, utf8.RuneError) {
+			continue
+		}
+		score := charsetScore(charset, decoded)
+		if score > bestScore {
+			best, bestScore = charset, score
+		}
+	}
+	if best == "" {
+		return "windows-1252"
+	}
+	return best
+}
+
+// frequentLeadBytes are the ranges of lead bytes that the most frequently
+// used characters of each double byte charset are encoded with: kana and
+// level 1 kanji in the Japanese charsets, Hangul in EUC-KR, level 1 hanzi in
+// GBK and frequent hanzi in Big5.
+var frequentLeadBytes = map[string][][2]byte{
+	"shift_jis": {{0x82, 0x83}, {0x88, 0x98}},
+	"euc-jp":    {{0xA4, 0xA5}, {0xB0, 0xCF}},
+	"euc-kr":    {{0xB0, 0xC8}},
+	"gbk":       {{0xB0, 0xD7}},
+	"big5":      {{0xA4, 0xC6}},
+}
+
+// charsetScore rates how likely decoded is to be text written in charset,
+// from the share of its non-ASCII characters that are frequently used.
+func charsetScore(charset string, decoded string) float64 {
+	enc, _ := LookupCharset(charset)
+	runes := []rune(decoded)
+	var score float64
+	n := 0
+	for i, r := range runes {
+		if r < utf8.RuneSelf {
+			continue
+		}
+		n++
+		if unicode.IsControl(r) || unicode.Is(unicode.Co, r) {
+			score -= 2
+			continue
+		}
+		if charset == "windows-1252" {
+			// Accented letters are usually next to ASCII letters, while
+			// double byte characters decode to runs of them.
+			if unicode.IsLetter(r) && (isASCIILetter(runes, i-1) || isASCIILetter(runes, i+1)) {
+				score++
+			} else {
+				score += 0.3
+			}
+			continue
+		}
+		b, err := enc.NewEncoder().String(string(r))
+		if err != nil || len(b) < 2 {
+			score += 0.3
+			continue
+		}
+		weight := 0.3
+		if unicode.IsPunct(r) || unicode.IsSymbol(r) {
+			weight = 0.5
+		}
+		for _, lead := range frequentLeadBytes[charset] {
+			if b[0] >= lead[0] && b[0] <= lead[1] {
+				weight = 1
+				break
+			}
+		}
+		if unicode.In(r, unicode.Hiragana, unicode.Katakana) && weight == 1 {
+			// Kana are a stronger sign of Japanese than kanji are.
+			weight = 1.2
+		}
+		score += weight
+	}
+	if n == 0 {
+		return 0
+	}
+	return score / float64(n)
+}
+
+// convertCharset converts s from charset to UTF-8, or from the charset
+// DetectCharset finds if detect is set. An empty charset is UTF-8.
+func convertCharset(s string, charset string, detect bool) (string, error) {
+	if detect {
+		charset = DetectCharset(s)
+	}
+	if charset == "" || charset == "utf-8" {
+		return s, nil
+	}
+	return FromCharset(s, charset)
+}
+
+// isASCIILetter returns true if runes[i] is an ASCII letter.
+func isASCIILetter(runes []rune, i int) bool {
+	if i < 0 || i >= len(runes) {
+		return false
+	}
+	r := runes[i]
+	return r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z'
+}
@@ -0,0 +1,179 @@
+/*
+Copyright © 2022 Chris Morrow cmmorrow@gmail.com
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program. If not, see <http://www.gnu.org/licenses/>.
+*/
+package urlkit
+
+import (
+	"bufio"
+	_ "embed"
+	"fmt"
+	"io"
+	"path"
+	"strings"
+)
+
+//go:embed data/tracking.txt
+var defaultRules string
+
+// Rule removes the query parameters with a key that matches Param from URLs
+// with a host that matches Host. Both are shell-style patterns. An empty Host
+// matches every URL.
+type Rule struct {
+	Host  string
+	Param string
+}
+
+// Rules is a list of tracking parameter rules.
+type Rules []Rule
+
+// DefaultRules returns the built-in rules for common tracking parameters.
+func DefaultRules() Rules {
+	rules, err := ParseRules(strings.NewReader(defaultRules))
+	if err != nil {
+		panic(err)
+	}
+	return rules
+}
+
+// ParseRules reads rules with one rule per line. A line is a parameter
+// pattern, optionally preceded by a host pattern and a space. Blank lines
+// and lines starting with # are ignored.
+func ParseRules(r io.Reader) (Rules, error) {
+	var rules Rules
+	scanner := bufio.NewScanner(r)
+	line := 0
+	for scanner.Scan() {
+		line++
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+		var rule Rule
+		fields := strings.Fields(text)
+		switch len(fields) {
+		case 1:
+			rule.Param = fields[0]
+		case 2:
+			rule.Host, rule.Param = strings.ToLower(fields[0]), fields[1]
+		default:
+			return nil, &Error{Kind: KindInvalidInput, Op: "rules", Input: text, Offset: -1, Err: fmt.Errorf("line %d: expected [host] param", line)}
+		}
+		rule.Param = strings.ToLower(rule.Param)
+		for _, pattern := range []string{rule.Host, rule.Param} {
+			if _, err := path.Match(pattern, ""); err != nil {
+				return nil, &Error{Kind: KindInvalidInput, Op: "rules", Input: text, Offset: -1, Err: fmt.Errorf("line %d: %w", line, err)}
+			}
+		}
+		rules = append(rules, rule)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, &Error{Kind: KindIO, Op: "rules", Offset: -1, Err: err}
+	}
+	return rules, nil
+}
+
+// Match reports whether a parameter with key should be removed from a URL
+// with host.
+func (rules Rules) Match(host string, key string) bool {
+	host = strings.ToLower(host)
+	key = strings.ToLower(key)
+	for _, rule := range rules {
+		if ok, _ := path.Match(rule.Param, key); ok && matchHost(rule.Host, host) {
+			return true
+		}
+	}
+	return false
+}
+
+// matchHost reports whether host or one of its parent domains matches pattern.
+func matchHost(pattern string, host string) bool {
+	if pattern == "" {
+		return true
+	}
+	for {
+		if ok, _ := path.Match(pattern, host); ok {
+			return true
+		}
+		i := strings.IndexByte(host, '.')
+		if i < 0 {
+			return false
+		}
+		host = host[i+1:]
+	}
+}
+
+// Clean removes the query parameters matched by rules from rawURL. The rest
+// of the URL is left exactly as written. The removed parameters are returned.
+func Clean(rawURL string, rules Rules) (string, Query, error) {
+	u, err := Parse(rawURL)
+	if err != nil {
+		return "", nil, err
+	}
+	host := u.Hostname()
+	var removed Query
+	out, err := EditQuery(rawURL, func(q RawQuery) (RawQuery, error) {
+		removed = q.Get(func(key string) bool { return rules.Match(host, key) })
+		return q.Delete(func(key string) bool { return rules.Match(host, key) }), nil
+	})
+	return out, removed, err
+}
+
+// Clean removes the query parameters matched by rules from the Query,
+// RawParams and Params of the URI. The removed parameters are returned.
+func (u *URI) Clean(rules Rules) Query {
+	host := u.Host
+	if i := strings.LastIndexByte(host, ':'); i >= 0 && !strings.HasSuffix(host, "]") {
+		host = host[:i]
+	}
+	match := func(key string) bool { return rules.Match(host, key) }
+
+	var removed Query
+	if u.Query != "" {
+		q := SplitRawQuery(u.Query)
+		removed = append(removed, q.Get(match)...)
+		u.Query = q.Delete(match).String()
+	}
+	var raw []string
+	for _, p := range u.RawParams {
+		key, value := splitPair(p)
+		if match(key) {
+			removed = append(removed, Param{Key: key, Value: value})
+		} else {
+			raw = append(raw, p)
+		}
+	}
+	if u.RawParams != nil {
+		u.RawParams = raw
+	}
+	if u.Params != nil {
+		q := u.BuildQuery()
+		var order []string
+		for _, key := range q.Keys() {
+			if match(key) {
+				for _, v := range q.Values(key) {
+					removed = append(removed, Param{Key: key, Value: v})
+				}
+				delete(u.Params, key)
+			} else {
+				order = append(order, key)
+			}
+		}
+		if u.paramOrder != nil {
+			u.paramOrder = order
+		}
+	}
+	return removed
+}
@@ -0,0 +1,245 @@
+/*
+Copyright © 2022 Chris Morrow cmmorrow@gmail.com
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program. If not, see <http://www.gnu.org/licenses/>.
+*/
+package urlkit
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"strings"
+)
+
+// Labels used for the components of a URL in text and JSON output.
+const (
+	SchemeLabel   = "scheme"
+	OpaqueLabel   = "uri-path"
+	UserLabel     = "user"
+	HostLabel     = "host"
+	PortLabel     = "port"
+	PathLabel     = "path"
+	FragmentLabel = "fragment"
+	ParamsLabel   = "params"
+	ParamLabel    = "param"
+	DomainLabel   = "domain"
+	IPLabel       = "ip"
+
+	TLDLabel               = "tld"
+	RegistrableDomainLabel = "registrable-domain"
+	SubdomainLabel         = "subdomain"
+	LabelsLabel            = "labels"
+	IPClassLabel           = "ip-class"
+	IPVersionLabel         = "ip-version"
+	ZoneLabel              = "zone"
+)
+
+// Options control how the components of a parsed URL are extracted.
+type Options struct {
+	// Puny converts the host to punycode (IDNA).
+	Puny bool
+	// IDNA are the UTS #46 options Puny converts the host with.
+	IDNA IDNAOptions
+	// NoDecode leaves the path, fragment and query parameters percent encoded.
+	NoDecode bool
+	// SortParams sorts the query parameters by key instead of keeping the
+	// order they appear in the URL.
+	SortParams bool
+	// Charset is the charset of the percent encoded bytes in the path,
+	// fragment and query parameters, which are decoded to UTF-8. An empty
+	// Charset is UTF-8.
+	Charset string
+	// DetectCharset guesses the charset of each decoded component with
+	// DetectCharset instead of using Charset.
+	DetectCharset bool
+	// Suffixes splits the host into its public suffix, registrable domain
+	// and subdomain if it is set.
+	Suffixes *SuffixList
+}
+
+// Components are the primary components of a parsed URL.
+type Components struct {
+	Scheme   string
+	UriPath  string
+	User     string
+	Host     string
+	Port     string
+	Path     string
+	Fragment string
+	Params   Query
+	// Domain are the parts of the host found with Options.Suffixes.
+	Domain *DomainParts
+	// IP is the address of a host that is an IP address.
+	IP *IPHost
+}
+
+// Parse parses a URL string. The error is an *Error of KindInvalidInput.
+func Parse(rawURL string) (*url.URL, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, parseError(rawURL, err)
+	}
+	return u, nil
+}
+
+// Parsers accepted by ParseWith.
+const (
+	// ParserGo is url.Parse, which most Go programs use.
+	ParserGo = "go"
+	// ParserRFC3986 is ParseRFC3986, which rejects anything that the
+	// grammar of RFC 3986 does not allow.
+	ParserRFC3986 = "rfc3986"
+	// ParserWHATWG is ParseWHATWG, which parses URLs as browsers do.
+	ParserWHATWG = "whatwg"
+)
+
+// ParserNames lists the parsers accepted by ParseWith.
+var ParserNames = []string{ParserGo, ParserRFC3986, ParserWHATWG}
+
+// LookupParser returns the parse function of a parser in ParserNames.
+func LookupParser(parser string) (func(string) (*url.URL, error), error) {
+	switch parser {
+	case ParserGo, "":
+		return Parse, nil
+	case ParserRFC3986:
+		return ParseRFC3986, nil
+	case ParserWHATWG:
+		return func(rawURL string) (*url.URL, error) {
+			u, err := ParseWHATWG(rawURL, nil)
+			if err != nil {
+				return nil, err
+			}
+			return u.URL(), nil
+		}, nil
+	}
+	return nil, &Error{Kind: KindInvalidInput, Op: "parser", Input: parser, Offset: -1,
+		Err: fmt.Errorf("unknown parser, expected one of %s", strings.Join(ParserNames, ", "))}
+}
+
+// ParseWith parses a URL string with one of ParserNames.
+func ParseWith(parser string, rawURL string) (*url.URL, error) {
+	parse, err := LookupParser(parser)
+	if err != nil {
+		return nil, err
+	}
+	return parse(rawURL)
+}
+
+// Split extracts the components of a parsed URL.
+func Split(u *url.URL, opts Options) (Components, error) {
+	host, err := Hostname(u, opts.Puny, opts.IDNA)
+	if err != nil {
+		return Components{}, err
+	}
+	c := Components{
+		Scheme:  u.Scheme,
+		UriPath: u.Opaque,
+		User:    u.User.String(),
+		Host:    host,
+		Port:    u.Port(),
+	}
+	if opts.NoDecode {
+		c.Path = u.EscapedPath()
+		c.Fragment = u.EscapedFragment()
+	} else {
+		if c.Path, err = convertCharset(u.Path, opts.Charset, opts.DetectCharset); err != nil {
+			return Components{}, err
+		}
+		if c.Fragment, err = convertCharset(u.Fragment, opts.Charset, opts.DetectCharset); err != nil {
+			return Components{}, err
+		}
+	}
+
+	// Like url.URL.Query, parameters that cannot be decoded are dropped.
+	c.Params, _ = ParseQuery(u.RawQuery)
+	for i, p := range c.Params {
+		if opts.NoDecode {
+			c.Params[i] = Param{Key: url.QueryEscape(p.Key), Value: url.QueryEscape(p.Value)}
+			continue
+		}
+		if c.Params[i].Key, err = convertCharset(p.Key, opts.Charset, opts.DetectCharset); err != nil {
+			return Components{}, err
+		}
+		if c.Params[i].Value, err = convertCharset(p.Value, opts.Charset, opts.DetectCharset); err != nil {
+			return Components{}, err
+		}
+	}
+	if opts.SortParams {
+		c.Params = c.Params.Sorted()
+	}
+	// A host that ends in a number but is not a valid IPv4 address is
+	// neither an IP address nor a domain, but is not an error as url.Parse
+	// accepts it.
+	var ipErr error
+	c.IP, ipErr = ParseIPHost(u.Hostname())
+	if opts.Suffixes != nil && c.IP == nil && ipErr == nil {
+		c.Domain = opts.Suffixes.Split(c.Host)
+	}
+	return c, nil
+}
+
+// Hostname returns the host of u without the port, optionally converted to
+// punycode with the IDNA options.
+func Hostname(u *url.URL, puny bool, idna IDNAOptions) (string, error) {
+	if puny {
+		return idna.ToASCII(u.Hostname())
+	}
+	return u.Hostname(), nil
+}
+
+// Map returns the components as a map keyed by their JSON names. Empty
+// components are nil. The params are a Query, which is written to JSON as an
+// object in order with single values not in a list. The domain parts are
+// only included if the host was split with a SuffixList, and the IP address
+// only if the host is one.
+func (c Components) Map() map[string]interface{} {
+	jsonData := make(map[string]interface{})
+
+	jsonData[SchemeLabel] = c.Scheme
+	jsonData["uriPath"] = c.UriPath
+	jsonData[UserLabel] = c.User
+	jsonData[HostLabel] = c.Host
+	jsonData[PortLabel] = c.Port
+	jsonData[PathLabel] = c.Path
+	jsonData[FragmentLabel] = c.Fragment
+
+	for label := range jsonData {
+		if jsonData[label] == "" {
+			jsonData[label] = nil
+		}
+	}
+
+	params := c.Params
+	if params == nil {
+		params = Query{}
+	}
+	jsonData[ParamsLabel] = params
+	if c.Domain != nil {
+		jsonData[DomainLabel] = c.Domain
+	}
+	if c.IP != nil {
+		jsonData[IPLabel] = c.IP
+	}
+	return jsonData
+}
+
+// JSON returns the components as a JSON object.
+func (c Components) JSON() ([]byte, error) {
+	b, err := json.Marshal(c.Map())
+	if err != nil {
+		return nil, &Error{Kind: KindJSON, Op: "marshal", Offset: -1, Err: err}
+	}
+	return b, nil
+}
@@ -0,0 +1,120 @@
+/*
+Copyright © 2022 Chris Morrow cmmorrow@gmail.com
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program. If not, see <http://www.gnu.org/licenses/>.
+*/
+package urlkit
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+)
+
+// DecodeOptions control how DecodeComponent decodes a string.
+type DecodeOptions struct {
+	// Component is the URL component the string is from. A + is only
+	// decoded to a space for ComponentForm, or if Component is empty.
+	Component string
+	// Strict fails on characters that RFC 3986 does not allow in the
+	// component unless they are percent encoded. The characters allowed in
+	// a query are checked if Component is empty.
+	Strict bool
+	// Lenient leaves malformed percent encodings as they are instead of
+	// failing.
+	Lenient bool
+	// Charset is the charset of the decoded bytes, which are converted to
+	// UTF-8. An empty Charset is UTF-8.
+	Charset string
+	// DetectCharset guesses the charset of the decoded bytes with
+	// DetectCharset instead of using Charset.
+	DetectCharset bool
+}
+
+// InvalidEscape is a % that is not followed by two hex digits.
+type InvalidEscape struct {
+	Offset int    `json:"offset"`
+	Escape string `json:"escape"`
+}
+
+// InvalidEscapes returns every malformed percent encoding in s.
+func InvalidEscapes(s string) []InvalidEscape {
+	var invalid []InvalidEscape
+	for i := 0; i < len(s); i++ {
+		if s[i] != '%' {
+			continue
+		}
+		if i+2 < len(s) && isHex(s[i+1]) && isHex(s[i+2]) {
+			i += 2
+			continue
+		}
+		end := i + 3
+		if end > len(s) {
+			end = len(s)
+		}
+		invalid = append(invalid, InvalidEscape{Offset: i, Escape: s[i:end]})
+	}
+	return invalid
+}
+
+// DecodeComponent decodes a percent encoded string from a URL component.
+func DecodeComponent(s string, opts DecodeOptions) (string, error) {
+	plusAsSpace := opts.Component == "" || opts.Component == ComponentForm
+	if opts.Strict {
+		if err := checkStrict(s, opts.Component); err != nil {
+			return "", err
+		}
+	}
+	if invalid := InvalidEscapes(s); len(invalid) > 0 && !opts.Lenient {
+		return "", &Error{Kind: KindInvalidInput, Op: "decode", Input: s, Offset: invalid[0].Offset,
+			Err: url.EscapeError(invalid[0].Escape)}
+	}
+
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		switch {
+		case s[i] == '%' && i+2 < len(s) && isHex(s[i+1]) && isHex(s[i+2]):
+			b.WriteByte(unhex(s[i+1])<<4 | unhex(s[i+2]))
+			i += 2
+		case s[i] == '+' && plusAsSpace:
+			b.WriteByte(' ')
+		default:
+			b.WriteByte(s[i])
+		}
+	}
+	return convertCharset(b.String(), opts.Charset, opts.DetectCharset)
+}
+
+// checkStrict returns an error for the first character in s that is not
+// allowed unencoded in component by RFC 3986.
+func checkStrict(s string, component string) error {
+	if component == "" {
+		component = ComponentQuery
+	}
+	set, err := ComponentSet(component, StandardRFC3986)
+	if err != nil {
+		return err
+	}
+	set = set.With("%")
+	if set.SpaceAsPlus {
+		set = set.With("+")
+	}
+	for i := 0; i < len(s); i++ {
+		if !set.Allows(s[i]) {
+			return &Error{Kind: KindInvalidInput, Op: "decode", Input: s, Offset: i,
+				Err: fmt.Errorf("character %q is not allowed in %s", s[i], component)}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,133 @@
+/*
+Copyright © 2022 Chris Morrow cmmorrow@gmail.com
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program. If not, see <http://www.gnu.org/licenses/>.
+*/
+package urlkit
+
+// Kinds of Change.
+const (
+	Added     = "added"
+	Removed   = "removed"
+	Changed   = "changed"
+	Reordered = "reordered"
+)
+
+// Change is a difference in one component or query parameter between two URLs.
+type Change struct {
+	// Component is the label of the component, or ParamLabel for a query
+	// parameter.
+	Component string `json:"component"`
+	// Key is the key of the query parameter.
+	Key  string `json:"key,omitempty"`
+	Kind string `json:"change"`
+	Old  string `json:"old,omitempty"`
+	New  string `json:"new,omitempty"`
+}
+
+// Diff compares the components of two URLs and returns the changes from a
+// to b. Query parameters are compared by key. Values are matched so that a
+// repeated parameter only reports the values that were added or removed.
+func Diff(a Components, b Components) []Change {
+	var changes []Change
+	fields := []struct {
+		label string
+		a, b  string
+	}{
+		{SchemeLabel, a.Scheme, b.Scheme},
+		{OpaqueLabel, a.UriPath, b.UriPath},
+		{UserLabel, a.User, b.User},
+		{HostLabel, a.Host, b.Host},
+		{PortLabel, a.Port, b.Port},
+		{PathLabel, a.Path, b.Path},
+		{FragmentLabel, a.Fragment, b.Fragment},
+	}
+	for _, f := range fields {
+		switch {
+		case f.a == f.b:
+		case f.a == "":
+			changes = append(changes, Change{Component: f.label, Kind: Added, New: f.b})
+		case f.b == "":
+			changes = append(changes, Change{Component: f.label, Kind: Removed, Old: f.a})
+		default:
+			changes = append(changes, Change{Component: f.label, Kind: Changed, Old: f.a, New: f.b})
+		}
+	}
+
+	keys := a.Params.Keys()
+	for _, key := range b.Params.Keys() {
+		if len(a.Params.Values(key)) == 0 {
+			keys = append(keys, key)
+		}
+	}
+	for _, key := range keys {
+		changes = append(changes, diffValues(key, a.Params.Values(key), b.Params.Values(key))...)
+	}
+	return changes
+}
+
+// diffValues compares the values of a query parameter.
+func diffValues(key string, a []string, b []string) []Change {
+	if equalStrings(a, b) {
+		return nil
+	}
+	// Match up the values that appear in both so only the differences remain.
+	remaining := make(map[string]int)
+	for _, v := range b {
+		remaining[v]++
+	}
+	var removed []string
+	for _, v := range a {
+		if remaining[v] > 0 {
+			remaining[v]--
+		} else {
+			removed = append(removed, v)
+		}
+	}
+	var added []string
+	for _, v := range b {
+		if remaining[v] > 0 {
+			remaining[v]--
+			added = append(added, v)
+		}
+	}
+
+	if len(removed) == 0 && len(added) == 0 {
+		return []Change{{Component: ParamLabel, Key: key, Kind: Reordered}}
+	}
+	var changes []Change
+	for len(removed) > 0 && len(added) > 0 {
+		changes = append(changes, Change{Component: ParamLabel, Key: key, Kind: Changed, Old: removed[0], New: added[0]})
+		removed, added = removed[1:], added[1:]
+	}
+	for _, v := range removed {
+		changes = append(changes, Change{Component: ParamLabel, Key: key, Kind: Removed, Old: v})
+	}
+	for _, v := range added {
+		changes = append(changes, Change{Component: ParamLabel, Key: key, Kind: Added, New: v})
+	}
+	return changes
+}
+
+func equalStrings(a []string, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,193 @@
+/*
+Copyright © 2022 Chris Morrow cmmorrow@gmail.com
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program. If not, see <http://www.gnu.org/licenses/>.
+*/
+package urlkit
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+// CheckParserDifferential is the check for URLs that parsers read
+// differently.
+const CheckParserDifferential = "parser-differential"
+
+// ParsedComponents are the components of a URL as a parser read them,
+// percent encoded.
+type ParsedComponents struct {
+	Scheme   string `json:"scheme"`
+	User     string `json:"user"`
+	Host     string `json:"host"`
+	Port     string `json:"port"`
+	Path     string `json:"path"`
+	Query    string `json:"query"`
+	Fragment string `json:"fragment"`
+}
+
+// ParserResult is the result of parsing a URL with one of ParserNames.
+// Components is nil if the parser rejects the URL.
+type ParserResult struct {
+	Parser     string            `json:"parser"`
+	Components *ParsedComponents `json:"components,omitempty"`
+	Error      string            `json:"error,omitempty"`
+}
+
+// CompareParsers parses rawURL with each of ParserNames.
+func CompareParsers(rawURL string) []ParserResult {
+	var results []ParserResult
+	for _, parser := range ParserNames {
+		result := ParserResult{Parser: parser}
+		u, err := ParseWith(parser, rawURL)
+		if err != nil {
+			var e *Error
+			if errors.As(err, &e) {
+				err = e.Err
+			}
+			result.Error = err.Error()
+		} else {
+			result.Components = parsedComponents(u)
+		}
+		results = append(results, result)
+	}
+	return results
+}
+
+func parsedComponents(u *url.URL) *ParsedComponents {
+	c := &ParsedComponents{
+		Scheme:   u.Scheme,
+		User:     u.User.String(),
+		Host:     u.Hostname(),
+		Port:     u.Port(),
+		Path:     u.EscapedPath(),
+		Query:    u.RawQuery,
+		Fragment: u.EscapedFragment(),
+	}
+	if u.Opaque != "" {
+		c.Path = u.Opaque
+	}
+	return c
+}
+
+// FindParserDifferentials reports the components of a URL that the parsers
+// of results read differently, and whether some of them reject it.
+// Differences that do not change what the URL refers to are ignored, such as
+// the case of the host, a default port or a percent encoded letter.
+func FindParserDifferentials(results []ParserResult) []Finding {
+	var accepted, rejected []string
+	var errs []string
+	for _, r := range results {
+		if r.Components == nil {
+			rejected = append(rejected, r.Parser)
+			errs = append(errs, fmt.Sprintf("%s (%s)", r.Parser, r.Error))
+		} else {
+			accepted = append(accepted, r.Parser)
+		}
+	}
+	if len(accepted) == 0 {
+		return nil
+	}
+	var findings []Finding
+	if len(rejected) > 0 {
+		findings = append(findings, Finding{Check: CheckParserDifferential,
+			Message: fmt.Sprintf("rejected by %s, accepted by %s", joinAnd(errs), joinAnd(accepted))})
+	}
+
+	components := []struct {
+		label string
+		value func(c *ParsedComponents) string
+		key   func(c *ParsedComponents) string
+	}{
+		{SchemeLabel, func(c *ParsedComponents) string { return c.Scheme }, func(c *ParsedComponents) string {
+			return strings.ToLower(c.Scheme)
+		}},
+		{UserLabel, func(c *ParsedComponents) string { return c.User }, func(c *ParsedComponents) string {
+			return comparableEscapes(c.User)
+		}},
+		{HostLabel, func(c *ParsedComponents) string { return c.Host }, comparableHost},
+		{PortLabel, func(c *ParsedComponents) string { return c.Port }, comparablePort},
+		{PathLabel, func(c *ParsedComponents) string { return c.Path }, func(c *ParsedComponents) string {
+			if c.Path == "" && (c.Host != "" || isSpecialScheme(strings.ToLower(c.Scheme))) {
+				return "/"
+			}
+			return comparableEscapes(c.Path)
+		}},
+		{ComponentQuery, func(c *ParsedComponents) string { return c.Query }, func(c *ParsedComponents) string {
+			return comparableEscapes(c.Query)
+		}},
+		{FragmentLabel, func(c *ParsedComponents) string { return c.Fragment }, func(c *ParsedComponents) string {
+			return comparableEscapes(c.Fragment)
+		}},
+	}
+	for _, component := range components {
+		// Parsers that read the component the same way are grouped
+		// together, in the order of results.
+		var keys []string
+		groups := map[string][]string{}
+		values := map[string]string{}
+		for _, r := range results {
+			if r.Components == nil {
+				continue
+			}
+			key := component.key(r.Components)
+			if _, ok := groups[key]; !ok {
+				keys = append(keys, key)
+				values[key] = component.value(r.Components)
+			}
+			groups[key] = append(groups[key], r.Parser)
+		}
+		if len(keys) < 2 {
+			continue
+		}
+		var parts []string
+		for _, key := range keys {
+			parts = append(parts, fmt.Sprintf("%s read %q", joinAnd(groups[key]), values[key]))
+		}
+		findings = append(findings, Finding{Check: CheckParserDifferential, Component: component.label,
+			Message: strings.Join(parts, ", ")})
+	}
+	return findings
+}
+
+// comparableEscapes returns s with percent encodings in a normal form and
+// the characters that some parsers leave as they are encoded.
+func comparableEscapes(s string) string {
+	s = EncodeComponent(s, printableExcept(" \"<>\\^`{|}").With("%"))
+	return normalizeEscapes(s, NormalizeOptions{UppercaseEscapes: true, DecodeUnreserved: true})
+}
+
+// comparableHost returns the host in lowercase and converted to ASCII.
+func comparableHost(c *ParsedComponents) string {
+	host := strings.ToLower(c.Host)
+	if ascii, err := whatwgIDNA.ToASCII(host); err == nil {
+		host = ascii
+	}
+	return host
+}
+
+// comparablePort returns the port as a number, or the default port of the
+// scheme if there is none.
+func comparablePort(c *ParsedComponents) string {
+	if c.Port == "" {
+		return DefaultPorts[strings.ToLower(c.Scheme)]
+	}
+	if n, err := strconv.Atoi(c.Port); err == nil {
+		return strconv.Itoa(n)
+	}
+	return c.Port
+}
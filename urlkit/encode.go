@@ -0,0 +1,47 @@
+/*
+Copyright © 2022 Chris Morrow cmmorrow@gmail.com
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program. If not, see <http://www.gnu.org/licenses/>.
+*/
+package urlkit
+
+import (
+	"net/url"
+)
+
+// Encode percent encodes s so it can be placed in a URL path.
+func Encode(s string) string {
+	return url.PathEscape(s)
+}
+
+// Decode decodes a percent encoded string. A + is decoded to a space.
+func Decode(s string) (string, error) {
+	decoded, err := url.QueryUnescape(s)
+	if err != nil {
+		return "", &Error{Kind: KindInvalidInput, Op: "decode", Input: s, Offset: invalidEscapeOffset(s), Err: err}
+	}
+	return decoded, nil
+}
+
+// ToASCII converts a domain to its IDNA (punycode) form with the punycode
+// profile.
+func ToASCII(domain string) (string, error) {
+	return IDNAOptions{}.ToASCII(domain)
+}
+
+// ToUnicode converts an IDNA (punycode) domain back to Unicode with the
+// punycode profile.
+func ToUnicode(domain string) (string, error) {
+	return IDNAOptions{}.ToUnicode(domain)
+}
@@ -0,0 +1,178 @@
+/*
+Copyright © 2022 Chris Morrow cmmorrow@gmail.com
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program. If not, see <http://www.gnu.org/licenses/>.
+*/
+package urlkit
+
+import (
+	"fmt"
+	"strings"
+)
+
+// URL components that have their own percent-encode set.
+const (
+	ComponentPath        = "path"
+	ComponentPathSegment = "path-segment"
+	ComponentQuery       = "query"
+	ComponentQueryValue  = "query-value"
+	ComponentFragment    = "fragment"
+	ComponentUserinfo    = "userinfo"
+	ComponentHost        = "host"
+	ComponentForm        = "form"
+)
+
+// ComponentNames lists the components accepted by ComponentSet.
+var ComponentNames = []string{
+	ComponentPath, ComponentPathSegment, ComponentQuery, ComponentQueryValue,
+	ComponentFragment, ComponentUserinfo, ComponentHost, ComponentForm,
+}
+
+// Standards that define percent-encode sets.
+const (
+	StandardRFC3986 = "rfc3986"
+	StandardWHATWG  = "whatwg"
+)
+
+// EncodeSet is the set of ASCII characters that are left as they are when
+// percent encoding. Every other byte is encoded.
+type EncodeSet struct {
+	allowed [128]bool
+	// SpaceAsPlus encodes a space as + rather than %20.
+	SpaceAsPlus bool
+}
+
+const (
+	rfcUnreserved = "ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz0123456789-._~"
+	rfcSubDelims  = "!$&'()*+,;="
+	rfcPchar      = rfcUnreserved + rfcSubDelims + ":@"
+)
+
+// newSet returns an EncodeSet that allows the characters in allowed.
+func newSet(allowed string) EncodeSet {
+	var s EncodeSet
+	return s.With(allowed)
+}
+
+// printableExcept returns an EncodeSet that allows every printable ASCII
+// character except those in encoded. WHATWG sets are defined this way.
+func printableExcept(encoded string) EncodeSet {
+	var s EncodeSet
+	for c := 0x21; c < 0x7f; c++ {
+		s.allowed[c] = true
+	}
+	return s.Without(encoded)
+}
+
+// With returns a copy of s that also allows the ASCII characters in safe.
+func (s EncodeSet) With(safe string) EncodeSet {
+	for i := 0; i < len(safe); i++ {
+		if safe[i] < 0x80 {
+			s.allowed[safe[i]] = true
+		}
+	}
+	return s
+}
+
+// Without returns a copy of s that encodes the ASCII characters in unsafe.
+func (s EncodeSet) Without(unsafe string) EncodeSet {
+	for i := 0; i < len(unsafe); i++ {
+		if unsafe[i] < 0x80 {
+			s.allowed[unsafe[i]] = false
+		}
+	}
+	return s
+}
+
+// Allows reports whether c is left as it is.
+func (s EncodeSet) Allows(c byte) bool {
+	return c < 0x80 && s.allowed[c]
+}
+
+// rfc3986Sets are the characters allowed in each component by the grammar in
+// RFC 3986. A query value also encodes &, = and + so it can be placed in a
+// key=value pair.
+var rfc3986Sets = map[string]EncodeSet{
+	ComponentPath:        newSet(rfcPchar + "/"),
+	ComponentPathSegment: newSet(rfcPchar),
+	ComponentQuery:       newSet(rfcPchar + "/?"),
+	ComponentQueryValue:  newSet(rfcPchar + "/?").Without("&=+;"),
+	ComponentFragment:    newSet(rfcPchar + "/?"),
+	ComponentUserinfo:    newSet(rfcUnreserved + rfcSubDelims + ":"),
+	ComponentHost:        newSet(rfcUnreserved + rfcSubDelims),
+	ComponentForm:        formSet,
+}
+
+// The percent-encode sets of the WHATWG URL Standard.
+var (
+	whatwgFragmentSet  = printableExcept(" \"<>`")
+	whatwgQuerySet     = printableExcept(" \"#<>")
+	whatwgPathSet      = whatwgQuerySet.Without("?^`{}")
+	whatwgUserinfoSet  = whatwgPathSet.Without("/:;=@[\\]^|")
+	whatwgComponentSet = whatwgUserinfoSet.Without("$%&+,")
+)
+
+// formSet is the application/x-www-form-urlencoded percent-encode set.
+var formSet = EncodeSet{allowed: newSet(rfcUnreserved + "*").Without("~").allowed, SpaceAsPlus: true}
+
+var whatwgSets = map[string]EncodeSet{
+	ComponentPath:        whatwgPathSet,
+	ComponentPathSegment: whatwgPathSet.Without("/"),
+	ComponentQuery:       whatwgQuerySet,
+	ComponentQueryValue:  whatwgComponentSet,
+	ComponentFragment:    whatwgFragmentSet,
+	ComponentUserinfo:    whatwgUserinfoSet,
+	ComponentHost:        printableExcept(""),
+	ComponentForm:        formSet,
+}
+
+// ComponentSet returns the percent-encode set for a URL component as
+// defined by standard, which is StandardRFC3986 or StandardWHATWG.
+func ComponentSet(component string, standard string) (EncodeSet, error) {
+	sets := rfc3986Sets
+	switch standard {
+	case StandardRFC3986, "":
+	case StandardWHATWG:
+		sets = whatwgSets
+	default:
+		return EncodeSet{}, &Error{Kind: KindInvalidInput, Op: "encode", Input: standard, Offset: -1,
+			Err: fmt.Errorf("unknown standard, use %s or %s", StandardRFC3986, StandardWHATWG)}
+	}
+	set, ok := sets[component]
+	if !ok {
+		return EncodeSet{}, &Error{Kind: KindInvalidInput, Op: "encode", Input: component, Offset: -1,
+			Err: fmt.Errorf("unknown component, use one of %s", strings.Join(ComponentNames, ", "))}
+	}
+	return set, nil
+}
+
+// EncodeComponent percent encodes every byte of s that set does not allow.
+func EncodeComponent(s string, set EncodeSet) string {
+	const upperhex = "0123456789ABCDEF"
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case set.Allows(c):
+			b.WriteByte(c)
+		case c == ' ' && set.SpaceAsPlus:
+			b.WriteByte('+')
+		default:
+			b.WriteByte('%')
+			b.WriteByte(upperhex[c>>4])
+			b.WriteByte(upperhex[c&15])
+		}
+	}
+	return b.String()
+}
@@ -0,0 +1,173 @@
+/*
+Copyright © 2022 Chris Morrow cmmorrow@gmail.com
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program. If not, see <http://www.gnu.org/licenses/>.
+*/
+package urlkit
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+// Kind is the category of an Error.
+type Kind int
+
+const (
+	// KindUnknown is an error that does not fit another kind.
+	KindUnknown Kind = iota
+	// KindInvalidInput is a URL or string that cannot be parsed or decoded.
+	KindInvalidInput
+	// KindIDNA is a domain that cannot be converted to or from punycode.
+	KindIDNA
+	// KindJSON is JSON that cannot be read or written.
+	KindJSON
+	// KindValidation is a URL that parses but fails a check.
+	KindValidation
+	// KindIO is a failure to read input or write output.
+	KindIO
+)
+
+var kindNames = map[Kind]string{
+	KindUnknown:      "unknown",
+	KindInvalidInput: "invalid-input",
+	KindIDNA:         "idna",
+	KindJSON:         "json",
+	KindValidation:   "validation",
+	KindIO:           "io",
+}
+
+func (k Kind) String() string {
+	return kindNames[k]
+}
+
+// Error is returned by the functions in this package. It records what kind
+// of failure occurred, the input that caused it and, where it is known, the
+// byte offset of the problem in the input.
+type Error struct {
+	Kind Kind
+	// Op is the operation that failed, such as parse or decode.
+	Op    string
+	Input string
+	// Offset is the byte offset of the problem in Input, or -1 if unknown.
+	Offset int
+	Err    error
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("%s %q: %v", e.Op, e.Input, e.Err)
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// KindOf returns the Kind of err, or KindUnknown if err is not an *Error.
+func KindOf(err error) Kind {
+	var e *Error
+	if errors.As(err, &e) {
+		return e.Kind
+	}
+	return KindUnknown
+}
+
+// parseError converts an error from url.Parse to an *Error.
+func parseError(input string, err error) *Error {
+	var urlErr *url.Error
+	if errors.As(err, &urlErr) {
+		err = urlErr.Err
+	}
+	return &Error{
+		Kind:   KindInvalidInput,
+		Op:     "parse",
+		Input:  input,
+		Offset: parseErrorOffset(input, err),
+		Err:    err,
+	}
+}
+
+// parseErrorOffset finds where in input the url.Parse error err occurred.
+func parseErrorOffset(input string, err error) int {
+	var escapeErr url.EscapeError
+	var hostErr url.InvalidHostError
+	switch {
+	case errors.As(err, &escapeErr):
+		return invalidEscapeOffset(input)
+	case errors.As(err, &hostErr):
+		return strings.Index(input, string(hostErr))
+	}
+	msg := err.Error()
+	switch {
+	case strings.Contains(msg, "invalid control character"):
+		return strings.IndexFunc(input, func(r rune) bool { return r < 0x20 || r == 0x7f })
+	case strings.Contains(msg, "missing protocol scheme"):
+		return 0
+	case strings.Contains(msg, "cannot contain colon"):
+		return strings.Index(input, ":")
+	case strings.HasPrefix(msg, "invalid port "):
+		port := strings.TrimSuffix(strings.TrimPrefix(msg, "invalid port "), " after host")
+		if p, uerr := strconv.Unquote(port); uerr == nil {
+			return strings.LastIndex(input, p)
+		}
+	case strings.Contains(msg, "missing ']' in host"):
+		return strings.Index(input, "[")
+	}
+	return -1
+}
+
+// invalidEscapeOffset returns the offset of the first % in s that is not
+// followed by two hex digits, or -1 if every escape is valid.
+func invalidEscapeOffset(s string) int {
+	if invalid := InvalidEscapes(s); len(invalid) > 0 {
+		return invalid[0].Offset
+	}
+	return -1
+}
+
+// idnaError converts an IDNA error to an *Error, locating the first label
+// of domain that fails to convert.
+func idnaError(op string, domain string, err error, convert func(string) (string, error)) *Error {
+	offset := -1
+	start := 0
+	for _, label := range strings.Split(domain, ".") {
+		if _, lerr := convert(label); lerr != nil {
+			offset = start
+			break
+		}
+		start += len(label) + 1
+	}
+	return &Error{Kind: KindIDNA, Op: op, Input: domain, Offset: offset, Err: err}
+}
+
+// jsonError converts an error from encoding/json to an *Error.
+func jsonError(op string, input string, err error) *Error {
+	offset := -1
+	var syntaxErr *json.SyntaxError
+	var typeErr *json.UnmarshalTypeError
+	switch {
+	case errors.As(err, &syntaxErr):
+		offset = int(syntaxErr.Offset)
+	case errors.As(err, &typeErr):
+		offset = int(typeErr.Offset)
+	}
+	return &Error{Kind: KindJSON, Op: op, Input: input, Offset: offset, Err: err}
+}
+
+func isHex(c byte) bool {
+	return '0' <= c && c <= '9' || 'a' <= c && c <= 'f' || 'A' <= c && c <= 'F'
+}
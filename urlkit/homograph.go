@@ -0,0 +1,373 @@
+/*
+Copyright © 2022 Chris Morrow cmmorrow@gmail.com
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program. If not, see <http://www.gnu.org/licenses/>.
+*/
+package urlkit
+
+import (
+	"bufio"
+	_ "embed"
+	"fmt"
+	"io"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"unicode"
+	"unicode/utf8"
+
+	"golang.org/x/text/unicode/norm"
+)
+
+//go:embed data/confusables.txt
+var confusablesData string
+
+var (
+	confusablesOnce sync.Once
+	confusables     map[rune]string
+)
+
+// confusableMap returns the UTS #39 confusable mappings, parsed the first
+// time they are used.
+func confusableMap() map[rune]string {
+	confusablesOnce.Do(func() {
+		confusables = make(map[rune]string)
+		for _, line := range strings.Split(confusablesData, "\n") {
+			if i := strings.IndexByte(line, '#'); i >= 0 {
+				line = line[:i]
+			}
+			fields := strings.Split(line, ";")
+			if len(fields) < 2 {
+				continue
+			}
+			source, err := strconv.ParseUint(strings.TrimSpace(fields[0]), 16, 32)
+			if err != nil {
+				continue
+			}
+			var target strings.Builder
+			for _, cp := range strings.Fields(fields[1]) {
+				r, err := strconv.ParseUint(cp, 16, 32)
+				if err != nil {
+					continue
+				}
+				target.WriteRune(rune(r))
+			}
+			confusables[rune(source)] = target.String()
+		}
+	})
+	return confusables
+}
+
+// Skeleton returns the UTS #39 skeleton of s. Two strings are confusable if
+// they have the same skeleton.
+func Skeleton(s string) string {
+	m := confusableMap()
+	var b strings.Builder
+	for _, r := range norm.NFD.String(s) {
+		if target, ok := m[r]; ok {
+			b.WriteString(target)
+		} else {
+			b.WriteRune(r)
+		}
+	}
+	return norm.NFD.String(b.String())
+}
+
+// Brands is a list of domains that confusable hosts are checked against.
+type Brands []string
+
+// ParseBrands reads domains with one domain per line. Blank lines and lines
+// starting with # are ignored.
+func ParseBrands(r io.Reader) (Brands, error) {
+	brands, err := readDomains(r)
+	return Brands(brands), err
+}
+
+// readDomains reads lowercased domains, one per line, skipping blank lines
+// and lines starting with #.
+func readDomains(r io.Reader) ([]string, error) {
+	var domains []string
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+		domains = append(domains, strings.ToLower(text))
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, &Error{Kind: KindIO, Op: "read", Offset: -1, Err: err}
+	}
+	return domains, nil
+}
+
+// FindHomographs finds characters in rawURL that can make it look like a
+// different URL: labels of the host that mix scripts, labels that are
+// confusable with ASCII, a host that is confusable with one of brands, and
+// invisible characters anywhere in the URL.
+func FindHomographs(rawURL string, brands Brands) ([]Finding, error) {
+	u, err := Parse(rawURL)
+	if err != nil {
+		return nil, err
+	}
+	return append(FindHostHomographs(u, brands), FindInvisible(rawURL)...), nil
+}
+
+// FindHostHomographs finds the labels of the host of u that mix scripts or
+// are confusable with ASCII, and a host that is confusable with one of
+// brands. The labels of a host that is plain ASCII are not checked.
+func FindHostHomographs(u *url.URL, brands Brands) []Finding {
+	host := strings.ToLower(u.Hostname())
+	plain := isPlainASCII(host)
+	if plain && len(brands) == 0 {
+		return nil
+	}
+	var labels []string
+	if !plain {
+		if unicodeHost, err := ToUnicode(host); err == nil {
+			host = unicodeHost
+		}
+		labels = strings.Split(host, ".")
+	}
+
+	var findings []Finding
+	for _, label := range labels {
+		if scripts := labelScripts(label); !allowedScripts(scripts) {
+			findings = append(findings, Finding{
+				Check:     CheckMixedScript,
+				Component: HostLabel,
+				Message:   fmt.Sprintf("label %s mixes the %s scripts", label, joinAnd(scripts)),
+				Value:     label,
+			})
+		}
+		if lookalike, ok := asciiLookalike(label); ok {
+			findings = append(findings, Finding{
+				Check:     CheckConfusable,
+				Component: HostLabel,
+				Message:   fmt.Sprintf("label %s is confusable with %s", label, lookalike),
+				Value:     label,
+			})
+		}
+	}
+	if brand := confusableBrand(host, brands); brand != "" {
+		findings = append(findings, Finding{
+			Check:     CheckConfusable,
+			Component: HostLabel,
+			Message:   fmt.Sprintf("%s is confusable with the brand domain %s", host, brand),
+			Value:     host,
+		})
+	}
+	return findings
+}
+
+// isPlainASCII returns true if host is ASCII and none of its labels are
+// punycode.
+func isPlainASCII(host string) bool {
+	for i := 0; i < len(host); i++ {
+		if host[i] >= utf8.RuneSelf {
+			return false
+		}
+	}
+	for _, label := range strings.Split(host, ".") {
+		if strings.HasPrefix(label, "xn--") {
+			return false
+		}
+	}
+	return true
+}
+
+// labelScripts returns the scripts of the characters in label, sorted and
+// without the Common and Inherited scripts.
+func labelScripts(label string) []string {
+	seen := make(map[string]bool)
+	for _, r := range label {
+		if r < utf8.RuneSelf {
+			if unicode.IsLetter(r) {
+				seen["Latin"] = true
+			}
+			continue
+		}
+		for name, table := range unicode.Scripts {
+			if name != "Common" && name != "Inherited" && unicode.Is(table, r) {
+				seen[name] = true
+				break
+			}
+		}
+	}
+	scripts := make([]string, 0, len(seen))
+	for name := range seen {
+		scripts = append(scripts, name)
+	}
+	sort.Strings(scripts)
+	return scripts
+}
+
+// allowedScriptSets are the combinations of scripts that the Highly
+// Restrictive level of UTS #39 allows in one label.
+var allowedScriptSets = [][]string{
+	{"Latin", "Han", "Hiragana", "Katakana"},
+	{"Latin", "Han", "Bopomofo"},
+	{"Latin", "Han", "Hangul"},
+}
+
+// allowedScripts returns true if a label with scripts is single script or
+// one of allowedScriptSets.
+func allowedScripts(scripts []string) bool {
+	if len(scripts) <= 1 {
+		return true
+	}
+	for _, set := range allowedScriptSets {
+		allowed := true
+		for _, s := range scripts {
+			if !containsString(set, s) {
+				allowed = false
+				break
+			}
+		}
+		if allowed {
+			return true
+		}
+	}
+	return false
+}
+
+// asciiLookalike returns the ASCII label that label is confusable with if
+// label has characters that are not ASCII and all of them are confusable
+// with ASCII.
+func asciiLookalike(label string) (string, bool) {
+	m := confusableMap()
+	var b strings.Builder
+	nonASCII := false
+	for _, r := range norm.NFD.String(label) {
+		if r < utf8.RuneSelf {
+			b.WriteRune(r)
+			continue
+		}
+		nonASCII = true
+		target, ok := m[r]
+		if !ok || !isASCII(target) {
+			return "", false
+		}
+		b.WriteString(target)
+	}
+	return b.String(), nonASCII
+}
+
+// confusableBrand returns the brand domain that consecutive labels of host
+// are confusable with but not equal to, such as pаypal.com in
+// pаypal.com.evil.net.
+func confusableBrand(host string, brands Brands) string {
+	labels := strings.Split(host, ".")
+	for _, brand := range brands {
+		brand = strings.ToLower(brand)
+		n := strings.Count(brand, ".") + 1
+		brandSkeleton := Skeleton(brand)
+		for i := 0; i+n <= len(labels); i++ {
+			part := strings.Join(labels[i:i+n], ".")
+			if part != brand && Skeleton(part) == brandSkeleton {
+				return brand
+			}
+		}
+	}
+	return ""
+}
+
+// invisibleRanges are characters that take up no space or are not displayed,
+// such as zero width spaces and joiners, bidi controls, fillers and tags.
+var invisibleRanges = &unicode.RangeTable{
+	R16: []unicode.Range16{
+		{0x00AD, 0x00AD, 1},
+		{0x034F, 0x034F, 1},
+		{0x061C, 0x061C, 1},
+		{0x115F, 0x1160, 1},
+		{0x17B4, 0x17B5, 1},
+		{0x180B, 0x180E, 1},
+		{0x200B, 0x200F, 1},
+		{0x202A, 0x202E, 1},
+		{0x2060, 0x206F, 1},
+		{0x3164, 0x3164, 1},
+		{0xFE00, 0xFE0F, 1},
+		{0xFEFF, 0xFEFF, 1},
+		{0xFFA0, 0xFFA0, 1},
+	},
+	R32: []unicode.Range32{
+		{0x1D173, 0x1D17A, 1},
+		{0xE0000, 0xE0FFF, 1},
+	},
+}
+
+// FindInvisible finds invisible characters in the components of rawURL,
+// whether they are percent encoded or not.
+func FindInvisible(rawURL string) []Finding {
+	r := SplitReference(rawURL)
+	userinfo, host := "", r.Authority
+	if i := strings.LastIndexByte(host, '@'); i >= 0 {
+		userinfo, host = host[:i], host[i+1:]
+	}
+	var findings []Finding
+	check := func(component string, raw string) {
+		decoded, err := DecodeComponent(raw, DecodeOptions{Component: ComponentPath, Lenient: true})
+		if err != nil {
+			decoded = raw
+		}
+		seen := make(map[rune]bool)
+		for _, c := range decoded {
+			if !unicode.Is(invisibleRanges, c) || seen[c] {
+				continue
+			}
+			seen[c] = true
+			findings = append(findings, Finding{
+				Check:     CheckInvisible,
+				Component: component,
+				Message:   fmt.Sprintf("invisible character %U", c),
+				Value:     raw,
+			})
+		}
+	}
+	check(SchemeLabel, r.Scheme)
+	check(UserLabel, userinfo)
+	check(HostLabel, host)
+	check(PathLabel, r.Path)
+	check(ParamsLabel, r.Query)
+	check(FragmentLabel, r.Fragment)
+	return findings
+}
+
+func isASCII(s string) bool {
+	for i := 0; i < len(s); i++ {
+		if s[i] >= utf8.RuneSelf {
+			return false
+		}
+	}
+	return true
+}
+
+func containsString(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
+
+// joinAnd joins items as a list in prose, such as "a, b and c".
+func joinAnd(items []string) string {
+	if len(items) <= 1 {
+		return strings.Join(items, "")
+	}
+	return strings.Join(items[:len(items)-1], ", ") + " and " + items[len(items)-1]
+}
@@ -0,0 +1,357 @@
+/*
+Copyright © 2022 Chris Morrow cmmorrow@gmail.com
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program. If not, see <http://www.gnu.org/licenses/>.
+*/
+package urlkit
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"golang.org/x/net/idna"
+	"golang.org/x/text/secure/bidirule"
+	"golang.org/x/text/unicode/bidi"
+	"golang.org/x/text/unicode/norm"
+)
+
+// UTS #46 processing profiles.
+const (
+	// IDNAPunycode converts labels to and from punycode with no mapping
+	// and, unless options are set, no validation.
+	IDNAPunycode = "punycode"
+	// IDNALookup maps and validates a domain for lookup, as browsers do.
+	IDNALookup = "lookup"
+	// IDNADisplay maps and validates a domain for display to a user.
+	IDNADisplay = "display"
+	// IDNARegistration validates a domain for registration. Nothing is
+	// mapped, so a domain that is not already in its mapped form fails.
+	IDNARegistration = "registration"
+)
+
+// IDNAProfileNames lists the profiles accepted by DefaultIDNAOptions.
+var IDNAProfileNames = []string{IDNAPunycode, IDNALookup, IDNADisplay, IDNARegistration}
+
+// IDNAOptions control how a domain is converted with UTS #46 processing.
+// The zero value is the punycode profile with no validation.
+type IDNAOptions struct {
+	// Profile is one of IDNAProfileNames. An empty Profile is IDNAPunycode.
+	Profile string
+	// Transitional maps deviation characters such as ß and ς to ss and σ,
+	// as IDNA2003 did, instead of keeping them. Only the lookup and display
+	// profiles map characters.
+	Transitional bool
+	// STD3 only allows letters, digits and hyphens in ASCII labels.
+	STD3 bool
+	// CheckHyphens rejects labels that start or end with a hyphen or have
+	// hyphens in the third and fourth positions.
+	CheckHyphens bool
+	// CheckBidi applies the Bidi rule of RFC 5893 to domains that contain
+	// right-to-left characters. The registration profile always does.
+	CheckBidi bool
+	// CheckJoiners applies the CONTEXTJ rules of RFC 5892 to zero width
+	// joiners and non-joiners, and rejects labels that start with a
+	// combining mark.
+	CheckJoiners bool
+	// VerifyDNSLength rejects empty labels and labels and domains that are
+	// too long for DNS.
+	VerifyDNSLength bool
+}
+
+// IDNAError is the UTS #46 rule that a label of a domain violates. The rule
+// is a validity criterion or processing step of UTS #46, such as V2 or P1,
+// or B for the Bidi rule and C for the CONTEXTJ rules.
+type IDNAError struct {
+	Label  string
+	Rule   string
+	Reason string
+}
+
+func (e *IDNAError) Error() string {
+	return fmt.Sprintf("label %q: %s (%s)", e.Label, e.Reason, e.Rule)
+}
+
+// DefaultIDNAOptions returns the options of a UTS #46 profile.
+func DefaultIDNAOptions(profile string) (IDNAOptions, error) {
+	switch profile {
+	case IDNAPunycode, "":
+		return IDNAOptions{Profile: IDNAPunycode}, nil
+	case IDNALookup, IDNADisplay:
+		return IDNAOptions{Profile: profile, STD3: true, CheckHyphens: true, CheckBidi: true, CheckJoiners: true}, nil
+	case IDNARegistration:
+		return IDNAOptions{Profile: profile, STD3: true, CheckHyphens: true, CheckBidi: true, CheckJoiners: true,
+			VerifyDNSLength: true}, nil
+	}
+	return IDNAOptions{}, &Error{Kind: KindInvalidInput, Op: "idna-profile", Input: profile, Offset: -1,
+		Err: fmt.Errorf("unknown IDNA profile, expected one of %s", strings.Join(IDNAProfileNames, ", "))}
+}
+
+// ToASCII converts a domain to its IDNA (punycode) form.
+func (o IDNAOptions) ToASCII(domain string) (string, error) {
+	out, err := o.profile().ToASCII(domain)
+	if err == nil && o.STD3 && o.maps() == nil {
+		err = o.checkSTD3(domain)
+	}
+	if err != nil {
+		return "", o.error("to-ascii", domain, err)
+	}
+	return out, nil
+}
+
+// ToUnicode converts an IDNA (punycode) domain back to Unicode.
+func (o IDNAOptions) ToUnicode(domain string) (string, error) {
+	out, err := o.profile().ToUnicode(domain)
+	if err == nil && o.STD3 && o.maps() == nil {
+		err = o.checkSTD3(domain)
+	}
+	if err != nil {
+		return "", o.error("to-unicode", domain, err)
+	}
+	return out, nil
+}
+
+// maps returns the profile option that maps and validates characters, or
+// nil for the punycode profile.
+func (o IDNAOptions) maps() idna.Option {
+	switch o.Profile {
+	case IDNALookup, IDNADisplay:
+		return idna.MapForLookup()
+	case IDNARegistration:
+		return idna.ValidateForRegistration()
+	}
+	return nil
+}
+
+func (o IDNAOptions) profile() *idna.Profile {
+	var opts []idna.Option
+	if m := o.maps(); m != nil {
+		opts = append(opts, m)
+	}
+	opts = append(opts,
+		idna.Transitional(o.Transitional),
+		idna.StrictDomainName(o.STD3),
+		idna.CheckHyphens(o.CheckHyphens),
+		idna.CheckJoiners(o.CheckJoiners),
+		idna.VerifyDNSLength(o.VerifyDNSLength),
+	)
+	if o.CheckBidi {
+		opts = append(opts, idna.BidiRule())
+	}
+	return idna.New(opts...)
+}
+
+// checkSTD3 applies the STD3 rules to the ASCII characters of domain, which
+// the punycode profile does not do itself.
+func (o IDNAOptions) checkSTD3(domain string) error {
+	for _, label := range strings.Split(domain, ".") {
+		for _, r := range label {
+			if r < utf8.RuneSelf && !isLDH(r) {
+				return std3Error(label, r)
+			}
+		}
+	}
+	return nil
+}
+
+// idnaLabel is a label of a domain and its byte offset in the domain.
+type idnaLabel struct {
+	text   string
+	offset int
+}
+
+// idnaLabels splits domain on the dots that UTS #46 treats as label
+// separators.
+func idnaLabels(domain string) []idnaLabel {
+	var labels []idnaLabel
+	start := 0
+	for i, r := range domain {
+		switch r {
+		case '.', '。', '．', '｡':
+			labels = append(labels, idnaLabel{domain[start:i], start})
+			start = i + utf8.RuneLen(r)
+		}
+	}
+	return append(labels, idnaLabel{domain[start:], start})
+}
+
+// error finds the rule that domain violates and returns it as an *Error
+// with the offset of the label, or of the character, that violates it.
+func (o IDNAOptions) error(op string, domain string, err error) *Error {
+	if _, ok := err.(*IDNAError); ok {
+		return &Error{Kind: KindIDNA, Op: op, Input: domain, Offset: strings.Index(domain, err.(*IDNAError).Label), Err: err}
+	}
+	labels := idnaLabels(domain)
+	for i, l := range labels {
+		if l.text == "" && i == len(labels)-1 && i > 0 {
+			// A trailing dot is the root label.
+			continue
+		}
+		if offset, lerr := o.checkLabel(l.text); lerr != nil {
+			return &Error{Kind: KindIDNA, Op: op, Input: domain, Offset: l.offset + offset, Err: lerr}
+		}
+	}
+	if o.CheckBidi || o.Profile == IDNARegistration {
+		if offset, berr := checkBidi(labels); berr != nil {
+			return &Error{Kind: KindIDNA, Op: op, Input: domain, Offset: offset, Err: berr}
+		}
+	}
+	if o.VerifyDNSLength {
+		if ascii, aerr := idna.Punycode.ToASCII(domain); aerr == nil && len(strings.TrimSuffix(ascii, ".")) > 253 {
+			return &Error{Kind: KindIDNA, Op: op, Input: domain, Offset: 0,
+				Err: &IDNAError{Label: domain, Rule: "A4", Reason: "domain is longer than 253 bytes"}}
+		}
+	}
+	return idnaError(op, domain, err, o.profile().ToASCII)
+}
+
+// checkLabel returns the first rule that label violates and the offset in
+// label of the character that violates it.
+func (o IDNAOptions) checkLabel(label string) (int, error) {
+	if label == "" {
+		if o.VerifyDNSLength {
+			return 0, &IDNAError{Label: label, Rule: "A4", Reason: "label is empty"}
+		}
+		return 0, nil
+	}
+	text := label
+	fromPunycode := strings.HasPrefix(strings.ToLower(label), "xn--")
+	if fromPunycode {
+		decoded, err := idna.Punycode.ToUnicode(label)
+		if err != nil {
+			return 0, &IDNAError{Label: label, Rule: "P4", Reason: "label is not valid punycode"}
+		}
+		text = decoded
+	}
+
+	// Characters are only validated by profiles that map them, and for
+	// labels that were punycode.
+	if o.maps() != nil || (fromPunycode && (o.CheckHyphens || o.CheckJoiners)) {
+		if (fromPunycode || o.Profile == IDNARegistration) && !norm.NFC.IsNormalString(text) {
+			return 0, &IDNAError{Label: label, Rule: "V1", Reason: "label is not in Unicode Normalization Form C"}
+		}
+		for i, r := range text {
+			if err := o.checkRune(label, r, fromPunycode); err != nil {
+				if fromPunycode {
+					i = 0
+				}
+				return i, err
+			}
+		}
+	}
+
+	if o.CheckHyphens {
+		switch {
+		case len(text) >= 4 && text[2] == '-' && text[3] == '-' && !fromPunycode:
+			return 2, &IDNAError{Label: label, Rule: "V2", Reason: "label has hyphens in the third and fourth positions"}
+		case strings.HasPrefix(text, "-"):
+			return 0, &IDNAError{Label: label, Rule: "V3", Reason: "label starts with a hyphen"}
+		case strings.HasSuffix(text, "-"):
+			return len(label) - 1, &IDNAError{Label: label, Rule: "V3", Reason: "label ends with a hyphen"}
+		}
+	}
+
+	if o.CheckJoiners {
+		if r, _ := utf8.DecodeRuneInString(text); unicode.Is(unicode.M, r) {
+			return 0, &IDNAError{Label: label, Rule: "V5", Reason: fmt.Sprintf("label starts with the combining mark %U", r)}
+		}
+		if _, err := idna.New(idna.CheckJoiners(true)).ToUnicode(text); err != nil {
+			i := strings.IndexAny(text, "‌‍")
+			if i < 0 || fromPunycode {
+				i = 0
+			}
+			r, _ := utf8.DecodeRuneInString(text[i:])
+			return i, &IDNAError{Label: label, Rule: "C", Reason: fmt.Sprintf("%U is not allowed by the CONTEXTJ rules here", r)}
+		}
+	}
+
+	if o.VerifyDNSLength {
+		ascii, err := idna.Punycode.ToASCII(text)
+		if err == nil && len(ascii) > 63 {
+			return 0, &IDNAError{Label: label, Rule: "A4", Reason: fmt.Sprintf("label is %d bytes long, more than 63", len(ascii))}
+		}
+	}
+	return 0, nil
+}
+
+// checkRune returns an error if the profile does not allow r.
+func (o IDNAOptions) checkRune(label string, r rune, fromPunycode bool) error {
+	if r < utf8.RuneSelf && !isLDH(r) {
+		if o.STD3 {
+			return std3Error(label, r)
+		}
+		return nil
+	}
+	if r < utf8.RuneSelf && o.Profile != IDNARegistration {
+		return nil
+	}
+	single := IDNAOptions{Profile: o.Profile, Transitional: o.Transitional}
+	if fromPunycode {
+		single.Profile = IDNADisplay
+	}
+	if _, err := single.profile().ToUnicode(string(r)); err == nil {
+		return nil
+	}
+	rule := "P1"
+	if fromPunycode {
+		rule = "V6"
+	}
+	reason := fmt.Sprintf("character %q (%U) is not allowed", r, r)
+	if o.Profile == IDNARegistration {
+		if mapped, err := idna.New(idna.MapForLookup(), idna.StrictDomainName(false)).ToUnicode(string(r)); err == nil && mapped != string(r) {
+			reason = fmt.Sprintf("character %q (%U) is not allowed for registration, it maps to %q", r, r, mapped)
+		}
+	}
+	return &IDNAError{Label: label, Rule: rule, Reason: reason}
+}
+
+// checkBidi applies the Bidi rule to every label if any label has
+// right-to-left characters.
+func checkBidi(labels []idnaLabel) (int, error) {
+	rtl := false
+	for _, l := range labels {
+		text := l.text
+		if decoded, err := idna.Punycode.ToUnicode(text); err == nil {
+			text = decoded
+		}
+		if bidirule.DirectionString(text) != bidi.LeftToRight {
+			rtl = true
+		}
+	}
+	if !rtl {
+		return 0, nil
+	}
+	for _, l := range labels {
+		text := l.text
+		if decoded, err := idna.Punycode.ToUnicode(text); err == nil {
+			text = decoded
+		}
+		if !bidirule.ValidString(text) {
+			return l.offset, &IDNAError{Label: l.text, Rule: "B",
+				Reason: "label does not satisfy the Bidi rule of RFC 5893 for a domain with right-to-left characters"}
+		}
+	}
+	return 0, nil
+}
+
+func std3Error(label string, r rune) error {
+	return &IDNAError{Label: label, Rule: "STD3",
+		Reason: fmt.Sprintf("character %q is not a letter, digit or hyphen, as the STD3 rules require", r)}
+}
+
+// isLDH returns true for the letters, digits and hyphen allowed by STD3.
+func isLDH(r rune) bool {
+	return r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || r == '-'
+}
@@ -0,0 +1,210 @@
+/*
+Copyright © 2022 Chris Morrow cmmorrow@gmail.com
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program. If not, see <http://www.gnu.org/licenses/>.
+*/
+package urlkit
+
+import (
+	"fmt"
+	"strings"
+)
+
+// DefaultMaxDepth is the default number of layers DecodeLayers decodes.
+const DefaultMaxDepth = 10
+
+// Checks run by Inspect.
+const (
+	CheckDoubleEncoding = "double-encoding"
+	CheckMixedScript    = "mixed-script"
+	CheckConfusable     = "confusable"
+	CheckInvisible      = "invisible"
+)
+
+// Finding is a problem found in a URL by one of the checks.
+type Finding struct {
+	Check string `json:"check"`
+	// Component is the label of the component the problem is in.
+	Component string `json:"component,omitempty"`
+	// Key is the key of the query parameter the problem is in.
+	Key     string `json:"key,omitempty"`
+	Message string `json:"message"`
+	Value   string `json:"value,omitempty"`
+}
+
+func (f Finding) String() string {
+	where := f.Component
+	if f.Key != "" {
+		where += " " + f.Key
+	}
+	if where == "" {
+		return fmt.Sprintf("%s: %s", f.Check, f.Message)
+	}
+	return fmt.Sprintf("%s: %s: %s", f.Check, where, f.Message)
+}
+
+// InspectOptions select the checks that Inspect runs. Every check except
+// Differential is run if none are selected.
+type InspectOptions struct {
+	// DoubleEncoding finds components that are percent encoded more than
+	// once.
+	DoubleEncoding bool
+	// Homograph finds mixed scripts, confusable characters and invisible
+	// characters, and hosts confusable with Brands.
+	Homograph bool
+	Brands    Brands
+	// Redirects finds parameters that redirect to hosts other than the
+	// host of the URL and TrustedHosts.
+	Redirects    bool
+	TrustedHosts TrustedHosts
+	// Differential finds components that the parsers of ParserNames read
+	// differently.
+	Differential bool
+}
+
+// Inspect runs the checks selected by opts on rawURL. With Differential, it
+// also returns what each parser read. The checks other than Differential
+// need a URL that Go's net/url accepts, so a URL that it rejects but another
+// parser accepts is reported by the differential check instead, which is
+// then run if no checks are selected.
+func Inspect(rawURL string, opts InspectOptions) ([]Finding, []ParserResult, error) {
+	goChecks := opts.DoubleEncoding || opts.Homograph || opts.Redirects
+	all := !goChecks && !opts.Differential
+	var findings []Finding
+	var parsers []ParserResult
+	_, parseErr := Parse(rawURL)
+	if opts.Differential || all && parseErr != nil {
+		parsers = CompareParsers(rawURL)
+		findings = append(findings, FindParserDifferentials(parsers)...)
+	}
+	if parseErr != nil {
+		if len(findings) > 0 || !goChecks && !all {
+			return findings, parsers, nil
+		}
+		return nil, nil, parseErr
+	}
+	if all || opts.DoubleEncoding {
+		f, err := FindDoubleEncoding(rawURL)
+		if err != nil {
+			return nil, nil, err
+		}
+		findings = append(findings, f...)
+	}
+	if all || opts.Homograph {
+		f, err := FindHomographs(rawURL, opts.Brands)
+		if err != nil {
+			return nil, nil, err
+		}
+		findings = append(findings, f...)
+	}
+	if all || opts.Redirects {
+		f, err := FindRedirects(rawURL, opts.TrustedHosts)
+		if err != nil {
+			return nil, nil, err
+		}
+		findings = append(findings, f...)
+	}
+	return findings, parsers, nil
+}
+
+// DecodeLayers decodes s repeatedly until it stops changing or maxDepth
+// layers have been decoded. It returns s followed by each decoded layer and
+// whether a fixed point was reached. Only a failure to decode s itself is an
+// error, since a later layer that cannot be decoded is already plain text.
+func DecodeLayers(s string, opts DecodeOptions, maxDepth int) ([]string, bool, error) {
+	// Layers are decoded as bytes and only converted from the charset for
+	// display, so that each layer is decoded from the bytes before it.
+	charset, detect := opts.Charset, opts.DetectCharset
+	opts.Charset, opts.DetectCharset = "", false
+	layers := []string{s}
+	fixed := false
+	for depth := 0; ; depth++ {
+		if depth == maxDepth {
+			decoded, err := DecodeComponent(s, opts)
+			fixed = err != nil || decoded == s
+			break
+		}
+		decoded, err := DecodeComponent(s, opts)
+		if err != nil {
+			if depth == 0 {
+				return nil, false, err
+			}
+			fixed = true
+			break
+		}
+		if decoded == s {
+			fixed = true
+			break
+		}
+		layers = append(layers, decoded)
+		s = decoded
+	}
+	for i := 1; i < len(layers); i++ {
+		converted, err := convertCharset(layers[i], charset, detect)
+		if err != nil {
+			return nil, false, err
+		}
+		layers[i] = converted
+	}
+	return layers, fixed, nil
+}
+
+// FindDoubleEncoding finds the components of rawURL that still contain
+// percent encodings after they are decoded, such as %252F.
+func FindDoubleEncoding(rawURL string) ([]Finding, error) {
+	if _, err := Parse(rawURL); err != nil {
+		return nil, err
+	}
+	r := SplitReference(rawURL)
+	var findings []Finding
+	check := func(component string, key string, raw string, decodeAs string) {
+		once, err := DecodeComponent(raw, DecodeOptions{Component: decodeAs, Lenient: true})
+		if err != nil || !hasEscape(once) {
+			return
+		}
+		layers, _, _ := DecodeLayers(raw, DecodeOptions{Component: ComponentPath, Lenient: true}, DefaultMaxDepth)
+		findings = append(findings, Finding{
+			Check:     CheckDoubleEncoding,
+			Component: component,
+			Key:       key,
+			Message:   fmt.Sprintf("encoded %d times, decodes to %s", len(layers)-1, layers[len(layers)-1]),
+			Value:     raw,
+		})
+	}
+
+	userinfo, host := "", r.Authority
+	if i := strings.LastIndexByte(host, '@'); i >= 0 {
+		userinfo, host = host[:i], host[i+1:]
+	}
+	check(UserLabel, "", userinfo, ComponentUserinfo)
+	check(HostLabel, "", host, ComponentHost)
+	check(PathLabel, "", r.Path, ComponentPath)
+	for _, p := range SplitRawQuery(r.Query) {
+		key := p.Key()
+		check(ParamLabel, key, p.RawKey, ComponentForm)
+		check(ParamLabel, key, p.RawValue, ComponentForm)
+	}
+	check(FragmentLabel, "", r.Fragment, ComponentFragment)
+	return findings, nil
+}
+
+// hasEscape reports whether s contains a valid percent encoding.
+func hasEscape(s string) bool {
+	for i := 0; i+2 < len(s); i++ {
+		if s[i] == '%' && isHex(s[i+1]) && isHex(s[i+2]) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,229 @@
+/*
+Copyright © 2022 Chris Morrow cmmorrow@gmail.com
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program. If not, see <http://www.gnu.org/licenses/>.
+*/
+package urlkit
+
+import (
+	"fmt"
+	"math"
+	"net"
+	"strconv"
+	"strings"
+)
+
+// Classes of IP addresses.
+const (
+	ClassPublic        = "public"
+	ClassUnspecified   = "unspecified"
+	ClassLoopback      = "loopback"
+	ClassPrivate       = "private"
+	ClassLinkLocal     = "link-local"
+	ClassMulticast     = "multicast"
+	ClassCGNAT         = "cgnat"
+	ClassDocumentation = "documentation"
+	ClassReserved      = "reserved"
+)
+
+// IPClassNames lists the classes returned by IPClass.
+var IPClassNames = []string{ClassPublic, ClassUnspecified, ClassLoopback, ClassPrivate, ClassLinkLocal,
+	ClassMulticast, ClassCGNAT, ClassDocumentation, ClassReserved}
+
+// IPHost is a host that is an IP address.
+type IPHost struct {
+	// Version is 4 or 6.
+	Version int `json:"version"`
+	// Canonical is the address in dotted decimal for IPv4 and in the form
+	// of RFC 5952 for IPv6.
+	Canonical string `json:"canonical"`
+	// Zone is the zone ID of an IPv6 address, such as eth0.
+	Zone string `json:"zone,omitempty"`
+	// Legacy is true if an IPv4 address is not in dotted decimal, such as
+	// 0x7f.1 or 2130706433.
+	Legacy bool   `json:"legacy"`
+	Class  string `json:"class"`
+	IP     net.IP `json:"-"`
+}
+
+// ipClasses are the address blocks of each class other than public, most
+// specific first.
+var ipClasses = []struct {
+	cidr  string
+	class string
+}{
+	{"0.0.0.0/32", ClassUnspecified},
+	{"0.0.0.0/8", ClassReserved},
+	{"10.0.0.0/8", ClassPrivate},
+	{"100.64.0.0/10", ClassCGNAT},
+	{"127.0.0.0/8", ClassLoopback},
+	{"169.254.0.0/16", ClassLinkLocal},
+	{"172.16.0.0/12", ClassPrivate},
+	{"192.0.0.0/24", ClassReserved},
+	{"192.0.2.0/24", ClassDocumentation},
+	{"192.168.0.0/16", ClassPrivate},
+	{"198.18.0.0/15", ClassReserved},
+	{"198.51.100.0/24", ClassDocumentation},
+	{"203.0.113.0/24", ClassDocumentation},
+	{"224.0.0.0/4", ClassMulticast},
+	{"240.0.0.0/4", ClassReserved},
+	{"::/128", ClassUnspecified},
+	{"::1/128", ClassLoopback},
+	{"64:ff9b::/96", ClassReserved},
+	{"100::/64", ClassReserved},
+	{"2001:db8::/32", ClassDocumentation},
+	{"2001::/23", ClassReserved},
+	{"3fff::/20", ClassDocumentation},
+	{"fc00::/7", ClassPrivate},
+	{"fe80::/10", ClassLinkLocal},
+	{"fec0::/10", ClassReserved},
+	{"ff00::/8", ClassMulticast},
+}
+
+// IPClass returns the class of ip. An IPv4-mapped IPv6 address has the
+// class of the IPv4 address.
+func IPClass(ip net.IP) string {
+	if ip4 := ip.To4(); ip4 != nil {
+		ip = ip4
+	}
+	for _, c := range ipClasses {
+		_, block, _ := net.ParseCIDR(c.cidr)
+		if len(block.IP) == len(ip) && block.Contains(ip) {
+			return c.class
+		}
+	}
+	if len(ip) == net.IPv6len && ip[0]&0xe0 != 0x20 {
+		// Only 2000::/3 is allocated for global unicast.
+		return ClassReserved
+	}
+	return ClassPublic
+}
+
+// ParseIPHost parses a host as an IP address. It returns nil if the host is
+// a domain. IPv4 addresses are parsed as the WHATWG URL Standard does, so
+// the legacy forms 0x7f.1, 0177.0.0.1, 127.1 and 2130706433 are all
+// 127.0.0.1. A host that ends in a number but is not a valid IPv4 address
+// is an error. IPv6 addresses may be in brackets and have a zone ID.
+func ParseIPHost(host string) (*IPHost, error) {
+	if strings.HasPrefix(host, "[") && strings.HasSuffix(host, "]") {
+		host = host[1 : len(host)-1]
+	}
+	if strings.Contains(host, ":") {
+		addr, zone := host, ""
+		if i := strings.IndexByte(host, '%'); i >= 0 {
+			addr, zone = host[:i], host[i+1:]
+			// RFC 6874 encodes the % before a zone ID in a URL as %25.
+			if strings.HasPrefix(host[i:], "%25") {
+				zone = host[i+3:]
+			}
+			if unescaped, err := Decode(zone); err == nil {
+				zone = unescaped
+			}
+		}
+		ip := net.ParseIP(addr)
+		if ip == nil {
+			return nil, &Error{Kind: KindInvalidInput, Op: "parse-ip", Input: host, Offset: -1, Err: fmt.Errorf("invalid IPv6 address")}
+		}
+		canonical := ip.String()
+		if ip4 := ip.To4(); ip4 != nil {
+			// net.IP formats an IPv4-mapped address as IPv4.
+			canonical = "::ffff:" + ip4.String()
+		}
+		return &IPHost{Version: 6, Canonical: canonical, Zone: zone, Class: IPClass(ip), IP: ip}, nil
+	}
+
+	ip, ok, err := parseIPv4(host)
+	if err != nil || !ok {
+		return nil, err
+	}
+	return &IPHost{Version: 4, Canonical: ip.String(), Legacy: ip.String() != host, Class: IPClass(ip), IP: ip}, nil
+}
+
+// parseIPv4 implements the IPv4 parser of the WHATWG URL Standard. ok is
+// false if the host does not end in a number, in which case it is a domain.
+func parseIPv4(host string) (net.IP, bool, error) {
+	parts := strings.Split(host, ".")
+	if parts[len(parts)-1] == "" && len(parts) > 1 {
+		parts = parts[:len(parts)-1]
+	}
+	if !endsInNumber(parts[len(parts)-1]) {
+		return nil, false, nil
+	}
+	invalid := func(reason string) error {
+		return &Error{Kind: KindInvalidInput, Op: "parse-ip", Input: host, Offset: -1, Err: fmt.Errorf("invalid IPv4 address, %s", reason)}
+	}
+	if len(parts) > 4 {
+		return nil, true, invalid("more than 4 parts")
+	}
+	numbers := make([]uint64, len(parts))
+	for i, part := range parts {
+		n, err := parseIPv4Number(part)
+		if err != nil {
+			return nil, true, invalid(fmt.Sprintf("part %q is not a number", part))
+		}
+		if i < len(parts)-1 && n > 255 {
+			return nil, true, invalid(fmt.Sprintf("part %q is more than 255", part))
+		}
+		numbers[i] = n
+	}
+	last := numbers[len(numbers)-1]
+	if float64(last) >= math.Pow(256, float64(5-len(numbers))) {
+		return nil, true, invalid(fmt.Sprintf("part %q is too large", parts[len(parts)-1]))
+	}
+	ipv4 := last
+	for i, n := range numbers[:len(numbers)-1] {
+		ipv4 += n << (8 * uint(3-i))
+	}
+	return net.IPv4(byte(ipv4>>24), byte(ipv4>>16), byte(ipv4>>8), byte(ipv4)).To4(), true, nil
+}
+
+// endsInNumber returns true if the last part of a host is a decimal number
+// or a hexadecimal number with a 0x prefix.
+func endsInNumber(last string) bool {
+	if last != "" && strings.Trim(last, "0123456789") == "" {
+		return true
+	}
+	// A hexadecimal number that is too large is still a number.
+	if len(last) < 2 || last[0] != '0' || last[1] != 'x' && last[1] != 'X' {
+		return false
+	}
+	for i := 2; i < len(last); i++ {
+		if !isHex(last[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+// parseIPv4Number parses a part of an IPv4 address, which is hexadecimal
+// with a 0x prefix, octal with a 0 prefix and decimal otherwise.
+func parseIPv4Number(part string) (uint64, error) {
+	if part == "" {
+		return 0, fmt.Errorf("empty part")
+	}
+	base := 10
+	switch {
+	case len(part) >= 2 && (part[:2] == "0x" || part[:2] == "0X"):
+		part, base = part[2:], 16
+	case len(part) >= 2 && part[0] == '0':
+		part, base = part[1:], 8
+	}
+	if part == "" {
+		return 0, nil
+	}
+	if strings.ContainsAny(part, "+-") {
+		return 0, fmt.Errorf("invalid number")
+	}
+	return strconv.ParseUint(part, base, 64)
+}
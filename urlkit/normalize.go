@@ -0,0 +1,309 @@
+/*
+Copyright © 2022 Chris Morrow cmmorrow@gmail.com
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program. If not, see <http://www.gnu.org/licenses/>.
+*/
+package urlkit
+
+import (
+	"net/url"
+	"sort"
+	"strings"
+)
+
+// DefaultPorts maps schemes to their default port.
+var DefaultPorts = map[string]string{
+	"ftp":    "21",
+	"gopher": "70",
+	"http":   "80",
+	"https":  "443",
+	"ldap":   "389",
+	"ldaps":  "636",
+	"nntp":   "119",
+	"rtsp":   "554",
+	"sftp":   "22",
+	"ssh":    "22",
+	"telnet": "23",
+	"ws":     "80",
+	"wss":    "443",
+}
+
+// NormalizeOptions select the normalization steps applied by Normalize.
+type NormalizeOptions struct {
+	// Lowercase lowercases the scheme and host.
+	Lowercase bool
+	// UppercaseEscapes uppercases the hex digits of percent encodings.
+	UppercaseEscapes bool
+	// DecodeUnreserved decodes percent encoded unreserved characters.
+	DecodeUnreserved bool
+	// RemoveDotSegments removes . and .. segments from the path.
+	RemoveDotSegments bool
+	// RemoveDefaultPort removes the port if it is the default for the scheme.
+	RemoveDefaultPort bool
+	// RootPath sets an empty path to / when there is a host.
+	RootPath bool
+	// SortParams sorts the query parameters by key.
+	SortParams bool
+	// RemoveEmptyQuery removes a ? with no query.
+	RemoveEmptyQuery bool
+	// RemoveEmptyFragment removes a # with no fragment.
+	RemoveEmptyFragment bool
+	// RemoveTrailingSlash removes a trailing / from the path.
+	RemoveTrailingSlash bool
+	// Puny converts the host to punycode (IDNA).
+	Puny bool
+	// IDNA are the UTS #46 options Puny converts the host with.
+	IDNA IDNAOptions
+}
+
+// DefaultNormalizeOptions are the RFC 3986 normalizations that do not change
+// what a URL refers to.
+var DefaultNormalizeOptions = NormalizeOptions{
+	Lowercase:         true,
+	UppercaseEscapes:  true,
+	DecodeUnreserved:  true,
+	RemoveDotSegments: true,
+	RemoveDefaultPort: true,
+	RootPath:          true,
+}
+
+// Normalize parses rawURL and returns it in a normal form following the
+// syntax-based and scheme-based normalization of RFC 3986 section 6.
+func Normalize(rawURL string, opts NormalizeOptions) (string, error) {
+	u, err := Parse(rawURL)
+	if err != nil {
+		return "", err
+	}
+	n, err := NormalizeURL(u, opts)
+	if err != nil {
+		return "", err
+	}
+	out := n.String()
+	if !opts.RemoveEmptyFragment && u.Fragment == "" && strings.HasSuffix(rawURL, "#") {
+		out += "#"
+	}
+	return out, nil
+}
+
+// isDefaultPort reports whether port is the default port of scheme. Ports
+// are compared as numbers, so 0080 is the default port of http.
+func isDefaultPort(scheme string, port string) bool {
+	def, ok := DefaultPorts[scheme]
+	return ok && parsePort(port) == parsePort(def)
+}
+
+// NormalizeURL returns a normalized copy of u. See Normalize.
+func NormalizeURL(u *url.URL, opts NormalizeOptions) (*url.URL, error) {
+	n := *u
+	if opts.Lowercase {
+		n.Scheme = strings.ToLower(n.Scheme)
+	}
+
+	host, err := Hostname(u, opts.Puny, opts.IDNA)
+	if err != nil {
+		return nil, err
+	}
+	if opts.Lowercase {
+		// The zone ID of an IPv6 address is an interface name, which is
+		// case-sensitive.
+		if i := strings.IndexByte(host, '%'); i >= 0 && strings.Contains(host, ":") {
+			host = strings.ToLower(host[:i]) + host[i:]
+		} else {
+			host = strings.ToLower(host)
+		}
+	}
+	if strings.Contains(host, ":") {
+		host = "[" + host + "]"
+	}
+	port := u.Port()
+	if opts.RemoveDefaultPort && (port == "" || isDefaultPort(n.Scheme, port)) {
+		port = ""
+	}
+	n.Host = host
+	if port != "" || !opts.RemoveDefaultPort && strings.HasSuffix(u.Host, ":") {
+		n.Host += ":" + port
+	}
+
+	if u.User != nil {
+		// Userinfo always escapes itself so only the decoding applies.
+		user := normalizeEscapes(u.User.String(), opts)
+		n.User = parseUserinfo(user)
+	}
+
+	if n.Opaque != "" {
+		n.Opaque = normalizeEscapes(n.Opaque, opts)
+	} else {
+		path := normalizeEscapes(u.EscapedPath(), opts)
+		if opts.RemoveDotSegments {
+			path = RemoveDotSegments(path)
+		}
+		if opts.RemoveTrailingSlash && len(path) > 1 {
+			path = strings.TrimSuffix(path, "/")
+		}
+		if opts.RootPath && path == "" && n.Host != "" {
+			path = "/"
+		}
+		if err := setPath(&n, path); err != nil {
+			return nil, err
+		}
+	}
+
+	query := normalizeEscapes(u.RawQuery, opts)
+	if opts.SortParams {
+		query = sortRawQuery(query)
+	}
+	n.RawQuery = query
+	if opts.RemoveEmptyQuery {
+		n.ForceQuery = false
+	}
+
+	if u.Fragment != "" {
+		fragment := normalizeEscapes(u.EscapedFragment(), opts)
+		if n.Fragment, err = url.PathUnescape(fragment); err != nil {
+			return nil, parseError(u.String(), err)
+		}
+		n.RawFragment = fragment
+	}
+	return &n, nil
+}
+
+// RemoveDotSegments removes the . and .. segments from path as described in
+// RFC 3986 section 5.2.4.
+func RemoveDotSegments(path string) string {
+	var out []string
+	in := path
+	for in != "" {
+		switch {
+		case strings.HasPrefix(in, "../"):
+			in = in[3:]
+		case strings.HasPrefix(in, "./"):
+			in = in[2:]
+		case strings.HasPrefix(in, "/./"):
+			in = in[2:]
+		case in == "/.":
+			in = "/"
+		case strings.HasPrefix(in, "/../"):
+			in = in[3:]
+			if len(out) > 0 {
+				out = out[:len(out)-1]
+			}
+		case in == "/..":
+			in = "/"
+			if len(out) > 0 {
+				out = out[:len(out)-1]
+			}
+		case in == "." || in == "..":
+			in = ""
+		default:
+			i := strings.IndexByte(in[1:], '/')
+			if i < 0 {
+				out = append(out, in)
+				in = ""
+			} else {
+				out = append(out, in[:i+1])
+				in = in[i+1:]
+			}
+		}
+	}
+	return strings.Join(out, "")
+}
+
+// normalizeEscapes uppercases percent encodings and decodes unreserved
+// characters in s as selected by opts. Invalid escapes are left as they are.
+func normalizeEscapes(s string, opts NormalizeOptions) string {
+	if !opts.UppercaseEscapes && !opts.DecodeUnreserved || !strings.Contains(s, "%") {
+		return s
+	}
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		if s[i] != '%' || i+2 >= len(s) || !isHex(s[i+1]) || !isHex(s[i+2]) {
+			b.WriteByte(s[i])
+			continue
+		}
+		c := unhex(s[i+1])<<4 | unhex(s[i+2])
+		switch {
+		case opts.DecodeUnreserved && isUnreserved(c):
+			b.WriteByte(c)
+		case opts.UppercaseEscapes:
+			b.WriteString(strings.ToUpper(s[i : i+3]))
+		default:
+			b.WriteString(s[i : i+3])
+		}
+		i += 2
+	}
+	return b.String()
+}
+
+// sortRawQuery sorts the key=value pairs of a query string by key without
+// decoding them. Values for the same key keep their order.
+func sortRawQuery(query string) string {
+	if query == "" {
+		return query
+	}
+	pairs := strings.Split(query, "&")
+	sort.SliceStable(pairs, func(i, j int) bool {
+		ki, _ := splitPair(pairs[i])
+		kj, _ := splitPair(pairs[j])
+		return ki < kj
+	})
+	return strings.Join(pairs, "&")
+}
+
+// setPath sets the path of u from its escaped form.
+func setPath(u *url.URL, escaped string) error {
+	path, err := url.PathUnescape(escaped)
+	if err != nil {
+		return parseError(escaped, err)
+	}
+	u.Path = path
+	u.RawPath = escaped
+	return nil
+}
+
+// parseUserinfo parses an escaped user[:password].
+func parseUserinfo(s string) *url.Userinfo {
+	username, password := splitUserinfo(s)
+	if strings.Contains(s, ":") {
+		return url.UserPassword(username, password)
+	}
+	return url.User(username)
+}
+
+func splitUserinfo(s string) (string, string) {
+	p := strings.SplitN(s, ":", 2)
+	username, _ := url.PathUnescape(p[0])
+	if len(p) == 1 {
+		return username, ""
+	}
+	password, _ := url.PathUnescape(p[1])
+	return username, password
+}
+
+// isUnreserved reports whether c is an unreserved character in RFC 3986.
+func isUnreserved(c byte) bool {
+	return 'a' <= c && c <= 'z' || 'A' <= c && c <= 'Z' || '0' <= c && c <= '9' ||
+		c == '-' || c == '.' || c == '_' || c == '~'
+}
+
+func unhex(c byte) byte {
+	switch {
+	case '0' <= c && c <= '9':
+		return c - '0'
+	case 'a' <= c && c <= 'f':
+		return c - 'a' + 10
+	case 'A' <= c && c <= 'F':
+		return c - 'A' + 10
+	}
+	return 0
+}
@@ -0,0 +1,262 @@
+/*
+Copyright © 2022 Chris Morrow cmmorrow@gmail.com
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program. If not, see <http://www.gnu.org/licenses/>.
+*/
+package urlkit
+
+import (
+	"fmt"
+	"io"
+	"path"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Rules checked by Validate.
+const (
+	RuleScheme    = "scheme"
+	RuleHostAllow = "host-allow"
+	RuleHostDeny  = "host-deny"
+	RulePort      = "port"
+	RuleIPClass   = "ip-class"
+	RuleUserinfo  = "userinfo"
+	RuleMaxLength = "max-length"
+)
+
+// Policy lists the URLs that are allowed, such as the URLs a server may
+// send requests to. Each rule that is left empty allows everything, except
+// AllowUserinfo, which must be set to allow a user or password in the URL.
+type Policy struct {
+	// Schemes are the allowed schemes.
+	Schemes []string `yaml:"schemes" json:"schemes,omitempty"`
+	// AllowHosts are shell-style patterns for the allowed hosts, such as
+	// *.example.com. A host that matches none of them is not allowed.
+	AllowHosts []string `yaml:"allowHosts" json:"allowHosts,omitempty"`
+	// DenyHosts are shell-style patterns for hosts that are not allowed,
+	// even if they match AllowHosts.
+	DenyHosts []string `yaml:"denyHosts" json:"denyHosts,omitempty"`
+	// Ports are the allowed ports and port ranges, such as 443 or
+	// 8000-8999. A URL without a port uses the default port of its scheme.
+	Ports []string `yaml:"ports" json:"ports,omitempty"`
+	// DenyIPClasses are the classes of IP address hosts that are not
+	// allowed, from IPClassNames.
+	DenyIPClasses []string `yaml:"denyIPClasses" json:"denyIPClasses,omitempty"`
+	// AllowUserinfo allows a user and password in the URL.
+	AllowUserinfo bool `yaml:"allowUserinfo" json:"allowUserinfo"`
+	// MaxLength is the maximum length of the URL in bytes.
+	MaxLength int `yaml:"maxLength" json:"maxLength,omitempty"`
+}
+
+// Violation is a rule of a Policy that a URL breaks.
+type Violation struct {
+	Rule    string `json:"rule"`
+	Message string `json:"message"`
+	Value   string `json:"value,omitempty"`
+}
+
+func (v Violation) String() string {
+	return fmt.Sprintf("%s: %s", v.Rule, v.Message)
+}
+
+// ParsePolicy reads a policy in YAML, or JSON, which is also YAML. Unknown
+// keys are an error so that a misspelled rule is not silently ignored.
+func ParsePolicy(r io.Reader) (*Policy, error) {
+	var p Policy
+	decoder := yaml.NewDecoder(r)
+	decoder.KnownFields(true)
+	if err := decoder.Decode(&p); err != nil && err != io.EOF {
+		return nil, &Error{Kind: KindInvalidInput, Op: "policy", Offset: -1, Err: err}
+	}
+	if err := p.compile(); err != nil {
+		return nil, err
+	}
+	return &p, nil
+}
+
+// compile checks the rules of p and normalizes them for Validate.
+func (p *Policy) compile() error {
+	policyError := func(value string, format string, args ...interface{}) error {
+		return &Error{Kind: KindInvalidInput, Op: "policy", Input: value, Offset: -1, Err: fmt.Errorf(format, args...)}
+	}
+	for i, scheme := range p.Schemes {
+		p.Schemes[i] = strings.ToLower(strings.TrimSuffix(scheme, ":"))
+	}
+	for _, patterns := range [][]string{p.AllowHosts, p.DenyHosts} {
+		for i, pattern := range patterns {
+			pattern = strings.TrimSuffix(strings.ToLower(pattern), ".")
+			if ascii, err := whatwgDomainToASCII(pattern); err == nil {
+				pattern = ascii
+			}
+			if _, err := path.Match(pattern, ""); err != nil {
+				return policyError(pattern, "invalid host pattern: %w", err)
+			}
+			patterns[i] = pattern
+		}
+	}
+	for _, port := range p.Ports {
+		if low, high := portRange(port); low < 0 || high < 0 || low > high {
+			return policyError(port, "invalid port range, expected a port such as 443 or a range such as 8000-8999")
+		}
+	}
+	for _, class := range p.DenyIPClasses {
+		if !containsString(IPClassNames, class) {
+			return policyError(class, "unknown IP class, expected one of %s", strings.Join(IPClassNames, ", "))
+		}
+	}
+	if p.MaxLength < 0 {
+		return policyError(strconv.Itoa(p.MaxLength), "maxLength must not be negative")
+	}
+	return nil
+}
+
+// portRange returns the first and last port of a port or a range of ports
+// such as 8000-8999. Either is -1 if s is invalid.
+func portRange(s string) (int, int) {
+	low, high := s, s
+	if i := strings.IndexByte(s, '-'); i >= 0 {
+		low, high = s[:i], s[i+1:]
+	}
+	return parsePort(low), parsePort(high)
+}
+
+// parsePort returns the port in s, or -1 if s is not a port.
+func parsePort(s string) int {
+	n, err := strconv.Atoi(strings.TrimSpace(s))
+	if err != nil || n < 0 || n > 65535 {
+		return -1
+	}
+	return n
+}
+
+// Validate checks rawURL against every rule of the policy and returns the
+// rules it breaks. Hosts are not resolved, so a domain that resolves to a
+// denied IP address is not found. Hosts are mapped and IP address hosts are
+// parsed as browsers do, so ｌocalhost is localhost, and 0x7f.1 and
+// １２７.０.０.１ are the loopback address 127.0.0.1 and are matched by host
+// patterns for 127.0.0.1.
+func (p *Policy) Validate(rawURL string) ([]Violation, error) {
+	u, err := Parse(rawURL)
+	if err != nil {
+		return nil, err
+	}
+	host := u.Hostname()
+	if host != "" && !strings.Contains(host, ":") {
+		// Map the host as browsers do, so that fullwidth characters and
+		// other characters that UTS #46 maps cannot hide a denied host.
+		ascii, err := whatwgDomainToASCII(host)
+		if err != nil {
+			return nil, &Error{Kind: KindInvalidInput, Op: "parse-whatwg", Input: u.Hostname(), Offset: -1, Err: err}
+		}
+		host = ascii
+	}
+	host = strings.TrimSuffix(strings.ToLower(host), ".")
+	ip, err := ParseIPHost(host)
+	if err != nil {
+		return nil, err
+	}
+	if ip != nil {
+		host = ip.Canonical
+	}
+
+	var violations []Violation
+	if p.MaxLength > 0 && len(rawURL) > p.MaxLength {
+		violations = append(violations, Violation{
+			Rule:    RuleMaxLength,
+			Message: fmt.Sprintf("URL is %d bytes, longer than %d", len(rawURL), p.MaxLength),
+			Value:   strconv.Itoa(len(rawURL)),
+		})
+	}
+	scheme := strings.ToLower(u.Scheme)
+	if len(p.Schemes) > 0 && !containsString(p.Schemes, scheme) {
+		violations = append(violations, Violation{
+			Rule:    RuleScheme,
+			Message: fmt.Sprintf("scheme %q is not one of %s", scheme, strings.Join(p.Schemes, ", ")),
+			Value:   scheme,
+		})
+	}
+	if !p.AllowUserinfo && u.User != nil {
+		violations = append(violations, Violation{
+			Rule:    RuleUserinfo,
+			Message: "URL contains a user or password",
+			Value:   u.User.Username(),
+		})
+	}
+	if len(p.AllowHosts) > 0 && matchPatterns(p.AllowHosts, host) == "" {
+		violations = append(violations, Violation{
+			Rule:    RuleHostAllow,
+			Message: fmt.Sprintf("host %s is not in the allowed hosts", host),
+			Value:   host,
+		})
+	}
+	if pattern := matchPatterns(p.DenyHosts, host); pattern != "" {
+		violations = append(violations, Violation{
+			Rule:    RuleHostDeny,
+			Message: fmt.Sprintf("host %s is denied by %s", host, pattern),
+			Value:   host,
+		})
+	}
+	if ip != nil && containsString(p.DenyIPClasses, ip.Class) {
+		message := fmt.Sprintf("host %s is in the %s IP class", ip.Canonical, ip.Class)
+		if ip.Version == 4 && ip.Canonical != u.Hostname() {
+			message += fmt.Sprintf(", written as %s", u.Hostname())
+		}
+		violations = append(violations, Violation{Rule: RuleIPClass, Message: message, Value: ip.Class})
+	}
+	port := u.Port()
+	if port == "" {
+		port = DefaultPorts[scheme]
+	}
+	// A URL without a port and a scheme with no default port can't be
+	// checked, so it fails rather than getting around the port rule.
+	switch {
+	case len(p.Ports) == 0:
+	case port == "":
+		violations = append(violations, Violation{
+			Rule:    RulePort,
+			Message: fmt.Sprintf("scheme %s has no default port to check against %s", scheme, strings.Join(p.Ports, ", ")),
+			Value:   port,
+		})
+	case !p.allowsPort(parsePort(port)):
+		violations = append(violations, Violation{
+			Rule:    RulePort,
+			Message: fmt.Sprintf("port %s is not one of %s", port, strings.Join(p.Ports, ", ")),
+			Value:   port,
+		})
+	}
+	return violations, nil
+}
+
+// allowsPort reports whether port is in one of the port ranges of p.
+func (p *Policy) allowsPort(port int) bool {
+	for _, r := range p.Ports {
+		if low, high := portRange(r); port >= low && port <= high {
+			return true
+		}
+	}
+	return false
+}
+
+// matchPatterns returns the first of patterns that matches host, or "" if
+// none do.
+func matchPatterns(patterns []string, host string) string {
+	for _, pattern := range patterns {
+		if ok, _ := path.Match(pattern, host); ok {
+			return pattern
+		}
+	}
+	return ""
+}
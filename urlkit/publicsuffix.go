@@ -0,0 +1,202 @@
+/*
+Copyright © 2022 Chris Morrow cmmorrow@gmail.com
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program. If not, see <http://www.gnu.org/licenses/>.
+*/
+package urlkit
+
+import (
+	"bufio"
+	_ "embed"
+	"fmt"
+	"io"
+	"net"
+	"strings"
+	"sync"
+)
+
+//go:embed data/public_suffix_list.dat
+var defaultSuffixList string
+
+// Sections of the Public Suffix List.
+const (
+	SectionICANN   = "icann"
+	SectionPrivate = "private"
+)
+
+// suffixRule is a rule of the Public Suffix List.
+type suffixRule struct {
+	private bool
+}
+
+// SuffixList is a Public Suffix List. Rules are keyed by their punycode
+// form, with a *. prefix for wildcard rules and a ! prefix for exceptions.
+type SuffixList struct {
+	rules map[string]suffixRule
+	// ICANNOnly ignores the rules in the private section, so that the
+	// suffix of foo.github.io is io rather than github.io.
+	ICANNOnly bool
+}
+
+// DomainParts are the parts of a domain found with a Public Suffix List.
+type DomainParts struct {
+	// TLD is the public suffix, or effective TLD, such as co.uk.
+	TLD string `json:"tld"`
+	// RegistrableDomain is the TLD and one more label, such as example.co.uk.
+	// It is empty if the domain is a public suffix.
+	RegistrableDomain string `json:"registrableDomain"`
+	// Subdomain is the labels before the registrable domain.
+	Subdomain string   `json:"subdomain"`
+	Labels    []string `json:"labels"`
+	// Section is the section of the list the TLD's rule is in, or empty if
+	// no rule matched and the last label is used as the TLD.
+	Section string `json:"section"`
+}
+
+var (
+	defaultSuffixesOnce sync.Once
+	defaultSuffixes     *SuffixList
+)
+
+// DefaultSuffixList returns the Public Suffix List snapshot embedded in the
+// package. Use ParseSuffixList to use a newer list.
+func DefaultSuffixList() *SuffixList {
+	defaultSuffixesOnce.Do(func() {
+		list, err := ParseSuffixList(strings.NewReader(defaultSuffixList))
+		if err != nil {
+			panic(err)
+		}
+		defaultSuffixes = list
+	})
+	l := *defaultSuffixes
+	return &l
+}
+
+// ParseSuffixList reads a list in the format of public_suffix_list.dat from
+// https://publicsuffix.org/list/. Rules may be in Unicode or punycode.
+func ParseSuffixList(r io.Reader) (*SuffixList, error) {
+	list := &SuffixList{rules: make(map[string]suffixRule)}
+	private := false
+	scanner := bufio.NewScanner(r)
+	line := 0
+	for scanner.Scan() {
+		line++
+		text := strings.TrimSpace(scanner.Text())
+		switch {
+		case strings.HasPrefix(text, "// ===BEGIN PRIVATE DOMAINS==="):
+			private = true
+			continue
+		case strings.HasPrefix(text, "// ===BEGIN ICANN DOMAINS==="):
+			private = false
+			continue
+		case text == "" || strings.HasPrefix(text, "//"):
+			continue
+		}
+		// Only the first word of a line is the rule.
+		if i := strings.IndexAny(text, " \t"); i >= 0 {
+			text = text[:i]
+		}
+		prefix := ""
+		switch {
+		case strings.HasPrefix(text, "!"):
+			prefix, text = "!", text[1:]
+		case strings.HasPrefix(text, "*."):
+			prefix, text = "*.", text[2:]
+		}
+		ascii := strings.ToLower(text)
+		var err error
+		if !isASCII(ascii) {
+			ascii, err = ToASCII(ascii)
+		}
+		if err != nil || ascii == "" {
+			return nil, &Error{Kind: KindInvalidInput, Op: "psl", Input: scanner.Text(), Offset: -1,
+				Err: fmt.Errorf("invalid rule on line %d", line)}
+		}
+		list.rules[prefix+ascii] = suffixRule{private: private}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, &Error{Kind: KindIO, Op: "read", Offset: -1, Err: err}
+	}
+	return list, nil
+}
+
+// lookup returns the rule with key, ignoring private rules if ICANNOnly is
+// set.
+func (l *SuffixList) lookup(key string) (suffixRule, bool) {
+	rule, ok := l.rules[key]
+	if ok && rule.private && l.ICANNOnly {
+		return rule, false
+	}
+	return rule, ok
+}
+
+// Split splits a domain into its public suffix, registrable domain and
+// subdomain with the algorithm of https://publicsuffix.org/list/. It
+// returns nil for an empty host, an IP address or a domain with an empty
+// label. The parts are in the form
+// of the domain, Unicode or punycode.
+func (l *SuffixList) Split(domain string) *DomainParts {
+	domain = strings.TrimSuffix(domain, ".")
+	if domain == "" || strings.HasPrefix(domain, "[") || net.ParseIP(domain) != nil ||
+		strings.HasPrefix(domain, ".") || strings.Contains(domain, "..") {
+		return nil
+	}
+	labels := strings.Split(domain, ".")
+	ascii := make([]string, len(labels))
+	for i, label := range labels {
+		ascii[i] = strings.ToLower(label)
+		if a, err := ToASCII(ascii[i]); err == nil && !isASCII(ascii[i]) {
+			ascii[i] = a
+		}
+	}
+
+	// The rule that matches the most labels wins, and an exception rule
+	// always matches more labels than the wildcard it is an exception to.
+	n, section := 1, ""
+	for i := range ascii {
+		key := strings.Join(ascii[i:], ".")
+		if rule, ok := l.lookup("!" + key); ok {
+			n, section = len(labels)-i-1, sectionOf(rule)
+			break
+		}
+		if rule, ok := l.lookup(key); ok {
+			n, section = len(labels)-i, sectionOf(rule)
+			break
+		}
+		if i+1 < len(ascii) {
+			if rule, ok := l.lookup("*." + strings.Join(ascii[i+1:], ".")); ok {
+				n, section = len(labels)-i, sectionOf(rule)
+				break
+			}
+		}
+	}
+
+	parts := &DomainParts{
+		TLD:     strings.Join(labels[len(labels)-n:], "."),
+		Labels:  labels,
+		Section: section,
+	}
+	if n < len(labels) {
+		parts.RegistrableDomain = strings.Join(labels[len(labels)-n-1:], ".")
+		parts.Subdomain = strings.Join(labels[:len(labels)-n-1], ".")
+	}
+	return parts
+}
+
+func sectionOf(rule suffixRule) string {
+	if rule.private {
+		return SectionPrivate
+	}
+	return SectionICANN
+}
@@ -0,0 +1,214 @@
+/*
+Copyright © 2022 Chris Morrow cmmorrow@gmail.com
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program. If not, see <http://www.gnu.org/licenses/>.
+*/
+package urlkit
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"sort"
+	"strings"
+)
+
+// Param is a single key=value pair from a query string.
+type Param struct {
+	Key   string `json:"key"`
+	Value string `json:"value"`
+}
+
+// Query is an ordered list of query parameters. Unlike url.Values, the
+// order of the parameters is kept and a key may appear more than once.
+type Query []Param
+
+// ParseQuery decodes a query string in order. Like url.ParseQuery, a
+// parameter that cannot be decoded is skipped and the first error is returned
+// along with the rest of the parameters.
+func ParseQuery(rawQuery string) (Query, error) {
+	var q Query
+	var firstErr error
+	for rawQuery != "" {
+		pair := rawQuery
+		rawQuery = ""
+		if i := strings.IndexByte(pair, '&'); i >= 0 {
+			pair, rawQuery = pair[:i], pair[i+1:]
+		}
+		if pair == "" {
+			continue
+		}
+		if strings.Contains(pair, ";") {
+			if firstErr == nil {
+				firstErr = fmt.Errorf("invalid semicolon separator in query")
+			}
+			continue
+		}
+		rawKey, rawValue := splitPair(pair)
+		key, err := url.QueryUnescape(rawKey)
+		if err == nil {
+			var value string
+			value, err = url.QueryUnescape(rawValue)
+			if err == nil {
+				q = append(q, Param{Key: key, Value: value})
+				continue
+			}
+		}
+		if firstErr == nil {
+			firstErr = err
+		}
+	}
+	return q, firstErr
+}
+
+// Get returns the first value for key, or "" if there is none.
+func (q Query) Get(key string) string {
+	for _, p := range q {
+		if p.Key == key {
+			return p.Value
+		}
+	}
+	return ""
+}
+
+// Values returns every value for key in order.
+func (q Query) Values(key string) []string {
+	var vals []string
+	for _, p := range q {
+		if p.Key == key {
+			vals = append(vals, p.Value)
+		}
+	}
+	return vals
+}
+
+// Keys returns each distinct key in the order it first appears.
+func (q Query) Keys() []string {
+	var keys []string
+	seen := make(map[string]bool)
+	for _, p := range q {
+		if !seen[p.Key] {
+			seen[p.Key] = true
+			keys = append(keys, p.Key)
+		}
+	}
+	return keys
+}
+
+// Sorted returns a copy of q sorted by key. Values for the same key keep
+// their order, which matches url.Values.Encode.
+func (q Query) Sorted() Query {
+	sorted := make(Query, len(q))
+	copy(sorted, q)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return sorted[i].Key < sorted[j].Key
+	})
+	return sorted
+}
+
+// URLValues converts q to a url.Values. The order of the keys is lost.
+func (q Query) URLValues() url.Values {
+	values := url.Values{}
+	for _, p := range q {
+		values[p.Key] = append(values[p.Key], p.Value)
+	}
+	return values
+}
+
+// Encode encodes q in order as a query string.
+func (q Query) Encode() string {
+	var b strings.Builder
+	for i, p := range q {
+		if i > 0 {
+			b.WriteByte('&')
+		}
+		b.WriteString(url.QueryEscape(p.Key))
+		b.WriteByte('=')
+		b.WriteString(url.QueryEscape(p.Value))
+	}
+	return b.String()
+}
+
+// MarshalJSON encodes q as a JSON object with the keys in the order they
+// first appear. A key with a single value is a string, otherwise a list.
+func (q Query) MarshalJSON() ([]byte, error) {
+	var b bytes.Buffer
+	b.WriteByte('{')
+	for i, key := range q.Keys() {
+		if i > 0 {
+			b.WriteByte(',')
+		}
+		k, err := json.Marshal(key)
+		if err != nil {
+			return nil, err
+		}
+		b.Write(k)
+		b.WriteByte(':')
+		var v []byte
+		if vals := q.Values(key); len(vals) > 1 {
+			v, err = json.Marshal(vals)
+		} else {
+			v, err = json.Marshal(vals[0])
+		}
+		if err != nil {
+			return nil, err
+		}
+		b.Write(v)
+	}
+	b.WriteByte('}')
+	return b.Bytes(), nil
+}
+
+// splitPair splits a key=value pair at the first =.
+func splitPair(pair string) (string, string) {
+	p := strings.SplitN(pair, "=", 2)
+	if len(p) == 1 {
+		return p[0], ""
+	}
+	return p[0], p[1]
+}
+
+// jsonKeyOrder returns the keys of the object named field in the JSON object
+// data, in the order they are written. Like encoding/json, field is matched
+// without regard to case.
+func jsonKeyOrder(data []byte, field string) []string {
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return nil
+	}
+	for name, raw := range fields {
+		if !strings.EqualFold(name, field) {
+			continue
+		}
+		dec := json.NewDecoder(bytes.NewReader(raw))
+		if t, err := dec.Token(); err != nil || t != json.Delim('{') {
+			return nil
+		}
+		var keys []string
+		for dec.More() {
+			t, err := dec.Token()
+			if err != nil {
+				return nil
+			}
+			keys = append(keys, t.(string))
+			var skip json.RawMessage
+			if err := dec.Decode(&skip); err != nil {
+				return nil
+			}
+		}
+		return keys
+	}
+	return nil
+}
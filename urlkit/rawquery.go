@@ -0,0 +1,226 @@
+/*
+Copyright © 2022 Chris Morrow cmmorrow@gmail.com
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program. If not, see <http://www.gnu.org/licenses/>.
+*/
+package urlkit
+
+import (
+	"fmt"
+	"net/url"
+	"path"
+	"regexp"
+	"strings"
+)
+
+// RawParam is a key=value pair from a query string exactly as written.
+type RawParam struct {
+	RawKey   string
+	RawValue string
+	// HasValue is false for a parameter with no =.
+	HasValue bool
+}
+
+// RawQuery is a query string split into its parameters without decoding
+// them, so that parameters that are not edited keep their original encoding.
+type RawQuery []RawParam
+
+// KeyMatcher reports whether a decoded query parameter key matches.
+type KeyMatcher func(key string) bool
+
+// Ways of matching query parameter keys.
+const (
+	MatchExact = "exact"
+	MatchGlob  = "glob"
+	MatchRegex = "regex"
+)
+
+// MatchKey returns a KeyMatcher for pattern. The mode is MatchExact,
+// MatchGlob for shell-style patterns such as utm_* or MatchRegex. Like a glob,
+// a regular expression must match the whole key.
+func MatchKey(pattern string, mode string) (KeyMatcher, error) {
+	switch mode {
+	case MatchExact, "":
+		return func(key string) bool { return key == pattern }, nil
+	case MatchGlob:
+		if _, err := path.Match(pattern, ""); err != nil {
+			return nil, &Error{Kind: KindInvalidInput, Op: "glob", Input: pattern, Offset: -1, Err: err}
+		}
+		return func(key string) bool {
+			ok, _ := path.Match(pattern, key)
+			return ok
+		}, nil
+	case MatchRegex:
+		re, err := regexp.Compile("^(?:" + pattern + ")$")
+		if err != nil {
+			return nil, &Error{Kind: KindInvalidInput, Op: "regex", Input: pattern, Offset: -1, Err: err}
+		}
+		return re.MatchString, nil
+	}
+	return nil, &Error{Kind: KindInvalidInput, Op: "match", Input: mode, Offset: -1,
+		Err: fmt.Errorf("unknown match mode %q", mode)}
+}
+
+// SplitRawQuery splits a query string on &. Empty parameters are dropped.
+func SplitRawQuery(query string) RawQuery {
+	var q RawQuery
+	for _, pair := range strings.Split(query, "&") {
+		if pair == "" {
+			continue
+		}
+		key, value := splitPair(pair)
+		q = append(q, RawParam{RawKey: key, RawValue: value, HasValue: strings.Contains(pair, "=")})
+	}
+	return q
+}
+
+// NewRawParam encodes key and value as a RawParam.
+func NewRawParam(key string, value string) RawParam {
+	return RawParam{RawKey: url.QueryEscape(key), RawValue: url.QueryEscape(value), HasValue: true}
+}
+
+// Key returns the decoded key, or the raw key if it cannot be decoded.
+func (p RawParam) Key() string {
+	return unescapeOrRaw(p.RawKey)
+}
+
+// Value returns the decoded value, or the raw value if it cannot be decoded.
+func (p RawParam) Value() string {
+	return unescapeOrRaw(p.RawValue)
+}
+
+func (p RawParam) String() string {
+	if !p.HasValue {
+		return p.RawKey
+	}
+	return p.RawKey + "=" + p.RawValue
+}
+
+// String joins the parameters into a query string.
+func (q RawQuery) String() string {
+	pairs := make([]string, len(q))
+	for i, p := range q {
+		pairs[i] = p.String()
+	}
+	return strings.Join(pairs, "&")
+}
+
+// Query decodes the parameters.
+func (q RawQuery) Query() Query {
+	out := make(Query, len(q))
+	for i, p := range q {
+		out[i] = Param{Key: p.Key(), Value: p.Value()}
+	}
+	return out
+}
+
+// Get returns the decoded parameters with keys that match.
+func (q RawQuery) Get(match KeyMatcher) Query {
+	var out Query
+	for _, p := range q {
+		if match(p.Key()) {
+			out = append(out, Param{Key: p.Key(), Value: p.Value()})
+		}
+	}
+	return out
+}
+
+// Delete removes the parameters with keys that match.
+func (q RawQuery) Delete(match KeyMatcher) RawQuery {
+	var out RawQuery
+	for _, p := range q {
+		if !match(p.Key()) {
+			out = append(out, p)
+		}
+	}
+	return out
+}
+
+// Rename changes the key of the parameters with keys that match to key.
+func (q RawQuery) Rename(match KeyMatcher, key string) RawQuery {
+	out := make(RawQuery, len(q))
+	for i, p := range q {
+		if match(p.Key()) {
+			p.RawKey = url.QueryEscape(key)
+		}
+		out[i] = p
+	}
+	return out
+}
+
+// SetMatching sets the value of every parameter with a key that matches.
+func (q RawQuery) SetMatching(match KeyMatcher, value string) RawQuery {
+	out := make(RawQuery, len(q))
+	for i, p := range q {
+		if match(p.Key()) {
+			p.RawValue, p.HasValue = url.QueryEscape(value), true
+		}
+		out[i] = p
+	}
+	return out
+}
+
+// Set sets the value of the first parameter named key and removes any
+// others with the same key. The parameter is added to the end if there is
+// none already.
+func (q RawQuery) Set(key string, value string) RawQuery {
+	var out RawQuery
+	found := false
+	for _, p := range q {
+		if p.Key() != key {
+			out = append(out, p)
+		} else if !found {
+			found = true
+			p.RawValue, p.HasValue = url.QueryEscape(value), true
+			out = append(out, p)
+		}
+	}
+	if !found {
+		out = append(out, NewRawParam(key, value))
+	}
+	return out
+}
+
+// Add adds a parameter to the end of the query.
+func (q RawQuery) Add(key string, value string) RawQuery {
+	out := make(RawQuery, len(q), len(q)+1)
+	copy(out, q)
+	return append(out, NewRawParam(key, value))
+}
+
+func unescapeOrRaw(s string) string {
+	out, err := url.QueryUnescape(s)
+	if err != nil {
+		return s
+	}
+	return out
+}
+
+// EditQuery calls edit with the query of rawURL and returns rawURL with the
+// edited query. Every other part of the URL is left exactly as written.
+func EditQuery(rawURL string, edit func(RawQuery) (RawQuery, error)) (string, error) {
+	if _, err := Parse(rawURL); err != nil {
+		return "", err
+	}
+	r := SplitReference(rawURL)
+	q := SplitRawQuery(r.Query)
+	edited, err := edit(q)
+	if err != nil {
+		return "", err
+	}
+	// Keep a ? with no query only if the query was empty to begin with.
+	r.HasQuery = len(edited) > 0 || r.HasQuery && len(q) == 0
+	r.Query = edited.String()
+	return r.String(), nil
+}
@@ -0,0 +1,215 @@
+/*
+Copyright © 2022 Chris Morrow cmmorrow@gmail.com
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program. If not, see <http://www.gnu.org/licenses/>.
+*/
+package urlkit
+
+import (
+	"fmt"
+	"io"
+	"strings"
+)
+
+// CheckOpenRedirect is the check for parameters that redirect off-site.
+const CheckOpenRedirect = "open-redirect"
+
+// maxRedirectNesting is the number of URLs nested in parameters of other
+// URLs that FindRedirects follows.
+const maxRedirectNesting = 5
+
+// dangerousSchemes run code or embed content when a browser is redirected
+// to them.
+var dangerousSchemes = []string{"javascript", "data", "vbscript"}
+
+// TrustedHosts is a list of shell-style host patterns that parameters may
+// redirect to. A pattern also matches the subdomains of the hosts it matches.
+type TrustedHosts []string
+
+// ParseTrustedHosts reads host patterns with one pattern per line. Blank
+// lines and lines starting with # are ignored.
+func ParseTrustedHosts(r io.Reader) (TrustedHosts, error) {
+	hosts, err := readDomains(r)
+	return TrustedHosts(hosts), err
+}
+
+// Match reports whether host is trusted.
+func (t TrustedHosts) Match(host string) bool {
+	host = strings.TrimSuffix(strings.ToLower(host), ".")
+	for _, pattern := range t {
+		// matchHost matches every host with an empty pattern.
+		if pattern == "" {
+			continue
+		}
+		if matchHost(strings.TrimSuffix(pattern, "."), host) {
+			return true
+		}
+	}
+	return false
+}
+
+// FindRedirects finds query and fragment parameters of rawURL that could
+// redirect a browser away from the host of rawURL and the trusted hosts:
+// absolute and scheme-relative URLs to other hosts, paths that browsers read
+// as scheme-relative URLs because of a backslash, such as /\evil.com, and
+// javascript: and data: URLs. Values are decoded until they stop changing,
+// and the parameters of a URL to a trusted host in a value are checked too.
+// A fragment without parameters is checked as a single value.
+func FindRedirects(rawURL string, trusted TrustedHosts) ([]Finding, error) {
+	u, err := Parse(rawURL)
+	if err != nil {
+		return nil, err
+	}
+	// A relative URL has no host of its own to trust.
+	if host := u.Hostname(); host != "" {
+		trusted = append(TrustedHosts{strings.ToLower(host)}, trusted...)
+	}
+	return findRedirects(rawURL, trusted, "", 0), nil
+}
+
+// findRedirects checks the parameters of rawURL, which is nested in the
+// parameter with key path prefix.
+func findRedirects(rawURL string, trusted TrustedHosts, prefix string, depth int) []Finding {
+	r := SplitReference(rawURL)
+	var findings []Finding
+	check := func(component string, key string, raw string) {
+		if prefix != "" {
+			key = prefix + " > " + key
+		}
+		layers, _, err := DecodeLayers(raw, DecodeOptions{Component: ComponentForm, Lenient: true}, DefaultMaxDepth)
+		if err != nil {
+			return
+		}
+		// A parameter is decoded once before it is used, so the raw value
+		// is only checked if it has nothing to decode.
+		for i, value := range layers {
+			if i == 0 && len(layers) > 1 {
+				continue
+			}
+			message, nested := redirectTarget(value, trusted)
+			if message != "" {
+				if i > 1 {
+					message += fmt.Sprintf(", after decoding %d times", i)
+				}
+				findings = append(findings, Finding{
+					Check:     CheckOpenRedirect,
+					Component: component,
+					Key:       key,
+					Message:   message,
+					Value:     value,
+				})
+				return
+			}
+			if nested != "" && depth < maxRedirectNesting {
+				findings = append(findings, findRedirects(nested, trusted, key, depth+1)...)
+				return
+			}
+		}
+	}
+
+	for _, p := range SplitRawQuery(r.Query) {
+		check(ParamLabel, p.Key(), p.RawValue)
+	}
+	if strings.Contains(r.Fragment, "=") {
+		// Client-side routes such as #!/login?next=/ have a query in the
+		// fragment.
+		fragment := r.Fragment
+		if i := strings.IndexByte(fragment, '?'); i >= 0 {
+			fragment = fragment[i+1:]
+		}
+		for _, p := range SplitRawQuery(fragment) {
+			check(FragmentLabel, p.Key(), p.RawValue)
+		}
+	} else if r.Fragment != "" {
+		check(FragmentLabel, "", r.Fragment)
+	}
+	return findings
+}
+
+// redirectTarget returns why a browser redirected to value could leave the
+// trusted hosts, or "" if it stays on them. If value is a URL to a trusted
+// host, it is returned as nested.
+func redirectTarget(value string, trusted TrustedHosts) (message string, nested string) {
+	// Browsers strip leading control characters and spaces, and tabs and
+	// newlines anywhere, before parsing a URL.
+	v := strings.TrimLeft(value, "\x00\x01\x02\x03\x04\x05\x06\x07\x08\x09\x0a\x0b\x0c\x0d\x0e\x0f"+
+		"\x10\x11\x12\x13\x14\x15\x16\x17\x18\x19\x1a\x1b\x1c\x1d\x1e\x1f ")
+	v = strings.NewReplacer("\t", "", "\n", "", "\r", "").Replace(v)
+
+	scheme, rest := "", v
+	if i := strings.IndexByte(v, ':'); i > 0 && isScheme(v[:i]) {
+		scheme, rest = strings.ToLower(v[:i]), v[i+1:]
+	}
+	if containsString(dangerousSchemes, scheme) {
+		return fmt.Sprintf("%s: URL", scheme), ""
+	}
+
+	special := scheme == "" || DefaultPorts[scheme] != "" || scheme == "file"
+	slashes := 0
+	for slashes < len(rest) && (rest[slashes] == '/' || special && rest[slashes] == '\\') {
+		slashes++
+	}
+	backslash := strings.Contains(rest[:slashes], "\\")
+	switch {
+	case scheme == "" && slashes < 2:
+		// A relative reference stays on the same host.
+		return "", ""
+	case scheme != "" && !special && slashes < 2:
+		// A URI such as mailto: does not have a host.
+		return "", ""
+	}
+
+	host := rest[slashes:]
+	if i := strings.IndexAny(host, "/?#\\"); i >= 0 {
+		host = host[:i]
+	}
+	if i := strings.LastIndexByte(host, '@'); i >= 0 {
+		host = host[i+1:]
+	}
+	if i := strings.LastIndexByte(host, ':'); i >= 0 && !strings.HasSuffix(host, "]") {
+		host = host[:i]
+	}
+	host = strings.TrimSuffix(strings.ToLower(host), ".")
+	if ip, err := ParseIPHost(host); err == nil && ip != nil {
+		host = ip.Canonical
+	}
+	if host == "" || trusted.Match(host) {
+		if scheme != "" {
+			return "", v
+		}
+		return "", ""
+	}
+
+	switch {
+	case backslash:
+		return fmt.Sprintf("URL with a backslash that browsers read as a URL to untrusted host %s", host), ""
+	case scheme == "":
+		return fmt.Sprintf("scheme-relative URL to untrusted host %s", host), ""
+	}
+	return fmt.Sprintf("absolute URL to untrusted host %s", host), ""
+}
+
+// isScheme reports whether s is a valid URL scheme.
+func isScheme(s string) bool {
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case 'a' <= c && c <= 'z' || 'A' <= c && c <= 'Z':
+		case i > 0 && ('0' <= c && c <= '9' || c == '+' || c == '-' || c == '.'):
+		default:
+			return false
+		}
+	}
+	return s != ""
+}
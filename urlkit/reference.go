@@ -0,0 +1,91 @@
+/*
+Copyright © 2022 Chris Morrow cmmorrow@gmail.com
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program. If not, see <http://www.gnu.org/licenses/>.
+*/
+package urlkit
+
+import "strings"
+
+// Reference is a URI reference split into the five components of RFC 3986.
+// The components are kept exactly as written, without any decoding. The Has
+// fields distinguish an empty component from one that is not present.
+type Reference struct {
+	Scheme       string
+	Authority    string
+	Path         string
+	Query        string
+	Fragment     string
+	HasScheme    bool
+	HasAuthority bool
+	HasQuery     bool
+	HasFragment  bool
+}
+
+// SplitReference splits s into its components using the regular expression
+// in RFC 3986 Appendix B. It never fails since any string matches.
+func SplitReference(s string) Reference {
+	var r Reference
+	if i := strings.IndexByte(s, '#'); i >= 0 {
+		r.Fragment, r.HasFragment = s[i+1:], true
+		s = s[:i]
+	}
+	if i := strings.IndexByte(s, '?'); i >= 0 {
+		r.Query, r.HasQuery = s[i+1:], true
+		s = s[:i]
+	}
+	if i := strings.IndexAny(s, ":/"); i > 0 && s[i] == ':' {
+		r.Scheme, r.HasScheme = s[:i], true
+		s = s[i+1:]
+	}
+	if strings.HasPrefix(s, "//") {
+		s = s[2:]
+		i := strings.IndexByte(s, '/')
+		if i < 0 {
+			i = len(s)
+		}
+		r.Authority, r.HasAuthority = s[:i], true
+		s = s[i:]
+	}
+	r.Path = s
+	return r
+}
+
+// IsAbsolute reports whether r has a scheme.
+func (r Reference) IsAbsolute() bool {
+	return r.HasScheme
+}
+
+// String recomposes the reference as described in RFC 3986 section 5.3.
+func (r Reference) String() string {
+	var b strings.Builder
+	if r.HasScheme {
+		b.WriteString(r.Scheme)
+		b.WriteByte(':')
+	}
+	if r.HasAuthority {
+		b.WriteString("//")
+		b.WriteString(r.Authority)
+	}
+	b.WriteString(r.Path)
+	if r.HasQuery {
+		b.WriteByte('?')
+		b.WriteString(r.Query)
+	}
+	if r.HasFragment {
+		b.WriteByte('#')
+		b.WriteString(r.Fragment)
+	}
+	return b.String()
+}
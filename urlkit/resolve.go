@@ -0,0 +1,172 @@
+/*
+Copyright © 2022 Chris Morrow cmmorrow@gmail.com
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program. If not, see <http://www.gnu.org/licenses/>.
+*/
+package urlkit
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Resolve resolves the reference ref against the absolute URL base as
+// described in RFC 3986 section 5.2.
+func Resolve(base string, ref string) (string, error) {
+	b := SplitReference(base)
+	if !b.IsAbsolute() {
+		return "", &Error{Kind: KindInvalidInput, Op: "resolve", Input: base, Offset: 0, Err: fmt.Errorf("base URL is not absolute")}
+	}
+	return ResolveReference(b, SplitReference(ref)).String(), nil
+}
+
+// ResolveReference resolves r against base using the strict algorithm in
+// RFC 3986 section 5.2.2. The base must be absolute.
+func ResolveReference(base Reference, r Reference) Reference {
+	var t Reference
+	switch {
+	case r.HasScheme:
+		t = r
+		t.Path = RemoveDotSegments(r.Path)
+	case r.HasAuthority:
+		t = r
+		t.Path = RemoveDotSegments(r.Path)
+		t.Scheme, t.HasScheme = base.Scheme, base.HasScheme
+	default:
+		t.Authority, t.HasAuthority = base.Authority, base.HasAuthority
+		t.Scheme, t.HasScheme = base.Scheme, base.HasScheme
+		switch {
+		case r.Path == "":
+			t.Path = base.Path
+			if r.HasQuery {
+				t.Query, t.HasQuery = r.Query, true
+			} else {
+				t.Query, t.HasQuery = base.Query, base.HasQuery
+			}
+		case strings.HasPrefix(r.Path, "/"):
+			t.Path = RemoveDotSegments(r.Path)
+			t.Query, t.HasQuery = r.Query, r.HasQuery
+		default:
+			t.Path = RemoveDotSegments(mergePaths(base, r.Path))
+			t.Query, t.HasQuery = r.Query, r.HasQuery
+		}
+	}
+	t.Fragment, t.HasFragment = r.Fragment, r.HasFragment
+	return t
+}
+
+// Relativize returns the shortest reference that resolves against base to
+// target. It is the inverse of Resolve. Both URLs must be absolute.
+func Relativize(base string, target string) (string, error) {
+	b := SplitReference(base)
+	if !b.IsAbsolute() {
+		return "", &Error{Kind: KindInvalidInput, Op: "relativize", Input: base, Offset: 0, Err: fmt.Errorf("base URL is not absolute")}
+	}
+	t := SplitReference(target)
+	if !t.IsAbsolute() {
+		return "", &Error{Kind: KindInvalidInput, Op: "relativize", Input: target, Offset: 0, Err: fmt.Errorf("target URL is not absolute")}
+	}
+	t.Path = RemoveDotSegments(t.Path)
+	want := t.String()
+
+	for _, candidate := range relativeCandidates(b, t) {
+		if ResolveReference(b, candidate).String() == want {
+			return candidate.String(), nil
+		}
+	}
+	return want, nil
+}
+
+// relativeCandidates returns references to t relative to base, shortest
+// first. The caller checks which of them resolve to t.
+func relativeCandidates(base Reference, t Reference) []Reference {
+	if !strings.EqualFold(base.Scheme, t.Scheme) {
+		return nil
+	}
+	var candidates []Reference
+	suffix := Reference{Query: t.Query, HasQuery: t.HasQuery, Fragment: t.Fragment, HasFragment: t.HasFragment}
+
+	if base.HasAuthority == t.HasAuthority && base.Authority == t.Authority {
+		if base.Path == t.Path {
+			if base.HasQuery == t.HasQuery && base.Query == t.Query {
+				r := suffix
+				r.Query, r.HasQuery = "", false
+				candidates = append(candidates, r)
+			}
+			if t.HasQuery {
+				candidates = append(candidates, suffix)
+			}
+		}
+		if p, ok := relativePath(base, t.Path); ok {
+			r := suffix
+			r.Path = p
+			candidates = append(candidates, r)
+		}
+		if strings.HasPrefix(t.Path, "/") && !strings.HasPrefix(t.Path, "//") {
+			r := suffix
+			r.Path = t.Path
+			candidates = append(candidates, r)
+		}
+	}
+	if t.HasAuthority {
+		r := t
+		r.Scheme, r.HasScheme = "", false
+		candidates = append(candidates, r)
+	}
+
+	// Sort by length so the shortest working reference is chosen.
+	for i := 1; i < len(candidates); i++ {
+		for j := i; j > 0 && len(candidates[j].String()) < len(candidates[j-1].String()); j-- {
+			candidates[j], candidates[j-1] = candidates[j-1], candidates[j]
+		}
+	}
+	return candidates
+}
+
+// relativePath returns a relative path from the directory of base to path.
+func relativePath(base Reference, path string) (string, bool) {
+	basePath := base.Path
+	if basePath == "" && base.HasAuthority {
+		basePath = "/"
+	}
+	if !strings.HasPrefix(basePath, "/") || !strings.HasPrefix(path, "/") {
+		return "", false
+	}
+	dir := strings.Split(basePath[:strings.LastIndexByte(basePath, '/')], "/")
+	segs := strings.Split(path, "/")
+	common := 0
+	for common < len(dir) && common < len(segs)-1 && dir[common] == segs[common] {
+		common++
+	}
+	var b strings.Builder
+	for i := common; i < len(dir); i++ {
+		b.WriteString("../")
+	}
+	rest := strings.Join(segs[common:], "/")
+	if b.Len() == 0 && (rest == "" || strings.Contains(strings.SplitN(rest, "/", 2)[0], ":")) {
+		b.WriteString("./")
+	}
+	b.WriteString(rest)
+	return b.String(), true
+}
+
+// mergePaths merges a relative path with the path of base as described in
+// RFC 3986 section 5.2.3.
+func mergePaths(base Reference, path string) string {
+	if base.HasAuthority && base.Path == "" {
+		return "/" + path
+	}
+	i := strings.LastIndexByte(base.Path, '/')
+	return base.Path[:i+1] + path
+}
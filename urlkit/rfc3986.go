@@ -0,0 +1,183 @@
+/*
+Copyright © 2022 Chris Morrow cmmorrow@gmail.com
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program. If not, see <http://www.gnu.org/licenses/>.
+*/
+package urlkit
+
+import (
+	"fmt"
+	"net"
+	"net/url"
+	"strings"
+	"unicode/utf8"
+)
+
+// ParseRFC3986 parses rawURL strictly by the grammar of RFC 3986. Unlike
+// url.Parse, every character that the grammar does not allow in a
+// component is an error, including spaces, backslashes and non-ASCII
+// characters, the port must be a number and a host in brackets must be an
+// IPv6 address without a zone ID or an IPvFuture. The scheme is lowercased
+// and nothing else is changed.
+func ParseRFC3986(rawURL string) (*url.URL, error) {
+	r := SplitReference(rawURL)
+	invalid := func(offset int, format string, args ...interface{}) error {
+		return &Error{Kind: KindInvalidInput, Op: "parse-rfc3986", Input: rawURL, Offset: offset, Err: fmt.Errorf(format, args...)}
+	}
+	offset := 0
+	if r.HasScheme {
+		if i := invalidSchemeOffset(r.Scheme); i >= 0 {
+			return nil, invalid(i, "invalid character %q in scheme", runeAt(r.Scheme, i))
+		}
+		offset = len(r.Scheme) + 1
+	}
+
+	u := &url.URL{Scheme: strings.ToLower(r.Scheme)}
+	if r.HasAuthority {
+		offset += 2
+		authority := r.Authority
+		if i := strings.LastIndexByte(authority, '@'); i >= 0 {
+			userinfo := authority[:i]
+			if j := invalidRFC3986Offset(userinfo, rfcUnreserved+rfcSubDelims+":"); j >= 0 {
+				return nil, invalid(offset+j, "invalid character %q in userinfo", runeAt(userinfo, j))
+			}
+			if parts := strings.SplitN(userinfo, ":", 2); len(parts) == 2 {
+				u.User = url.UserPassword(pathUnescapeOrRaw(parts[0]), pathUnescapeOrRaw(parts[1]))
+			} else {
+				u.User = url.User(pathUnescapeOrRaw(userinfo))
+			}
+			authority = authority[i+1:]
+			offset += i + 1
+		}
+		host, port := authority, ""
+		hostEnd := strings.IndexByte(authority, ':')
+		if strings.HasPrefix(authority, "[") {
+			hostEnd = strings.IndexByte(authority, ']') + 1
+			if hostEnd == 0 {
+				return nil, invalid(offset, "missing ] in host")
+			}
+			if err := checkIPLiteral(authority[1 : hostEnd-1]); err != nil {
+				return nil, invalid(offset, "%v", err)
+			}
+			if hostEnd < len(authority) && authority[hostEnd] != ':' {
+				return nil, invalid(offset+hostEnd, "invalid character %q after host", runeAt(authority, hostEnd))
+			}
+		}
+		if hostEnd >= 0 && hostEnd < len(authority) {
+			host, port = authority[:hostEnd], authority[hostEnd+1:]
+			if i := strings.IndexFunc(port, func(r rune) bool { return r < '0' || r > '9' }); i >= 0 {
+				return nil, invalid(offset+hostEnd+1+i, "invalid character %q in port", runeAt(port, i))
+			}
+		}
+		if !strings.HasPrefix(host, "[") {
+			if i := invalidRFC3986Offset(host, rfcUnreserved+rfcSubDelims); i >= 0 {
+				return nil, invalid(offset+i, "invalid character %q in host", runeAt(host, i))
+			}
+		}
+		u.Host = r.Authority[len(r.Authority)-len(authority):]
+		offset += len(authority)
+	}
+
+	switch {
+	case r.HasAuthority && r.Path != "" && !strings.HasPrefix(r.Path, "/"):
+		return nil, invalid(offset, "path after an authority must start with /")
+	case !r.HasScheme && !r.HasAuthority:
+		// The first segment of a relative path cannot contain a colon, as
+		// it would be read as a scheme.
+		first := r.Path
+		if i := strings.IndexByte(first, '/'); i >= 0 {
+			first = first[:i]
+		}
+		if i := strings.IndexByte(first, ':'); i >= 0 {
+			return nil, invalid(offset+i, "colon in the first segment of a relative path")
+		}
+	}
+	if i := invalidRFC3986Offset(r.Path, rfcPchar+"/"); i >= 0 {
+		return nil, invalid(offset+i, "invalid character %q in path", runeAt(r.Path, i))
+	}
+	if r.HasScheme && !r.HasAuthority && r.Path != "" && !strings.HasPrefix(r.Path, "/") {
+		u.Opaque = r.Path
+	} else {
+		setEscapedPath(u, r.Path)
+	}
+	offset += len(r.Path)
+	if r.HasQuery {
+		if i := invalidRFC3986Offset(r.Query, rfcPchar+"/?"); i >= 0 {
+			return nil, invalid(offset+1+i, "invalid character %q in query", runeAt(r.Query, i))
+		}
+		u.RawQuery, u.ForceQuery = r.Query, r.Query == ""
+		offset += len(r.Query) + 1
+	}
+	if r.HasFragment {
+		if i := invalidRFC3986Offset(r.Fragment, rfcPchar+"/?"); i >= 0 {
+			return nil, invalid(offset+1+i, "invalid character %q in fragment", runeAt(r.Fragment, i))
+		}
+		u.Fragment, u.RawFragment = pathUnescapeOrRaw(r.Fragment), r.Fragment
+	}
+	return u, nil
+}
+
+// runeAt returns the character that starts at offset i of s.
+func runeAt(s string, i int) rune {
+	r, _ := utf8.DecodeRuneInString(s[i:])
+	return r
+}
+
+// invalidSchemeOffset returns the offset of the first character of scheme
+// that RFC 3986 does not allow, or -1.
+func invalidSchemeOffset(scheme string) int {
+	for i := 0; i < len(scheme); i++ {
+		c := scheme[i]
+		if !isASCIIAlpha(rune(c)) && (i == 0 || !(c >= '0' && c <= '9' || c == '+' || c == '-' || c == '.')) {
+			return i
+		}
+	}
+	return -1
+}
+
+// invalidRFC3986Offset returns the offset of the first byte of s that is
+// neither in allowed nor part of a percent encoding, or -1.
+func invalidRFC3986Offset(s string, allowed string) int {
+	set := newSet(allowed)
+	for i := 0; i < len(s); i++ {
+		switch {
+		case s[i] == '%' && i+2 < len(s) && isHex(s[i+1]) && isHex(s[i+2]):
+			i += 2
+		case !set.Allows(s[i]):
+			return i
+		}
+	}
+	return -1
+}
+
+// checkIPLiteral checks the address in the brackets of a host, which is an
+// IPv6 address or an IPvFuture such as v1.fe80::a+en1.
+func checkIPLiteral(addr string) error {
+	if len(addr) > 0 && (addr[0] == 'v' || addr[0] == 'V') {
+		dot := strings.IndexByte(addr, '.')
+		if dot < 0 {
+			return fmt.Errorf("invalid IPvFuture address %q", addr)
+		}
+		version, rest := addr[1:dot], addr[dot+1:]
+		if version == "" || strings.Trim(version, "0123456789abcdefABCDEF") != "" || rest == "" ||
+			invalidRFC3986Offset(rest, rfcUnreserved+rfcSubDelims+":") >= 0 || strings.Contains(rest, "%") {
+			return fmt.Errorf("invalid IPvFuture address %q", addr)
+		}
+		return nil
+	}
+	if net.ParseIP(addr) == nil || !strings.Contains(addr, ":") {
+		return fmt.Errorf("invalid IPv6 address %q", addr)
+	}
+	return nil
+}
@@ -0,0 +1,669 @@
+/*
+Copyright © 2022 Chris Morrow cmmorrow@gmail.com
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program. If not, see <http://www.gnu.org/licenses/>.
+*/
+package urlkit
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"regexp"
+	"strings"
+)
+
+// rfcGenDelims are the gen-delims characters of RFC 3986.
+const rfcGenDelims = ":/?#[]@"
+
+var (
+	templateUnreservedSet = newSet(rfcUnreserved)
+	templateReservedSet   = newSet(rfcUnreserved + rfcGenDelims + rfcSubDelims)
+)
+
+// maxTemplatePrefix is the largest prefix length RFC 6570 allows.
+const maxTemplatePrefix = 9999
+
+// templateOperator is how the variables of an expression are expanded, as
+// listed in appendix A of RFC 6570.
+type templateOperator struct {
+	first    string
+	sep      string
+	named    bool
+	ifEmpty  string
+	reserved bool
+}
+
+var templateOperators = map[byte]templateOperator{
+	0:   {first: "", sep: ","},
+	'+': {first: "", sep: ",", reserved: true},
+	'.': {first: ".", sep: "."},
+	'/': {first: "/", sep: "/"},
+	';': {first: ";", sep: ";", named: true},
+	'?': {first: "?", sep: "&", named: true, ifEmpty: "="},
+	'&': {first: "&", sep: "&", named: true, ifEmpty: "="},
+	'#': {first: "#", sep: ",", reserved: true},
+}
+
+// Template is a URI Template as defined by RFC 6570, such as
+// /users/{id}/repos{?page,per_page}.
+type Template struct {
+	raw   string
+	parts []templatePart
+	// re matches the expansions of the template, with a group for each
+	// expression.
+	re *regexp.Regexp
+}
+
+// templatePart is either a literal or an expression of a Template.
+type templatePart struct {
+	literal string
+	expr    *templateExpr
+}
+
+// templateExpr is an expression such as {?page,per_page}.
+type templateExpr struct {
+	op     byte
+	vars   []templateVar
+	offset int
+}
+
+// templateVar is a variable of an expression with its modifier.
+type templateVar struct {
+	name    string
+	explode bool
+	// prefix is the length of a prefix modifier such as {var:3}, or 0.
+	prefix int
+}
+
+// TemplateVars are the values of the variables of a Template. A value is a
+// string, a list of strings as a []string, or an associative array as a
+// Query so that the order of its keys is kept. A variable that is missing,
+// nil, or an empty list or associative array is undefined.
+type TemplateVars map[string]interface{}
+
+// ParseTemplate parses a URI Template. Every level of RFC 6570 is supported.
+func ParseTemplate(s string) (*Template, error) {
+	templateError := func(offset int, format string, args ...interface{}) error {
+		return &Error{Kind: KindInvalidInput, Op: "template", Input: s, Offset: offset, Err: fmt.Errorf(format, args...)}
+	}
+	t := &Template{raw: s}
+	for i := 0; i < len(s); {
+		start := strings.IndexByte(s[i:], '{')
+		if end := strings.IndexByte(s[i:], '}'); end >= 0 && (start < 0 || end < start) {
+			return nil, templateError(i+end, "unexpected }")
+		}
+		if start < 0 {
+			t.parts = append(t.parts, templatePart{literal: s[i:]})
+			break
+		}
+		if start > 0 {
+			t.parts = append(t.parts, templatePart{literal: s[i : i+start]})
+		}
+		start += i
+		end := strings.IndexByte(s[start:], '}')
+		if end < 0 {
+			return nil, templateError(start, "unclosed expression")
+		}
+		end += start
+		expr, err := parseTemplateExpr(s[start+1:end], start)
+		if err != nil {
+			return nil, &Error{Kind: KindInvalidInput, Op: "template", Input: s, Offset: expr.offset, Err: err}
+		}
+		t.parts = append(t.parts, templatePart{expr: expr})
+		i = end + 1
+	}
+	t.re = regexp.MustCompile(t.pattern())
+	return t, nil
+}
+
+// parseTemplateExpr parses the inside of an expression that starts at
+// offset. If there is an error, the offset of the problem is returned in
+// the expression.
+func parseTemplateExpr(s string, offset int) (*templateExpr, error) {
+	expr := &templateExpr{offset: offset}
+	i := 0
+	if s != "" {
+		switch c := s[0]; {
+		case strings.IndexByte("+#./;?&", c) >= 0:
+			expr.op = c
+			i++
+		case strings.IndexByte("=,!@|", c) >= 0:
+			expr.offset = offset + 1
+			return expr, fmt.Errorf("operator %q is reserved", c)
+		}
+	}
+	for _, spec := range strings.Split(s[i:], ",") {
+		v := templateVar{name: spec}
+		if strings.HasSuffix(spec, "*") {
+			v.name, v.explode = spec[:len(spec)-1], true
+		} else if j := strings.IndexByte(spec, ':'); j >= 0 {
+			v.name = spec[:j]
+			n := 0
+			for _, c := range []byte(spec[j+1:]) {
+				if c < '0' || c > '9' || n > maxTemplatePrefix {
+					n = -1
+					break
+				}
+				n = n*10 + int(c-'0')
+			}
+			if n < 1 || n > maxTemplatePrefix || spec[j+1] == '0' {
+				expr.offset = offset + 1 + i + j
+				return expr, fmt.Errorf("invalid prefix %q, expected a length from 1 to %d", spec[j:], maxTemplatePrefix)
+			}
+			v.prefix = n
+		}
+		if !isTemplateVarName(v.name) {
+			expr.offset = offset + 1 + i
+			return expr, fmt.Errorf("invalid variable name %q", v.name)
+		}
+		expr.vars = append(expr.vars, v)
+		i += len(spec) + 1
+	}
+	return expr, nil
+}
+
+// isTemplateVarName reports whether s is a varname of RFC 6570: letters,
+// digits, _ and percent encodings, in parts separated by single dots.
+func isTemplateVarName(s string) bool {
+	if s == "" || s[0] == '.' || s[len(s)-1] == '.' || strings.Contains(s, "..") {
+		return false
+	}
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case 'a' <= c && c <= 'z' || 'A' <= c && c <= 'Z' || '0' <= c && c <= '9' || c == '_' || c == '.':
+		case c == '%' && i+2 < len(s) && isHex(s[i+1]) && isHex(s[i+2]):
+			i += 2
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+// String returns the template as it was parsed.
+func (t *Template) String() string {
+	return t.raw
+}
+
+// Variables returns the names of the variables of the template in the
+// order they first appear.
+func (t *Template) Variables() []string {
+	var names []string
+	for _, part := range t.parts {
+		if part.expr == nil {
+			continue
+		}
+		for _, v := range part.expr.vars {
+			if !containsString(names, v.name) {
+				names = append(names, v.name)
+			}
+		}
+	}
+	return names
+}
+
+// Expand expands the template with vars following RFC 6570. A prefix
+// modifier on a list or associative array is an error.
+func (t *Template) Expand(vars TemplateVars) (string, error) {
+	var b strings.Builder
+	for _, part := range t.parts {
+		if part.expr == nil {
+			b.WriteString(encodeTemplate(part.literal, true))
+			continue
+		}
+		if err := part.expr.expand(&b, vars); err != nil {
+			return "", &Error{Kind: KindInvalidInput, Op: "expand", Input: t.raw, Offset: part.expr.offset, Err: err}
+		}
+	}
+	return b.String(), nil
+}
+
+// expand writes the expansion of the expression to b.
+func (expr *templateExpr) expand(b *strings.Builder, vars TemplateVars) error {
+	op := templateOperators[expr.op]
+	first := true
+	write := func(s string) {
+		if first {
+			b.WriteString(op.first)
+			first = false
+		} else {
+			b.WriteString(op.sep)
+		}
+		b.WriteString(s)
+	}
+	// named returns name=value, or the name alone for an empty value where
+	// the operator does not add an =.
+	named := func(name string, value string) string {
+		if value == "" {
+			return name + op.ifEmpty
+		}
+		return name + "=" + value
+	}
+	encode := func(s string) string {
+		return encodeTemplate(s, op.reserved)
+	}
+
+	for _, v := range expr.vars {
+		switch value := vars[v.name].(type) {
+		case nil:
+		case string:
+			if v.prefix > 0 {
+				value = runePrefix(value, v.prefix)
+			}
+			if op.named {
+				write(named(v.name, encode(value)))
+			} else {
+				write(encode(value))
+			}
+		case []string:
+			if len(value) == 0 {
+				continue
+			}
+			if v.prefix > 0 {
+				return fmt.Errorf("prefix modifier on list variable %q", v.name)
+			}
+			items := make([]string, len(value))
+			for i, item := range value {
+				items[i] = encode(item)
+				if v.explode && op.named {
+					items[i] = named(v.name, items[i])
+				}
+			}
+			switch {
+			case v.explode:
+				write(strings.Join(items, op.sep))
+			case op.named:
+				write(named(v.name, strings.Join(items, ",")))
+			default:
+				write(strings.Join(items, ","))
+			}
+		case Query:
+			if len(value) == 0 {
+				continue
+			}
+			if v.prefix > 0 {
+				return fmt.Errorf("prefix modifier on associative array variable %q", v.name)
+			}
+			var items []string
+			for _, p := range value {
+				if v.explode {
+					items = append(items, encode(p.Key)+"="+encode(p.Value))
+					if op.named && p.Value == "" {
+						items[len(items)-1] = named(encode(p.Key), "")
+					}
+				} else {
+					items = append(items, encode(p.Key), encode(p.Value))
+				}
+			}
+			switch {
+			case v.explode:
+				write(strings.Join(items, op.sep))
+			case op.named:
+				write(named(v.name, strings.Join(items, ",")))
+			default:
+				write(strings.Join(items, ","))
+			}
+		default:
+			return fmt.Errorf("variable %q has unsupported type %T", v.name, value)
+		}
+	}
+	return nil
+}
+
+// encodeTemplate percent encodes s for an expansion. Unreserved characters
+// are always allowed. For reserved expansion, reserved characters and
+// percent encodings are allowed too.
+func encodeTemplate(s string, reserved bool) string {
+	if !reserved {
+		return EncodeComponent(s, templateUnreservedSet)
+	}
+	var b strings.Builder
+	for i := 0; i < len(s); {
+		j := i
+		for j < len(s) && !(s[j] == '%' && j+2 < len(s) && isHex(s[j+1]) && isHex(s[j+2])) {
+			j++
+		}
+		b.WriteString(EncodeComponent(s[i:j], templateReservedSet))
+		if j < len(s) {
+			b.WriteString(s[j : j+3])
+			j += 3
+		}
+		i = j
+	}
+	return b.String()
+}
+
+// runePrefix returns the first n characters of s.
+func runePrefix(s string, n int) string {
+	for i := range s {
+		if n == 0 {
+			return s[:i]
+		}
+		n--
+	}
+	return s
+}
+
+// The characters an expansion can contain, as regular expression atoms.
+const (
+	matchUnreserved = `[A-Za-z0-9\-._~]|%[0-9A-Fa-f]{2}`
+	matchReserved   = `[A-Za-z0-9\-._~:/?#\[\]@!$&'()*+,;=]|%[0-9A-Fa-f]{2}`
+)
+
+// pattern returns a regular expression that matches the expansions of the
+// template.
+func (t *Template) pattern() string {
+	var b strings.Builder
+	b.WriteString("^")
+	for _, part := range t.parts {
+		if part.expr == nil {
+			b.WriteString(regexp.QuoteMeta(encodeTemplate(part.literal, true)))
+			continue
+		}
+		op := templateOperators[part.expr.op]
+		switch {
+		case op.reserved:
+			b.WriteString("((?:" + regexp.QuoteMeta(op.first) + "(?:" + matchReserved + ")*)?)")
+		case part.expr.op == 0:
+			b.WriteString("((?:" + matchUnreserved + "|[,=])*)")
+		default:
+			item := "(?:" + matchUnreserved + "|[,=])*"
+			if op.sep != op.first {
+				item = "(?:" + matchUnreserved + "|[,=" + regexp.QuoteMeta(op.sep) + "])*"
+			}
+			b.WriteString("((?:" + regexp.QuoteMeta(op.first) + item + ")*)")
+		}
+	}
+	b.WriteString("$")
+	return b.String()
+}
+
+// Match extracts the values of the variables of the template from s, which
+// is an expansion of the template. It is the inverse of Expand, as far as
+// an expansion can be inverted: a value with a comma is a list, a value of
+// exploded name=value pairs is an associative array, and an undefined
+// variable is missing. Parameters of a form-style query that the template
+// does not name are only allowed if it has an exploded variable to hold
+// them, as in {?page,rest*}. If the template has no scheme or authority,
+// they are ignored in s, so that /users/{id} matches a complete URL.
+func (t *Template) Match(s string) (TemplateVars, bool) {
+	groups := t.re.FindStringSubmatch(s)
+	if groups == nil {
+		r := SplitReference(t.raw)
+		if r.HasScheme || r.HasAuthority {
+			return nil, false
+		}
+		if r = SplitReference(s); !r.HasScheme && !r.HasAuthority {
+			return nil, false
+		}
+		rest := s
+		if r.HasScheme {
+			rest = rest[len(r.Scheme)+1:]
+		}
+		if r.HasAuthority {
+			rest = rest[2+len(r.Authority):]
+		}
+		if groups = t.re.FindStringSubmatch(rest); groups == nil {
+			return nil, false
+		}
+	}
+	m := templateMatch{vars: TemplateVars{}, prefixed: map[string]bool{}}
+	i := 1
+	for _, part := range t.parts {
+		if part.expr == nil {
+			continue
+		}
+		if !m.expr(part.expr, groups[i]) {
+			return nil, false
+		}
+		i++
+	}
+	return m.vars, true
+}
+
+// templateMatch holds the variables found by Match.
+type templateMatch struct {
+	vars TemplateVars
+	// prefixed are the variables that only have a value from a prefix
+	// modifier, which is replaced by a complete value.
+	prefixed map[string]bool
+}
+
+// expr extracts the variables of expr from its expansion s.
+func (m *templateMatch) expr(expr *templateExpr, s string) bool {
+	op := templateOperators[expr.op]
+	if s == "" {
+		return true
+	}
+	items := strings.Split(strings.TrimPrefix(s, op.first), op.sep)
+	if !op.named {
+		// Each variable takes one item, except that an exploded variable
+		// or the last variable takes the items the others do not need.
+		for i, v := range expr.vars {
+			if len(items) == 0 {
+				break
+			}
+			n := 1
+			if v.explode || i == len(expr.vars)-1 {
+				n = len(items) - (len(expr.vars) - 1 - i)
+			}
+			if n < 1 {
+				n = 1
+			}
+			m.set(v, templateValue(items[:n], v.explode, op))
+			items = items[n:]
+		}
+		return true
+	}
+
+	var rest *templateVar
+	for i, v := range expr.vars {
+		if v.explode {
+			rest = &expr.vars[i]
+		}
+	}
+	for _, item := range items {
+		if item == "" {
+			continue
+		}
+		name, value := splitPair(item)
+		v, ok := expr.variable(name)
+		switch {
+		case ok && v.explode:
+			m.add(v.name, unescapeTemplate(value))
+		case ok:
+			m.set(v, templateValue([]string{value}, false, op))
+		case rest != nil:
+			pairs, _ := m.vars[rest.name].(Query)
+			m.vars[rest.name] = append(pairs, Param{Key: unescapeTemplate(name), Value: unescapeTemplate(value)})
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+// variable returns the variable of expr named name.
+func (expr *templateExpr) variable(name string) (templateVar, bool) {
+	for _, v := range expr.vars {
+		if v.name == name {
+			return v, true
+		}
+	}
+	return templateVar{}, false
+}
+
+// set sets the value of v unless it is a prefix of a value that is
+// already known.
+func (m *templateMatch) set(v templateVar, value interface{}) {
+	_, known := m.vars[v.name]
+	if v.prefix > 0 {
+		if !known {
+			m.vars[v.name] = value
+			m.prefixed[v.name] = true
+		}
+		return
+	}
+	if !known || m.prefixed[v.name] {
+		m.vars[v.name] = value
+		delete(m.prefixed, v.name)
+	}
+}
+
+// add adds value to the list variable name.
+func (m *templateMatch) add(name string, value string) {
+	switch current := m.vars[name].(type) {
+	case string:
+		m.vars[name] = []string{current, value}
+	case []string:
+		m.vars[name] = append(current, value)
+	default:
+		m.vars[name] = value
+	}
+}
+
+// templateValue converts the items of an expansion to a value. Exploded
+// name=value pairs are an associative array, and several items, or an item
+// with commas from an operator that does not separate items with commas,
+// are a list.
+func templateValue(items []string, explode bool, op templateOperator) interface{} {
+	if explode && allPairs(items) {
+		var pairs Query
+		for _, item := range items {
+			key, value := splitPair(item)
+			pairs = append(pairs, Param{Key: unescapeTemplate(key), Value: unescapeTemplate(value)})
+		}
+		return pairs
+	}
+	if len(items) == 1 && !op.reserved && op.sep != "," && strings.Contains(items[0], ",") {
+		items = strings.Split(items[0], ",")
+	}
+	if len(items) == 1 || op.reserved && !explode {
+		return unescapeTemplate(strings.Join(items, ","))
+	}
+	list := make([]string, len(items))
+	for i, item := range items {
+		list[i] = unescapeTemplate(item)
+	}
+	return list
+}
+
+// allPairs reports whether every item is a name=value pair.
+func allPairs(items []string) bool {
+	for _, item := range items {
+		if !strings.Contains(item, "=") {
+			return false
+		}
+	}
+	return len(items) > 0
+}
+
+// unescapeTemplate decodes the percent encodings of an expanded value.
+func unescapeTemplate(s string) string {
+	decoded, err := DecodeComponent(s, DecodeOptions{Component: ComponentPath, Lenient: true})
+	if err != nil {
+		return s
+	}
+	return decoded
+}
+
+// ParseTemplateVars reads template variables from a JSON object. Strings,
+// numbers and booleans are string values, arrays are lists and objects are
+// associative arrays. null is undefined.
+func ParseTemplateVars(data []byte) (TemplateVars, error) {
+	varsError := func(err error) error {
+		return &Error{Kind: KindJSON, Op: "template-vars", Input: string(data), Offset: -1, Err: err}
+	}
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	if t, err := dec.Token(); err != nil || t != json.Delim('{') {
+		return nil, varsError(fmt.Errorf("expected a JSON object"))
+	}
+	vars := TemplateVars{}
+	for dec.More() {
+		t, err := dec.Token()
+		if err != nil {
+			return nil, varsError(err)
+		}
+		name := t.(string)
+		if t, err = dec.Token(); err != nil {
+			return nil, varsError(err)
+		}
+		switch t {
+		case json.Delim('['):
+			list := []string{}
+			for dec.More() {
+				item, err := templateScalar(dec)
+				if err != nil {
+					return nil, varsError(fmt.Errorf("%s: %w", name, err))
+				}
+				list = append(list, item)
+			}
+			dec.Token()
+			vars[name] = list
+		case json.Delim('{'):
+			pairs := Query{}
+			for dec.More() {
+				key, err := dec.Token()
+				if err != nil {
+					return nil, varsError(err)
+				}
+				value, err := templateScalar(dec)
+				if err != nil {
+					return nil, varsError(fmt.Errorf("%s: %w", name, err))
+				}
+				pairs = append(pairs, Param{Key: key.(string), Value: value})
+			}
+			dec.Token()
+			vars[name] = pairs
+		case nil:
+			vars[name] = nil
+		default:
+			vars[name] = scalarString(t)
+		}
+	}
+	if _, err := dec.Token(); err != nil {
+		return nil, varsError(err)
+	}
+	if _, err := dec.Token(); err != io.EOF {
+		return nil, varsError(fmt.Errorf("unexpected data after the JSON object"))
+	}
+	return vars, nil
+}
+
+// templateScalar reads a string, number or boolean from dec.
+func templateScalar(dec *json.Decoder) (string, error) {
+	t, err := dec.Token()
+	if err != nil {
+		return "", err
+	}
+	if _, ok := t.(json.Delim); ok || t == nil {
+		return "", fmt.Errorf("expected a string, number or boolean")
+	}
+	return scalarString(t), nil
+}
+
+// scalarString formats a JSON string, number or boolean token.
+func scalarString(t json.Token) string {
+	switch v := t.(type) {
+	case string:
+		return v
+	case json.Number:
+		return v.String()
+	}
+	return fmt.Sprint(t)
+}
@@ -0,0 +1,190 @@
+/*
+Copyright © 2022 Chris Morrow cmmorrow@gmail.com
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program. If not, see <http://www.gnu.org/licenses/>.
+*/
+
+// Package urlkit parses, builds, encodes and decodes URLs and URIs.
+//
+// It is the library behind the url command-line tool. Every function returns
+// an error instead of exiting so it can be used from other Go programs.
+package urlkit
+
+import (
+	"encoding/json"
+	"net/url"
+	"sort"
+	"strings"
+)
+
+// URI holds the components used to build a URI or URL.
+type URI struct {
+	Scheme    string
+	UriPath   string
+	User      string
+	Host      string
+	Port      string
+	Path      string
+	Fragment  string
+	Query     string
+	RawParams []string
+	Params    map[string]interface{}
+	// SortParams sorts the query parameters by key when building the URL.
+	SortParams bool `json:"-"`
+
+	// paramOrder is the order of the keys of Params when read from JSON.
+	paramOrder []string
+}
+
+// FromJSON reads a URI from a JSON object with the same keys as the output
+// of Components.JSON. The order of the params is kept.
+func FromJSON(data []byte) (URI, error) {
+	var uri = URI{}
+	err := json.Unmarshal(data, &uri)
+	if err != nil {
+		return uri, jsonError("read-json", string(data), err)
+	}
+	uri.paramOrder = jsonKeyOrder(data, ParamsLabel)
+	return uri, nil
+}
+
+// AsURL builds a url.URL from the URI components.
+func (u *URI) AsURL() url.URL {
+	var output = url.URL{
+		Scheme:   u.Scheme,
+		Opaque:   u.UriPath,
+		Host:     u.BuildHostname(),
+		Path:     u.Path,
+		Fragment: u.Fragment,
+	}
+	if u.User != "" {
+		output.User = u.SetUser()
+	}
+	if u.Query != "" {
+		output.RawQuery = u.Query
+	} else if u.SortParams {
+		output.RawQuery = u.BuildQuery().Sorted().Encode()
+	} else {
+		output.RawQuery = u.BuildQuery().Encode()
+	}
+	return output
+}
+
+// String returns the URI as a string.
+func (u *URI) String() string {
+	out := u.AsURL()
+	return out.String()
+}
+
+// SetUser splits User into a username and optional password.
+func (u *URI) SetUser() *url.Userinfo {
+	var username, password string
+	if u.User != "" {
+		user := strings.Split(u.User, ":")
+		if len(user) == 1 {
+			user = append(user, "")
+		}
+		username, password = user[0], user[1]
+	}
+	if password != "" {
+		return url.UserPassword(username, password)
+	} else {
+		return url.User(username)
+	}
+}
+
+// BuildHostname joins Host and Port. A port in Port replaces any port in Host.
+func (u *URI) BuildHostname() string {
+	if u.Host == "" {
+		return ""
+	}
+	if u.Port != "" {
+		splitHost := strings.SplitN(u.Host, ":", 2)
+		return splitHost[0] + ":" + u.Port
+	} else {
+		return u.Host
+	}
+}
+
+// BuildQuery returns the query parameters from Params, or RawParams if
+// Params is not set, in order. RawParams are kept in the order given. Params
+// read with FromJSON are in the order of the JSON object, otherwise they are
+// sorted by key since the order of a map is random.
+func (u *URI) BuildQuery() Query {
+	var q Query
+	switch {
+	case u.Params != nil:
+		keys := u.paramOrder
+		if len(keys) != len(u.Params) {
+			keys = make([]string, 0, len(u.Params))
+			for key := range u.Params {
+				keys = append(keys, key)
+			}
+			sort.Strings(keys)
+		}
+		for _, key := range keys {
+			switch v := u.Params[key].(type) {
+			case string:
+				q = append(q, Param{Key: key, Value: v})
+			case []interface{}:
+				for i := range v {
+					if s, ok := v[i].(string); ok {
+						q = append(q, Param{Key: key, Value: s})
+					}
+				}
+			}
+		}
+	case u.RawParams != nil:
+		for i := range u.RawParams {
+			key, value := splitPair(u.RawParams[i])
+			q = append(q, Param{Key: key, Value: value})
+		}
+	}
+	return q
+}
+
+// BuildValues returns the query parameters from Params, or RawParams if
+// Params is not set.
+func (u *URI) BuildValues() url.Values {
+	values := url.Values{}
+	switch {
+	case u.Params != nil:
+
+		for key, val := range u.Params {
+			switch v := val.(type) {
+			case string:
+				values[key] = []string{v}
+			case []interface{}:
+				var vv []string
+				for i := range v {
+					if s, ok := v[i].(string); ok {
+						vv = append(vv, s)
+					}
+				}
+				values[key] = vv
+			}
+		}
+		return values
+	case u.RawParams != nil:
+		for i := range u.RawParams {
+			p := strings.SplitN(u.RawParams[i], "=", 2)
+			if len(p) == 1 {
+				p = append(p, "")
+			}
+			values[p[0]] = append(values[p[0]], p[1])
+		}
+	}
+
+	return values
+}
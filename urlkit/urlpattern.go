@@ -0,0 +1,1033 @@
+/*
+Copyright © 2022 Chris Morrow cmmorrow@gmail.com
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program. If not, see <http://www.gnu.org/licenses/>.
+*/
+package urlkit
+
+import (
+	"fmt"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// The components of a URLPattern, in order.
+const (
+	PatternProtocol = "protocol"
+	PatternUsername = "username"
+	PatternPassword = "password"
+	PatternHostname = "hostname"
+	PatternPort     = "port"
+	PatternPathname = "pathname"
+	PatternSearch   = "search"
+	PatternHash     = "hash"
+)
+
+// PatternComponents lists the components of a URLPattern in order.
+var PatternComponents = []string{PatternProtocol, PatternUsername, PatternPassword, PatternHostname,
+	PatternPort, PatternPathname, PatternSearch, PatternHash}
+
+// specialSchemes are the special schemes of the WHATWG URL Standard.
+var specialSchemes = []string{"ftp", "file", "http", "https", "ws", "wss"}
+
+// URLPattern is a pattern for URLs as defined by the WHATWG URLPattern
+// Standard, such as https://*.example.com/books/:id(\d+). Each component of
+// a URL is matched by a pattern of its own.
+type URLPattern struct {
+	components map[string]*patternComponent
+}
+
+// patternComponent is the compiled pattern of one component.
+type patternComponent struct {
+	pattern string
+	re      *regexp.Regexp
+	names   []string
+}
+
+// URLPatternResult is the result of matching a URL against a URLPattern.
+type URLPatternResult struct {
+	Inputs   []string                  `json:"inputs"`
+	Protocol URLPatternComponentResult `json:"protocol"`
+	Username URLPatternComponentResult `json:"username"`
+	Password URLPatternComponentResult `json:"password"`
+	Hostname URLPatternComponentResult `json:"hostname"`
+	Port     URLPatternComponentResult `json:"port"`
+	Pathname URLPatternComponentResult `json:"pathname"`
+	Search   URLPatternComponentResult `json:"search"`
+	Hash     URLPatternComponentResult `json:"hash"`
+}
+
+// URLPatternComponentResult is the input and named groups of a component.
+// A group that did not take part in the match is nil.
+type URLPatternComponentResult struct {
+	Input  string             `json:"input"`
+	Groups map[string]*string `json:"groups"`
+}
+
+// URLPatternOptions change how a URLPattern is compiled.
+type URLPatternOptions struct {
+	// BaseURL is the URL that a relative pattern, such as /books/:id, is
+	// relative to.
+	BaseURL string
+	// IgnoreCase matches letters without regard to case.
+	IgnoreCase bool
+}
+
+// ParseURLPattern compiles a URLPattern from a pattern string. Components
+// that the pattern does not give are *, which matches anything, unless they
+// are taken from opts.BaseURL.
+func ParseURLPattern(pattern string, opts URLPatternOptions) (*URLPattern, error) {
+	patternError := func(err error) error {
+		return &Error{Kind: KindInvalidInput, Op: "url-pattern", Input: pattern, Offset: -1, Err: err}
+	}
+	init, err := parseConstructorString(pattern)
+	if err != nil {
+		return nil, patternError(err)
+	}
+	if opts.BaseURL != "" {
+		base, err := Parse(opts.BaseURL)
+		if err != nil {
+			return nil, err
+		}
+		inheritBaseURL(init, base)
+	} else if _, ok := init[PatternProtocol]; !ok {
+		return nil, patternError(fmt.Errorf("relative pattern without a base URL"))
+	}
+	for _, name := range PatternComponents {
+		if _, ok := init[name]; !ok {
+			init[name] = "*"
+		}
+	}
+
+	p := &URLPattern{components: map[string]*patternComponent{}}
+	compile := func(name string, options patternOptions) error {
+		options.ignoreCase = opts.IgnoreCase
+		c, err := compilePatternComponent(init[name], options, name)
+		if err != nil {
+			return fmt.Errorf("%s: %w", name, err)
+		}
+		p.components[name] = c
+		return nil
+	}
+	if err := compile(PatternProtocol, patternOptions{}); err != nil {
+		return nil, patternError(err)
+	}
+	special := p.components[PatternProtocol].matchesSpecialScheme()
+	if special {
+		if port, ok := DefaultPorts[init[PatternProtocol]]; ok && port == init[PatternPort] {
+			init[PatternPort] = ""
+		}
+	}
+	for _, name := range PatternComponents[1:] {
+		options := patternOptions{}
+		switch {
+		case name == PatternHostname:
+			options.delimiter = "."
+		case name == PatternPathname && special:
+			options.delimiter, options.prefix = "/", "/"
+		}
+		if err := compile(name, options); err != nil {
+			return nil, patternError(err)
+		}
+	}
+	return p, nil
+}
+
+// inheritBaseURL sets the components that a relative pattern does not give
+// from base, following the URLPattern Standard: each component before the
+// first one that the pattern gives is taken from base.
+func inheritBaseURL(init map[string]string, base *url.URL) {
+	password, _ := base.User.Password()
+	baseComponents := map[string]string{
+		PatternProtocol: escapePatternString(base.Scheme),
+		PatternUsername: escapePatternString(base.User.Username()),
+		PatternPassword: escapePatternString(password),
+		PatternHostname: escapePatternString(strings.ToLower(base.Hostname())),
+		PatternPort:     escapePatternString(base.Port()),
+		PatternPathname: escapePatternString(base.EscapedPath()),
+		PatternSearch:   escapePatternString(base.RawQuery),
+		PatternHash:     escapePatternString(base.EscapedFragment()),
+	}
+	for _, name := range PatternComponents {
+		if _, ok := init[name]; ok {
+			break
+		}
+		init[name] = baseComponents[name]
+	}
+	if path, ok := init[PatternPathname]; ok && !strings.HasPrefix(path, "/") && !strings.HasPrefix(path, "{/") &&
+		!strings.HasPrefix(path, "\\/") && base.Path != "" {
+		// A relative pathname is resolved against the directory of the
+		// base URL's path.
+		dir := baseComponents[PatternPathname]
+		if i := strings.LastIndexByte(dir, '/'); i >= 0 {
+			init[PatternPathname] = dir[:i+1] + path
+		}
+	}
+}
+
+// escapePatternString escapes the characters of s that have a meaning in
+// a pattern.
+func escapePatternString(s string) string {
+	var b strings.Builder
+	for _, r := range s {
+		if strings.ContainsRune("+*?:{}()\\", r) {
+			b.WriteByte('\\')
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
+
+// Component returns the pattern of a component, such as the pathname.
+func (p *URLPattern) Component(name string) string {
+	if c, ok := p.components[name]; ok {
+		return c.pattern
+	}
+	return ""
+}
+
+// Test reports whether rawURL matches the pattern.
+func (p *URLPattern) Test(rawURL string) (bool, error) {
+	result, err := p.Exec(rawURL)
+	return result != nil, err
+}
+
+// Exec matches rawURL against the pattern. It returns the input and named
+// groups of each component, or nil if rawURL does not match.
+func (p *URLPattern) Exec(rawURL string) (*URLPatternResult, error) {
+	u, err := Parse(rawURL)
+	if err != nil {
+		return nil, err
+	}
+	if !u.IsAbs() {
+		return nil, &Error{Kind: KindInvalidInput, Op: "url-pattern", Input: rawURL, Offset: -1, Err: fmt.Errorf("URL is not absolute")}
+	}
+	scheme := strings.ToLower(u.Scheme)
+	password, _ := u.User.Password()
+	host := strings.ToLower(u.Hostname())
+	if ascii, err := ToASCII(host); err == nil {
+		host = ascii
+	}
+	if strings.Contains(host, ":") {
+		host = "[" + host + "]"
+	}
+	port := u.Port()
+	if DefaultPorts[scheme] == port && containsString(specialSchemes, scheme) {
+		port = ""
+	}
+	path := u.EscapedPath()
+	if u.Opaque != "" {
+		path = u.Opaque
+	} else if path == "" && containsString(specialSchemes, scheme) {
+		path = "/"
+	}
+	inputs := map[string]string{
+		PatternProtocol: scheme,
+		PatternUsername: u.User.Username(),
+		PatternPassword: password,
+		PatternHostname: host,
+		PatternPort:     port,
+		PatternPathname: path,
+		PatternSearch:   u.RawQuery,
+		PatternHash:     u.EscapedFragment(),
+	}
+
+	result := &URLPatternResult{Inputs: []string{rawURL}}
+	fields := map[string]*URLPatternComponentResult{
+		PatternProtocol: &result.Protocol,
+		PatternUsername: &result.Username,
+		PatternPassword: &result.Password,
+		PatternHostname: &result.Hostname,
+		PatternPort:     &result.Port,
+		PatternPathname: &result.Pathname,
+		PatternSearch:   &result.Search,
+		PatternHash:     &result.Hash,
+	}
+	for _, name := range PatternComponents {
+		groups, ok := p.components[name].exec(inputs[name])
+		if !ok {
+			return nil, nil
+		}
+		*fields[name] = URLPatternComponentResult{Input: inputs[name], Groups: groups}
+	}
+	return result, nil
+}
+
+// exec matches the input of a component and returns its named groups.
+func (c *patternComponent) exec(input string) (map[string]*string, bool) {
+	m := c.re.FindStringSubmatchIndex(input)
+	if m == nil {
+		return nil, false
+	}
+	groups := map[string]*string{}
+	for i, name := range c.names {
+		if start := m[2*i+2]; start >= 0 {
+			value := input[start:m[2*i+3]]
+			groups[name] = &value
+		} else {
+			groups[name] = nil
+		}
+	}
+	return groups, true
+}
+
+// matchesSpecialScheme reports whether the protocol component matches one
+// of the special schemes.
+func (c *patternComponent) matchesSpecialScheme() bool {
+	for _, scheme := range specialSchemes {
+		if c.re.MatchString(scheme) {
+			return true
+		}
+	}
+	return false
+}
+
+// Token types of the pattern tokenizer.
+const (
+	tokenOpen = iota
+	tokenClose
+	tokenRegexp
+	tokenName
+	tokenChar
+	tokenEscapedChar
+	tokenOtherModifier
+	tokenAsterisk
+	tokenEnd
+	tokenInvalidChar
+)
+
+// patternToken is a token of a pattern string. index is an offset in
+// runes.
+type patternToken struct {
+	kind  int
+	index int
+	value string
+}
+
+// tokenizePattern splits a pattern string into tokens. If lenient is true,
+// invalid characters are returned as tokenInvalidChar instead of failing.
+func tokenizePattern(input []rune, lenient bool) ([]patternToken, error) {
+	var tokens []patternToken
+	fail := func(index int, next int, msg string) (int, error) {
+		if !lenient {
+			return 0, fmt.Errorf("%s at %q", msg, string(input[index:]))
+		}
+		tokens = append(tokens, patternToken{kind: tokenInvalidChar, index: index, value: string(input[index:next])})
+		return next, nil
+	}
+	for i := 0; i < len(input); {
+		c := input[i]
+		var err error
+		switch c {
+		case '*':
+			tokens = append(tokens, patternToken{tokenAsterisk, i, "*"})
+			i++
+		case '+', '?':
+			tokens = append(tokens, patternToken{tokenOtherModifier, i, string(c)})
+			i++
+		case '\\':
+			if i == len(input)-1 {
+				i, err = fail(i, i+1, "pattern ends with \\")
+				break
+			}
+			tokens = append(tokens, patternToken{tokenEscapedChar, i, string(input[i+1])})
+			i += 2
+		case '{':
+			tokens = append(tokens, patternToken{tokenOpen, i, "{"})
+			i++
+		case '}':
+			tokens = append(tokens, patternToken{tokenClose, i, "}"})
+			i++
+		case ':':
+			j := i + 1
+			for j < len(input) && isPatternNameRune(input[j], j == i+1) {
+				j++
+			}
+			if j == i+1 {
+				i, err = fail(i, i+1, "missing name after :")
+				break
+			}
+			tokens = append(tokens, patternToken{tokenName, i, string(input[i+1 : j])})
+			i = j
+		case '(':
+			var value string
+			var next int
+			value, next, err = scanPatternRegexp(input, i)
+			if err != nil {
+				i, err = fail(i, i+1, err.Error())
+				break
+			}
+			tokens = append(tokens, patternToken{tokenRegexp, i, value})
+			i = next
+		default:
+			tokens = append(tokens, patternToken{tokenChar, i, string(c)})
+			i++
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+	tokens = append(tokens, patternToken{tokenEnd, len(input), ""})
+	return tokens, nil
+}
+
+// isPatternNameRune reports whether r can be part of a group name.
+func isPatternNameRune(r rune, first bool) bool {
+	if r == '$' || r == '_' || unicode.IsLetter(r) || unicode.Is(unicode.Other_ID_Start, r) || unicode.Is(unicode.Nl, r) {
+		return true
+	}
+	if first {
+		return false
+	}
+	return r == '‌' || r == '‍' || unicode.IsDigit(r) || unicode.In(r, unicode.Mn, unicode.Mc, unicode.Nd, unicode.Pc) ||
+		unicode.Is(unicode.Other_ID_Continue, r)
+}
+
+// scanPatternRegexp scans the regular expression group that starts at
+// input[start], which is (. It returns the expression and the index after
+// the group.
+func scanPatternRegexp(input []rune, start int) (string, int, error) {
+	depth := 1
+	i := start + 1
+	for ; i < len(input); i++ {
+		c := input[i]
+		if c > unicode.MaxASCII {
+			return "", 0, fmt.Errorf("non-ASCII character in regular expression")
+		}
+		if i == start+1 && c == '?' {
+			return "", 0, fmt.Errorf("regular expression starts with ?")
+		}
+		switch c {
+		case '\\':
+			if i == len(input)-1 {
+				return "", 0, fmt.Errorf("regular expression ends with \\")
+			}
+			if input[i+1] > unicode.MaxASCII {
+				return "", 0, fmt.Errorf("non-ASCII character in regular expression")
+			}
+			i++
+		case ')':
+			depth--
+			if depth == 0 {
+				value := string(input[start+1 : i])
+				if value == "" {
+					return "", 0, fmt.Errorf("empty regular expression")
+				}
+				return value, i + 1, nil
+			}
+		case '(':
+			depth++
+			if i == len(input)-1 || input[i+1] != '?' {
+				return "", 0, fmt.Errorf("regular expression has a capturing group")
+			}
+		}
+	}
+	return "", 0, fmt.Errorf("unclosed regular expression")
+}
+
+// Modifiers of a pattern part.
+const (
+	modifierNone       = ""
+	modifierOptional   = "?"
+	modifierZeroOrMore = "*"
+	modifierOneOrMore  = "+"
+)
+
+// Types of pattern parts.
+const (
+	partFixed = iota
+	partRegexp
+	partSegmentWildcard
+	partFullWildcard
+)
+
+// patternPart is a fixed string or a group of a pattern.
+type patternPart struct {
+	kind     int
+	value    string
+	modifier string
+	name     string
+	prefix   string
+	suffix   string
+}
+
+// patternOptions are the options of a component: the delimiter that a
+// segment wildcard such as :id does not match, and the prefix that is
+// made part of an optional group, like the / of /:id?.
+type patternOptions struct {
+	delimiter  string
+	prefix     string
+	ignoreCase bool
+}
+
+// segmentWildcard returns the regular expression of a segment wildcard.
+func (o patternOptions) segmentWildcard() string {
+	if o.delimiter == "" {
+		return "(?s:.)+?"
+	}
+	return "[^" + regexp.QuoteMeta(o.delimiter) + "]+?"
+}
+
+// patternParser parses the tokens of a pattern string into parts.
+type patternParser struct {
+	tokens       []patternToken
+	index        int
+	options      patternOptions
+	encode       func(string) (string, error)
+	parts        []patternPart
+	pendingFixed string
+	nextName     int
+	input        []rune
+}
+
+// parsePatternString parses the pattern of a component into parts. encode
+// canonicalizes fixed text for the component.
+func parsePatternString(input string, options patternOptions, encode func(string) (string, error)) ([]patternPart, error) {
+	runes := []rune(input)
+	tokens, err := tokenizePattern(runes, false)
+	if err != nil {
+		return nil, err
+	}
+	p := &patternParser{tokens: tokens, options: options, encode: encode, input: runes}
+	for p.index < len(p.tokens) {
+		charToken := p.tryConsume(tokenChar)
+		nameToken := p.tryConsume(tokenName)
+		regexpToken := p.tryConsumeRegexpOrWildcard(nameToken)
+		if nameToken != nil || regexpToken != nil {
+			prefix := ""
+			if charToken != nil {
+				prefix = charToken.value
+			}
+			if prefix != "" && prefix != options.prefix {
+				p.pendingFixed += prefix
+				prefix = ""
+			}
+			if err := p.addPendingFixed(); err != nil {
+				return nil, err
+			}
+			modifier := p.tryConsumeModifier()
+			if err := p.addPart(prefix, nameToken, regexpToken, "", modifier); err != nil {
+				return nil, err
+			}
+			continue
+		}
+		fixedToken := charToken
+		if fixedToken == nil {
+			fixedToken = p.tryConsume(tokenEscapedChar)
+		}
+		if fixedToken != nil {
+			p.pendingFixed += fixedToken.value
+			continue
+		}
+		if openToken := p.tryConsume(tokenOpen); openToken != nil {
+			prefix := p.consumeText()
+			nameToken := p.tryConsume(tokenName)
+			regexpToken := p.tryConsumeRegexpOrWildcard(nameToken)
+			suffix := p.consumeText()
+			if err := p.consumeRequired(tokenClose); err != nil {
+				return nil, err
+			}
+			modifier := p.tryConsumeModifier()
+			if err := p.addPart(prefix, nameToken, regexpToken, suffix, modifier); err != nil {
+				return nil, err
+			}
+			continue
+		}
+		if err := p.addPendingFixed(); err != nil {
+			return nil, err
+		}
+		if err := p.consumeRequired(tokenEnd); err != nil {
+			return nil, err
+		}
+	}
+	return p.parts, nil
+}
+
+func (p *patternParser) tryConsume(kind int) *patternToken {
+	if p.index >= len(p.tokens) || p.tokens[p.index].kind != kind {
+		return nil
+	}
+	t := &p.tokens[p.index]
+	p.index++
+	return t
+}
+
+func (p *patternParser) tryConsumeModifier() *patternToken {
+	if t := p.tryConsume(tokenOtherModifier); t != nil {
+		return t
+	}
+	return p.tryConsume(tokenAsterisk)
+}
+
+func (p *patternParser) tryConsumeRegexpOrWildcard(nameToken *patternToken) *patternToken {
+	t := p.tryConsume(tokenRegexp)
+	if t == nil && nameToken == nil {
+		t = p.tryConsume(tokenAsterisk)
+	}
+	return t
+}
+
+func (p *patternParser) consumeRequired(kind int) error {
+	if t := p.tryConsume(kind); t != nil {
+		return nil
+	}
+	t := p.tokens[p.index]
+	return fmt.Errorf("unexpected %q at %q", t.value, string(p.input[t.index:]))
+}
+
+func (p *patternParser) consumeText() string {
+	var b strings.Builder
+	for {
+		t := p.tryConsume(tokenChar)
+		if t == nil {
+			t = p.tryConsume(tokenEscapedChar)
+		}
+		if t == nil {
+			return b.String()
+		}
+		b.WriteString(t.value)
+	}
+}
+
+func (p *patternParser) addPendingFixed() error {
+	if p.pendingFixed == "" {
+		return nil
+	}
+	encoded, err := p.encode(p.pendingFixed)
+	if err != nil {
+		return err
+	}
+	p.pendingFixed = ""
+	p.parts = append(p.parts, patternPart{kind: partFixed, value: encoded, modifier: modifierNone})
+	return nil
+}
+
+func (p *patternParser) addPart(prefix string, nameToken *patternToken, regexpToken *patternToken, suffix string, modifierToken *patternToken) error {
+	modifier := modifierNone
+	if modifierToken != nil {
+		modifier = modifierToken.value
+	}
+	if nameToken == nil && regexpToken == nil && modifier == modifierNone {
+		p.pendingFixed += prefix
+		return nil
+	}
+	if err := p.addPendingFixed(); err != nil {
+		return err
+	}
+	if nameToken == nil && regexpToken == nil {
+		if prefix == "" {
+			return nil
+		}
+		encoded, err := p.encode(prefix)
+		if err != nil {
+			return err
+		}
+		p.parts = append(p.parts, patternPart{kind: partFixed, value: encoded, modifier: modifier})
+		return nil
+	}
+
+	value := ""
+	switch {
+	case regexpToken == nil:
+		value = p.options.segmentWildcard()
+	case regexpToken.kind == tokenAsterisk:
+		value = ".*"
+	default:
+		value = regexpToken.value
+	}
+	kind := partRegexp
+	switch value {
+	case p.options.segmentWildcard():
+		kind, value = partSegmentWildcard, ""
+	case ".*":
+		kind, value = partFullWildcard, ""
+	}
+	name := ""
+	if nameToken != nil {
+		name = nameToken.value
+	} else {
+		name = strconv.Itoa(p.nextName)
+		p.nextName++
+	}
+	for _, part := range p.parts {
+		if part.name == name && part.kind != partFixed {
+			return fmt.Errorf("duplicate group name %q", name)
+		}
+	}
+	encodedPrefix, err := p.encode(prefix)
+	if err != nil {
+		return err
+	}
+	encodedSuffix, err := p.encode(suffix)
+	if err != nil {
+		return err
+	}
+	p.parts = append(p.parts, patternPart{kind: kind, value: value, modifier: modifier, name: name,
+		prefix: encodedPrefix, suffix: encodedSuffix})
+	return nil
+}
+
+// patternRegexp generates the regular expression of parts and the names of
+// its groups.
+func patternRegexp(parts []patternPart, options patternOptions) (string, []string) {
+	var b strings.Builder
+	if options.ignoreCase {
+		b.WriteString("(?i)")
+	}
+	b.WriteString("^")
+	var names []string
+	for _, part := range parts {
+		if part.kind == partFixed {
+			if part.modifier == modifierNone {
+				b.WriteString(regexp.QuoteMeta(part.value))
+			} else {
+				b.WriteString("(?:" + regexp.QuoteMeta(part.value) + ")" + part.modifier)
+			}
+			continue
+		}
+		names = append(names, part.name)
+		value := part.value
+		switch part.kind {
+		case partSegmentWildcard:
+			value = options.segmentWildcard()
+		case partFullWildcard:
+			value = ".*"
+		}
+		prefix, suffix := regexp.QuoteMeta(part.prefix), regexp.QuoteMeta(part.suffix)
+		switch {
+		case prefix == "" && suffix == "" && (part.modifier == modifierNone || part.modifier == modifierOptional):
+			b.WriteString("(" + value + ")" + part.modifier)
+		case prefix == "" && suffix == "":
+			b.WriteString("((?:" + value + ")" + part.modifier + ")")
+		case part.modifier == modifierNone || part.modifier == modifierOptional:
+			b.WriteString("(?:" + prefix + "(" + value + ")" + suffix + ")" + part.modifier)
+		default:
+			b.WriteString("(?:" + prefix + "((?:" + value + ")(?:" + suffix + prefix + "(?:" + value + "))*)" + suffix + ")")
+			if part.modifier == modifierZeroOrMore {
+				b.WriteString("?")
+			}
+		}
+	}
+	b.WriteString("$")
+	return b.String(), names
+}
+
+// compilePatternComponent compiles the pattern of the component name.
+func compilePatternComponent(pattern string, options patternOptions, name string) (*patternComponent, error) {
+	parts, err := parsePatternString(pattern, options, patternEncoder(name))
+	if err != nil {
+		return nil, err
+	}
+	expr, names := patternRegexp(parts, options)
+	re, err := regexp.Compile(expr)
+	if err != nil {
+		return nil, fmt.Errorf("invalid regular expression: %v", err)
+	}
+	return &patternComponent{pattern: pattern, re: re, names: names}, nil
+}
+
+// patternEncoder returns the function that canonicalizes the fixed text of
+// a component so that it matches the way the component of a URL is written.
+func patternEncoder(name string) func(string) (string, error) {
+	encodeWith := func(set EncodeSet) func(string) (string, error) {
+		return func(s string) (string, error) {
+			return EncodeComponent(s, set.With("%")), nil
+		}
+	}
+	switch name {
+	case PatternProtocol:
+		return func(s string) (string, error) {
+			s = strings.ToLower(s)
+			if s != "" && !isScheme(s) {
+				return "", fmt.Errorf("invalid protocol %q", s)
+			}
+			return s, nil
+		}
+	case PatternUsername, PatternPassword:
+		return encodeWith(whatwgUserinfoSet)
+	case PatternHostname:
+		return func(s string) (string, error) {
+			if strings.HasPrefix(s, "[") {
+				return strings.ToLower(s), nil
+			}
+			ascii, err := ToASCII(strings.ToLower(s))
+			if err != nil {
+				return "", fmt.Errorf("invalid hostname %q", s)
+			}
+			return ascii, nil
+		}
+	case PatternPort:
+		return func(s string) (string, error) {
+			for _, c := range s {
+				if c < '0' || c > '9' {
+					return "", fmt.Errorf("invalid port %q", s)
+				}
+			}
+			return s, nil
+		}
+	case PatternPathname:
+		return encodeWith(whatwgPathSet)
+	case PatternSearch:
+		return encodeWith(whatwgQuerySet)
+	}
+	return encodeWith(whatwgFragmentSet)
+}
+
+// constructorParser splits a pattern string into the patterns of its
+// components, following the constructor string parser of the URLPattern
+// Standard.
+type constructorParser struct {
+	input          []rune
+	tokens         []patternToken
+	result         map[string]string
+	componentStart int
+	index          int
+	increment      int
+	groupDepth     int
+	ipv6Depth      int
+	special        bool
+	state          string
+}
+
+// Constructor string parser states that are not components.
+const (
+	stateInit      = "init"
+	stateAuthority = "authority"
+	stateDone      = "done"
+)
+
+// parseConstructorString returns the pattern of each component that a
+// pattern string gives.
+func parseConstructorString(input string) (map[string]string, error) {
+	runes := []rune(input)
+	tokens, _ := tokenizePattern(runes, true)
+	p := &constructorParser{input: runes, tokens: tokens, result: map[string]string{}, increment: 1, state: stateInit}
+	for p.index < len(p.tokens) {
+		p.increment = 1
+		if p.tokens[p.index].kind == tokenEnd {
+			if p.state == stateInit {
+				p.rewind()
+				switch {
+				case p.isNonSpecial(p.index, "#"):
+					p.changeState(PatternHash, 1)
+				case p.isSearchPrefix():
+					p.changeState(PatternSearch, 1)
+				default:
+					p.changeState(PatternPathname, 0)
+				}
+				p.index += p.increment
+				continue
+			}
+			if p.state == stateAuthority {
+				p.rewind()
+				p.state = PatternHostname
+				p.index += p.increment
+				continue
+			}
+			p.changeState(stateDone, 0)
+			break
+		}
+		if p.tokens[p.index].kind == tokenOpen {
+			p.groupDepth++
+			p.index += p.increment
+			continue
+		}
+		if p.groupDepth > 0 {
+			if p.tokens[p.index].kind != tokenClose {
+				p.index += p.increment
+				continue
+			}
+			p.groupDepth--
+		}
+		if err := p.step(); err != nil {
+			return nil, err
+		}
+		p.index += p.increment
+	}
+	if _, ok := p.result[PatternHostname]; ok {
+		if _, ok := p.result[PatternPort]; !ok {
+			p.result[PatternPort] = ""
+		}
+	}
+	return p.result, nil
+}
+
+// step handles the current token in the current state.
+func (p *constructorParser) step() error {
+	switch p.state {
+	case stateInit:
+		if p.isNonSpecial(p.index, ":") {
+			p.rewind()
+			p.state = PatternProtocol
+		}
+	case PatternProtocol:
+		if p.isNonSpecial(p.index, ":") {
+			protocol, err := compilePatternComponent(p.componentString(), patternOptions{}, PatternProtocol)
+			if err != nil {
+				return err
+			}
+			p.special = protocol.matchesSpecialScheme()
+			next, skip := PatternPathname, 1
+			if p.isNonSpecial(p.index+1, "/") && p.isNonSpecial(p.index+2, "/") {
+				next, skip = stateAuthority, 3
+			} else if p.special {
+				next, skip = stateAuthority, 1
+			}
+			p.changeState(next, skip)
+		}
+	case stateAuthority:
+		if p.isNonSpecial(p.index, "@") {
+			p.rewind()
+			p.state = PatternUsername
+		} else if p.isNonSpecial(p.index, "/") || p.isSearchPrefix() || p.isNonSpecial(p.index, "#") {
+			p.rewind()
+			p.state = PatternHostname
+		}
+	case PatternUsername:
+		if p.isNonSpecial(p.index, ":") {
+			p.changeState(PatternPassword, 1)
+		} else if p.isNonSpecial(p.index, "@") {
+			p.changeState(PatternHostname, 1)
+		}
+	case PatternPassword:
+		if p.isNonSpecial(p.index, "@") {
+			p.changeState(PatternHostname, 1)
+		}
+	case PatternHostname:
+		switch {
+		case p.isNonSpecial(p.index, "["):
+			p.ipv6Depth++
+		case p.isNonSpecial(p.index, "]"):
+			p.ipv6Depth--
+		case p.isNonSpecial(p.index, ":") && p.ipv6Depth == 0:
+			p.changeState(PatternPort, 1)
+		case p.isNonSpecial(p.index, "/"):
+			p.changeState(PatternPathname, 0)
+		case p.isSearchPrefix():
+			p.changeState(PatternSearch, 1)
+		case p.isNonSpecial(p.index, "#"):
+			p.changeState(PatternHash, 1)
+		}
+	case PatternPort:
+		switch {
+		case p.isNonSpecial(p.index, "/"):
+			p.changeState(PatternPathname, 0)
+		case p.isSearchPrefix():
+			p.changeState(PatternSearch, 1)
+		case p.isNonSpecial(p.index, "#"):
+			p.changeState(PatternHash, 1)
+		}
+	case PatternPathname:
+		switch {
+		case p.isSearchPrefix():
+			p.changeState(PatternSearch, 1)
+		case p.isNonSpecial(p.index, "#"):
+			p.changeState(PatternHash, 1)
+		}
+	case PatternSearch:
+		if p.isNonSpecial(p.index, "#") {
+			p.changeState(PatternHash, 1)
+		}
+	}
+	return nil
+}
+
+// changeState ends the current component and starts the next one after
+// skip tokens. Components between the two are empty.
+func (p *constructorParser) changeState(state string, skip int) {
+	if p.state != stateInit && p.state != stateAuthority && p.state != stateDone {
+		p.result[p.state] = p.componentString()
+	}
+	if p.state != stateInit && state != stateDone {
+		before := func(states ...string) bool { return containsString(states, p.state) }
+		if before(PatternProtocol, stateAuthority, PatternUsername, PatternPassword) &&
+			containsString([]string{PatternPort, PatternPathname, PatternSearch, PatternHash}, state) {
+			if _, ok := p.result[PatternHostname]; !ok {
+				p.result[PatternHostname] = ""
+			}
+		}
+		if before(PatternProtocol, stateAuthority, PatternUsername, PatternPassword, PatternHostname, PatternPort) &&
+			(state == PatternSearch || state == PatternHash) {
+			if _, ok := p.result[PatternPathname]; !ok {
+				p.result[PatternPathname] = ""
+				if p.special {
+					p.result[PatternPathname] = "/"
+				}
+			}
+		}
+		if before(PatternProtocol, stateAuthority, PatternUsername, PatternPassword, PatternHostname, PatternPort, PatternPathname) &&
+			state == PatternHash {
+			if _, ok := p.result[PatternSearch]; !ok {
+				p.result[PatternSearch] = ""
+			}
+		}
+	}
+	p.state = state
+	p.index += skip
+	p.componentStart = p.index
+	p.increment = 0
+}
+
+func (p *constructorParser) rewind() {
+	p.index = p.componentStart
+	p.increment = 0
+}
+
+// token returns the token at index, or the end token if index is past it.
+func (p *constructorParser) token(index int) patternToken {
+	if index < len(p.tokens) {
+		return p.tokens[index]
+	}
+	return p.tokens[len(p.tokens)-1]
+}
+
+// isNonSpecial reports whether the token at index is the character value
+// and not part of the pattern syntax.
+func (p *constructorParser) isNonSpecial(index int, value string) bool {
+	t := p.token(index)
+	if t.value != value {
+		return false
+	}
+	return t.kind == tokenChar || t.kind == tokenEscapedChar || t.kind == tokenInvalidChar
+}
+
+// isSearchPrefix reports whether the current token is a ? that starts the
+// search rather than a modifier.
+func (p *constructorParser) isSearchPrefix() bool {
+	if p.isNonSpecial(p.index, "?") {
+		return true
+	}
+	if p.tokens[p.index].value != "?" {
+		return false
+	}
+	if p.index == 0 {
+		return true
+	}
+	switch p.token(p.index - 1).kind {
+	case tokenName, tokenRegexp, tokenClose, tokenAsterisk:
+		return false
+	}
+	return true
+}
+
+// componentString returns the input from the start of the component to
+// the current token.
+func (p *constructorParser) componentString() string {
+	start := p.token(p.componentStart).index
+	end := p.token(p.index).index
+	return string(p.input[start:end])
+}
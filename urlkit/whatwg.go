@@ -0,0 +1,819 @@
+/*
+Copyright © 2022 Chris Morrow cmmorrow@gmail.com
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program. If not, see <http://www.gnu.org/licenses/>.
+*/
+package urlkit
+
+import (
+	"fmt"
+	"net"
+	"net/url"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// WHATWGURL is a URL parsed by the basic URL parser of the WHATWG URL
+// Standard, as browsers parse URLs. Its methods return the components as
+// the URL interface of the Standard does.
+type WHATWGURL struct {
+	scheme   string
+	username string
+	password string
+	// host is nil if the URL has no host, which is different from an empty
+	// host as in file:///etc.
+	host *string
+	// port is -1 if the URL has no port or the port is the default port of
+	// the scheme.
+	port int
+	// path is the list of segments of a hierarchical path. If opaque is
+	// true, path has a single element that is the whole path, as in
+	// mailto:me@mysite.com.
+	path     []string
+	opaque   bool
+	query    *string
+	fragment *string
+}
+
+// whatwgParser is the state of the basic URL parser.
+type whatwgParser struct {
+	input   []rune
+	pointer int
+	base    *WHATWGURL
+	url     *WHATWGURL
+	buffer  strings.Builder
+}
+
+// Parser states of the basic URL parser.
+const (
+	stateSchemeStart = iota
+	stateScheme
+	stateNoScheme
+	stateSpecialRelativeOrAuthority
+	statePathOrAuthority
+	stateRelative
+	stateRelativeSlash
+	stateSpecialAuthoritySlashes
+	stateSpecialAuthorityIgnoreSlashes
+	stateWHATWGAuthority
+	stateHost
+	statePort
+	stateFile
+	stateFileSlash
+	stateFileHost
+	statePathStart
+	statePath
+	stateOpaquePath
+	stateQuery
+	stateFragment
+)
+
+// whatwgC0ControlSet is the C0 control percent-encode set.
+var whatwgC0ControlSet = printableExcept("").With(" ")
+
+// whatwgSpecialQuerySet is the percent-encode set of the query of a URL
+// with a special scheme.
+var whatwgSpecialQuerySet = whatwgQuerySet.Without("'")
+
+// ParseWHATWG parses rawURL with the basic URL parser of the WHATWG URL
+// Standard. A relative URL is resolved against base, which may be nil.
+// Unlike url.Parse, backslashes are read as slashes in URLs with a special
+// scheme such as http, tabs and newlines are removed, default ports are
+// dropped, and hosts are converted to ASCII with IPv4 numbers in every form
+// that browsers accept.
+func ParseWHATWG(rawURL string, base *WHATWGURL) (*WHATWGURL, error) {
+	input := strings.TrimFunc(rawURL, func(r rune) bool { return r <= ' ' })
+	input = strings.NewReplacer("\t", "", "\n", "", "\r", "").Replace(input)
+	p := &whatwgParser{input: []rune(input), base: base, url: &WHATWGURL{port: -1}}
+	if err := p.parse(); err != nil {
+		return nil, &Error{Kind: KindInvalidInput, Op: "parse-whatwg", Input: rawURL, Offset: -1, Err: err}
+	}
+	return p.url, nil
+}
+
+// eof is the code point of the parser at the end of the input.
+const eof = -1
+
+// at returns the code point at i, or eof.
+func (p *whatwgParser) at(i int) rune {
+	if i < 0 || i >= len(p.input) {
+		return eof
+	}
+	return p.input[i]
+}
+
+// remaining returns the input after the pointer.
+func (p *whatwgParser) remaining() string {
+	if p.pointer+1 >= len(p.input) {
+		return ""
+	}
+	return string(p.input[p.pointer+1:])
+}
+
+func (p *whatwgParser) parse() error {
+	u, base := p.url, p.base
+	state := stateSchemeStart
+	var atSignSeen, insideBrackets, passwordTokenSeen bool
+	for ; ; p.pointer++ {
+		c := p.at(p.pointer)
+		special := isSpecialScheme(u.scheme)
+		switch state {
+		case stateSchemeStart:
+			if isASCIIAlpha(c) {
+				p.buffer.WriteRune(toASCIILower(c))
+				state = stateScheme
+			} else {
+				state = stateNoScheme
+				p.pointer--
+			}
+		case stateScheme:
+			switch {
+			case isASCIIAlpha(c) || c >= '0' && c <= '9' || c == '+' || c == '-' || c == '.':
+				p.buffer.WriteRune(toASCIILower(c))
+			case c == ':':
+				u.scheme = p.buffer.String()
+				p.buffer.Reset()
+				switch {
+				case u.scheme == "file":
+					state = stateFile
+				case isSpecialScheme(u.scheme) && base != nil && base.scheme == u.scheme:
+					state = stateSpecialRelativeOrAuthority
+				case isSpecialScheme(u.scheme):
+					state = stateSpecialAuthoritySlashes
+				case strings.HasPrefix(p.remaining(), "/"):
+					state = statePathOrAuthority
+					p.pointer++
+				default:
+					u.path, u.opaque = []string{""}, true
+					state = stateOpaquePath
+				}
+			default:
+				p.buffer.Reset()
+				state = stateNoScheme
+				p.pointer = -1
+			}
+		case stateNoScheme:
+			switch {
+			case base == nil || base.opaque && c != '#':
+				return fmt.Errorf("relative URL without a base URL")
+			case base.opaque:
+				u.scheme, u.path, u.opaque, u.query = base.scheme, base.path, true, base.query
+				u.fragment = new(string)
+				state = stateFragment
+			case base.scheme != "file":
+				state = stateRelative
+				p.pointer--
+			default:
+				state = stateFile
+				p.pointer--
+			}
+		case stateSpecialRelativeOrAuthority:
+			if c == '/' && strings.HasPrefix(p.remaining(), "/") {
+				state = stateSpecialAuthorityIgnoreSlashes
+				p.pointer++
+			} else {
+				state = stateRelative
+				p.pointer--
+			}
+		case statePathOrAuthority:
+			if c == '/' {
+				state = stateWHATWGAuthority
+			} else {
+				state = statePath
+				p.pointer--
+			}
+		case stateRelative:
+			u.scheme = base.scheme
+			if c == '/' || isSpecialScheme(u.scheme) && c == '\\' {
+				state = stateRelativeSlash
+				break
+			}
+			u.username, u.password, u.host, u.port = base.username, base.password, base.host, base.port
+			u.path, u.query = append([]string(nil), base.path...), base.query
+			switch c {
+			case '?':
+				u.query = new(string)
+				state = stateQuery
+			case '#':
+				u.fragment = new(string)
+				state = stateFragment
+			case eof:
+			default:
+				u.query = nil
+				u.shortenPath()
+				state = statePath
+				p.pointer--
+			}
+		case stateRelativeSlash:
+			switch {
+			case special && (c == '/' || c == '\\'):
+				state = stateSpecialAuthorityIgnoreSlashes
+			case c == '/':
+				state = stateWHATWGAuthority
+			default:
+				u.username, u.password, u.host, u.port = base.username, base.password, base.host, base.port
+				state = statePath
+				p.pointer--
+			}
+		case stateSpecialAuthoritySlashes:
+			if c == '/' && strings.HasPrefix(p.remaining(), "/") {
+				p.pointer++
+			} else {
+				p.pointer--
+			}
+			state = stateSpecialAuthorityIgnoreSlashes
+		case stateSpecialAuthorityIgnoreSlashes:
+			if c != '/' && c != '\\' {
+				state = stateWHATWGAuthority
+				p.pointer--
+			}
+		case stateWHATWGAuthority:
+			switch {
+			case c == '@':
+				buffer := p.buffer.String()
+				if atSignSeen {
+					buffer = "%40" + buffer
+				}
+				atSignSeen = true
+				for _, r := range buffer {
+					if r == ':' && !passwordTokenSeen {
+						passwordTokenSeen = true
+						continue
+					}
+					encoded := EncodeComponent(string(r), whatwgUserinfoSet.With("%"))
+					if passwordTokenSeen {
+						u.password += encoded
+					} else {
+						u.username += encoded
+					}
+				}
+				p.buffer.Reset()
+			case c == eof || c == '/' || c == '?' || c == '#' || special && c == '\\':
+				if atSignSeen && p.buffer.Len() == 0 {
+					return fmt.Errorf("missing host after credentials")
+				}
+				p.pointer -= utf8.RuneCountInString(p.buffer.String()) + 1
+				p.buffer.Reset()
+				state = stateHost
+			default:
+				p.buffer.WriteRune(c)
+			}
+		case stateHost:
+			switch {
+			case c == ':' && !insideBrackets:
+				if p.buffer.Len() == 0 {
+					return fmt.Errorf("missing host")
+				}
+				host, err := parseWHATWGHost(p.buffer.String(), !special)
+				if err != nil {
+					return err
+				}
+				u.host = &host
+				p.buffer.Reset()
+				state = statePort
+			case c == eof || c == '/' || c == '?' || c == '#' || special && c == '\\':
+				p.pointer--
+				if special && p.buffer.Len() == 0 {
+					return fmt.Errorf("missing host")
+				}
+				host, err := parseWHATWGHost(p.buffer.String(), !special)
+				if err != nil {
+					return err
+				}
+				u.host = &host
+				p.buffer.Reset()
+				state = statePathStart
+			default:
+				if c == '[' {
+					insideBrackets = true
+				} else if c == ']' {
+					insideBrackets = false
+				}
+				p.buffer.WriteRune(c)
+			}
+		case statePort:
+			switch {
+			case c >= '0' && c <= '9':
+				p.buffer.WriteRune(c)
+			case c == eof || c == '/' || c == '?' || c == '#' || special && c == '\\':
+				if p.buffer.Len() > 0 {
+					port, err := strconv.Atoi(p.buffer.String())
+					if err != nil || port > 65535 {
+						return fmt.Errorf("port %s is out of range", p.buffer.String())
+					}
+					if DefaultPorts[u.scheme] == strconv.Itoa(port) && special {
+						port = -1
+					}
+					u.port = port
+					p.buffer.Reset()
+				}
+				state = statePathStart
+				p.pointer--
+			default:
+				return fmt.Errorf("invalid port")
+			}
+		case stateFile:
+			u.scheme = "file"
+			u.host = new(string)
+			switch {
+			case c == '/' || c == '\\':
+				state = stateFileSlash
+			case base != nil && base.scheme == "file":
+				u.host, u.path, u.query = base.host, append([]string(nil), base.path...), base.query
+				switch c {
+				case '?':
+					u.query = new(string)
+					state = stateQuery
+				case '#':
+					u.fragment = new(string)
+					state = stateFragment
+				case eof:
+				default:
+					u.query = nil
+					if !startsWithWindowsDriveLetter(p.input[p.pointer:]) {
+						u.shortenPath()
+					} else {
+						u.path = nil
+					}
+					state = statePath
+					p.pointer--
+				}
+			default:
+				state = statePath
+				p.pointer--
+			}
+		case stateFileSlash:
+			if c == '/' || c == '\\' {
+				state = stateFileHost
+				break
+			}
+			if base != nil && base.scheme == "file" {
+				u.host = base.host
+				if !startsWithWindowsDriveLetter(p.input[p.pointer:]) && len(base.path) > 0 &&
+					isWindowsDriveLetter(base.path[0], true) {
+					u.path = append(u.path, base.path[0])
+				}
+			}
+			state = statePath
+			p.pointer--
+		case stateFileHost:
+			if c != eof && c != '/' && c != '\\' && c != '?' && c != '#' {
+				p.buffer.WriteRune(c)
+				break
+			}
+			p.pointer--
+			switch buffer := p.buffer.String(); {
+			case isWindowsDriveLetter(buffer, false):
+				// The buffer is kept as the first segment of the path.
+				state = statePath
+			case buffer == "":
+				u.host = new(string)
+				state = statePathStart
+			default:
+				host, err := parseWHATWGHost(buffer, !special)
+				if err != nil {
+					return err
+				}
+				if host == "localhost" {
+					host = ""
+				}
+				u.host = &host
+				p.buffer.Reset()
+				state = statePathStart
+			}
+		case statePathStart:
+			switch {
+			case special:
+				state = statePath
+				if c != '/' && c != '\\' {
+					p.pointer--
+				}
+			case c == '?':
+				u.query = new(string)
+				state = stateQuery
+			case c == '#':
+				u.fragment = new(string)
+				state = stateFragment
+			case c != eof:
+				state = statePath
+				if c != '/' {
+					p.pointer--
+				}
+			}
+		case statePath:
+			slash := c == '/' || special && c == '\\'
+			if c != eof && !slash && c != '?' && c != '#' {
+				p.buffer.WriteString(EncodeComponent(string(c), whatwgPathSet.With("%")))
+				break
+			}
+			switch buffer := p.buffer.String(); {
+			case isDoubleDotSegment(buffer):
+				u.shortenPath()
+				if !slash {
+					u.path = append(u.path, "")
+				}
+			case isSingleDotSegment(buffer):
+				if !slash {
+					u.path = append(u.path, "")
+				}
+			default:
+				if u.scheme == "file" && len(u.path) == 0 && isWindowsDriveLetter(buffer, false) {
+					buffer = buffer[:1] + ":"
+				}
+				u.path = append(u.path, buffer)
+			}
+			p.buffer.Reset()
+			if c == '?' {
+				u.query = new(string)
+				state = stateQuery
+			} else if c == '#' {
+				u.fragment = new(string)
+				state = stateFragment
+			}
+		case stateOpaquePath:
+			switch c {
+			case '?':
+				u.query = new(string)
+				state = stateQuery
+			case '#':
+				u.fragment = new(string)
+				state = stateFragment
+			case eof:
+			default:
+				u.path[0] += EncodeComponent(string(c), whatwgC0ControlSet.With("%"))
+			}
+		case stateQuery:
+			if c != eof && c != '#' {
+				p.buffer.WriteRune(c)
+				break
+			}
+			set := whatwgQuerySet
+			if special {
+				set = whatwgSpecialQuerySet
+			}
+			*u.query += EncodeComponent(p.buffer.String(), set.With("%"))
+			p.buffer.Reset()
+			if c == '#' {
+				u.fragment = new(string)
+				state = stateFragment
+			}
+		case stateFragment:
+			if c != eof {
+				*u.fragment += EncodeComponent(string(c), whatwgFragmentSet.With("%"))
+			}
+		}
+		// A state that decrements the pointer at the end of the input runs
+		// another state on the end of the input.
+		if p.pointer >= len(p.input) {
+			return nil
+		}
+	}
+}
+
+// shortenPath removes the last segment of the path, except for the drive
+// letter of a file URL.
+func (u *WHATWGURL) shortenPath() {
+	if u.scheme == "file" && len(u.path) == 1 && isWindowsDriveLetter(u.path[0], true) {
+		return
+	}
+	if len(u.path) > 0 {
+		u.path = u.path[:len(u.path)-1]
+	}
+}
+
+// isSpecialScheme reports whether scheme is a special scheme, which has a
+// host and a hierarchical path.
+func isSpecialScheme(scheme string) bool {
+	return containsString(specialSchemes, scheme)
+}
+
+func isASCIIAlpha(c rune) bool {
+	return c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z'
+}
+
+func toASCIILower(c rune) rune {
+	if c >= 'A' && c <= 'Z' {
+		return c + 'a' - 'A'
+	}
+	return c
+}
+
+// isWindowsDriveLetter reports whether s is a drive letter such as C: or
+// C|. A normalized drive letter must end in a colon.
+func isWindowsDriveLetter(s string, normalized bool) bool {
+	if len(s) != 2 || !isASCIIAlpha(rune(s[0])) {
+		return false
+	}
+	return s[1] == ':' || !normalized && s[1] == '|'
+}
+
+// startsWithWindowsDriveLetter reports whether input starts with a drive
+// letter that is the whole of the first segment.
+func startsWithWindowsDriveLetter(input []rune) bool {
+	if len(input) < 2 || !isWindowsDriveLetter(string(input[:2]), false) {
+		return false
+	}
+	return len(input) == 2 || strings.ContainsRune("/\\?#", input[2])
+}
+
+func isSingleDotSegment(s string) bool {
+	return s == "." || strings.EqualFold(s, "%2e")
+}
+
+func isDoubleDotSegment(s string) bool {
+	switch strings.ToLower(s) {
+	case "..", ".%2e", "%2e.", "%2e%2e":
+		return true
+	}
+	return false
+}
+
+// Code points that are not allowed in hosts, and also in domains.
+const (
+	forbiddenHostCodePoints   = "\x00\t\n\r #/:<>?@[\\]^|"
+	forbiddenDomainCodePoints = forbiddenHostCodePoints + "%\x7f"
+)
+
+// whatwgIDNA are the UTS #46 options of the domain to ASCII algorithm.
+var whatwgIDNA = IDNAOptions{Profile: IDNALookup, CheckBidi: true, CheckJoiners: true}
+
+// parseWHATWGHost parses a host with the host parser of the WHATWG URL
+// Standard. The host of a URL that does not have a special scheme is opaque
+// and only percent encoded.
+func parseWHATWGHost(input string, opaque bool) (string, error) {
+	if strings.HasPrefix(input, "[") {
+		if !strings.HasSuffix(input, "]") {
+			return "", fmt.Errorf("unclosed IPv6 address")
+		}
+		ip := net.ParseIP(input[1 : len(input)-1])
+		if ip == nil || strings.Contains(input, "%") {
+			return "", fmt.Errorf("invalid IPv6 address %s", input)
+		}
+		return "[" + serializeIPv6(ip) + "]", nil
+	}
+	if opaque {
+		if i := strings.IndexAny(input, forbiddenHostCodePoints); i >= 0 {
+			return "", fmt.Errorf("forbidden host code point %q", input[i])
+		}
+		return EncodeComponent(input, whatwgC0ControlSet.With("%")), nil
+	}
+	domain := strings.ToValidUTF8(string(percentDecodeBytes(input)), "\uFFFD")
+	ascii, err := whatwgDomainToASCII(domain)
+	if err != nil {
+		return "", err
+	}
+	labels := strings.Split(ascii, ".")
+	if labels[len(labels)-1] == "" && len(labels) > 1 {
+		labels = labels[:len(labels)-1]
+	}
+	if endsInNumber(labels[len(labels)-1]) {
+		ip, _, err := parseIPv4(ascii)
+		if err != nil {
+			return "", err
+		}
+		return ip.String(), nil
+	}
+	return ascii, nil
+}
+
+// whatwgDomainToASCII implements the domain to ASCII algorithm of the WHATWG
+// URL Standard.
+func whatwgDomainToASCII(domain string) (string, error) {
+	ascii := strings.ToLower(domain)
+	fast := true
+	for _, r := range domain {
+		if r >= utf8.RuneSelf {
+			fast = false
+		}
+	}
+	for _, label := range strings.Split(ascii, ".") {
+		if strings.HasPrefix(label, "xn--") {
+			fast = false
+		}
+	}
+	if !fast {
+		var err error
+		if ascii, err = whatwgIDNA.ToASCII(domain); err != nil {
+			return "", err
+		}
+	}
+	if ascii == "" {
+		return "", fmt.Errorf("empty host")
+	}
+	if i := strings.IndexAny(ascii, forbiddenDomainCodePoints); i >= 0 {
+		return "", fmt.Errorf("forbidden domain code point %q", ascii[i])
+	}
+	for _, r := range ascii {
+		if r < ' ' {
+			return "", fmt.Errorf("forbidden domain code point %q", r)
+		}
+	}
+	return ascii, nil
+}
+
+// percentDecodeBytes decodes the valid percent encodings in s and leaves
+// the others as they are.
+func percentDecodeBytes(s string) []byte {
+	var b []byte
+	for i := 0; i < len(s); i++ {
+		if s[i] == '%' && i+2 < len(s) && isHex(s[i+1]) && isHex(s[i+2]) {
+			n, _ := strconv.ParseUint(s[i+1:i+3], 16, 8)
+			b = append(b, byte(n))
+			i += 2
+			continue
+		}
+		b = append(b, s[i])
+	}
+	return b
+}
+
+// serializeIPv6 writes ip in the form of the WHATWG URL Standard, which
+// compresses the first longest run of zero pieces and never uses dotted
+// decimal.
+func serializeIPv6(ip net.IP) string {
+	ip = ip.To16()
+	var pieces [8]uint16
+	for i := range pieces {
+		pieces[i] = uint16(ip[2*i])<<8 | uint16(ip[2*i+1])
+	}
+	start, length := -1, 1
+	for i := 0; i < 8; {
+		j := i
+		for j < 8 && pieces[j] == 0 {
+			j++
+		}
+		if j-i > length {
+			start, length = i, j-i
+		}
+		if j == i {
+			j++
+		}
+		i = j
+	}
+	var b strings.Builder
+	for i := 0; i < 8; i++ {
+		if i == start {
+			b.WriteString("::")
+			i += length - 1
+			continue
+		}
+		if b.Len() > 0 && !strings.HasSuffix(b.String(), ":") {
+			b.WriteByte(':')
+		}
+		b.WriteString(strconv.FormatUint(uint64(pieces[i]), 16))
+	}
+	return b.String()
+}
+
+// Href returns the serialized URL.
+func (u *WHATWGURL) Href() string {
+	var b strings.Builder
+	b.WriteString(u.scheme + ":")
+	if u.host != nil {
+		b.WriteString("//")
+		if u.username != "" || u.password != "" {
+			b.WriteString(u.username)
+			if u.password != "" {
+				b.WriteString(":" + u.password)
+			}
+			b.WriteByte('@')
+		}
+		b.WriteString(u.Host())
+	} else if !u.opaque && len(u.path) > 1 && u.path[0] == "" {
+		// Without /. the path //x would be read as a host.
+		b.WriteString("/.")
+	}
+	b.WriteString(u.Pathname())
+	if u.query != nil {
+		b.WriteString("?" + *u.query)
+	}
+	if u.fragment != nil {
+		b.WriteString("#" + *u.fragment)
+	}
+	return b.String()
+}
+
+// Protocol returns the scheme followed by a colon.
+func (u *WHATWGURL) Protocol() string {
+	return u.scheme + ":"
+}
+
+// Username returns the percent encoded username.
+func (u *WHATWGURL) Username() string {
+	return u.username
+}
+
+// Password returns the percent encoded password.
+func (u *WHATWGURL) Password() string {
+	return u.password
+}
+
+// Host returns the host and port.
+func (u *WHATWGURL) Host() string {
+	if u.port < 0 {
+		return u.Hostname()
+	}
+	return u.Hostname() + ":" + u.Port()
+}
+
+// Hostname returns the host, which is empty if the URL has none.
+func (u *WHATWGURL) Hostname() string {
+	if u.host == nil {
+		return ""
+	}
+	return *u.host
+}
+
+// Port returns the port, which is empty if the URL has none or it is the
+// default port of the scheme.
+func (u *WHATWGURL) Port() string {
+	if u.port < 0 {
+		return ""
+	}
+	return strconv.Itoa(u.port)
+}
+
+// Pathname returns the percent encoded path.
+func (u *WHATWGURL) Pathname() string {
+	if u.opaque {
+		return u.path[0]
+	}
+	var b strings.Builder
+	for _, segment := range u.path {
+		b.WriteString("/" + segment)
+	}
+	return b.String()
+}
+
+// Search returns the query with a leading ?, or an empty string if the query
+// is empty.
+func (u *WHATWGURL) Search() string {
+	if u.query == nil || *u.query == "" {
+		return ""
+	}
+	return "?" + *u.query
+}
+
+// Hash returns the fragment with a leading #, or an empty string if the
+// fragment is empty.
+func (u *WHATWGURL) Hash() string {
+	if u.fragment == nil || *u.fragment == "" {
+		return ""
+	}
+	return "#" + *u.fragment
+}
+
+// URL returns u as a url.URL. An opaque path is the Opaque field.
+func (u *WHATWGURL) URL() *url.URL {
+	out := &url.URL{Scheme: u.scheme, Host: u.Host()}
+	if u.username != "" || u.password != "" {
+		username, _ := url.PathUnescape(u.username)
+		password, _ := url.PathUnescape(u.password)
+		if u.password != "" {
+			out.User = url.UserPassword(username, password)
+		} else {
+			out.User = url.User(username)
+		}
+	}
+	if u.opaque {
+		out.Opaque = u.path[0]
+	} else {
+		setEscapedPath(out, u.Pathname())
+	}
+	if u.query != nil {
+		out.RawQuery = *u.query
+		out.ForceQuery = *u.query == ""
+	}
+	if u.fragment != nil {
+		out.Fragment = pathUnescapeOrRaw(*u.fragment)
+		out.RawFragment = *u.fragment
+	}
+	return out
+}
+
+// setEscapedPath sets the path of u from its percent encoded form.
+func setEscapedPath(u *url.URL, escaped string) {
+	u.Path = pathUnescapeOrRaw(escaped)
+	u.RawPath = escaped
+}
+
+// pathUnescapeOrRaw decodes s, or returns it as it is if it has invalid
+// percent encodings.
+func pathUnescapeOrRaw(s string) string {
+	if unescaped, err := url.PathUnescape(s); err == nil {
+		return unescaped
+	}
+	return s
+}